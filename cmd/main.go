@@ -1,12 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"pharmly-backend/config"
 	server "pharmly-backend/internal/Server"
+	"syscall"
 )
 
 func main() {
-	application, err := server.NewApp()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	application, err := server.NewApp(cfg)
 	if err != nil {
 		log.Fatal("Failed to initialize application:", err)
 	}
@@ -15,13 +32,10 @@ func main() {
 		log.Fatal("Failed to initialize components:", err)
 	}
 
-	if err := application.Start(); err != nil {
-		log.Fatal("Failed to start server:", err)
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	defer func() {
-		if err := application.Shutdown(); err != nil {
-			log.Fatal("Failed to shutdown gracefully:", err)
-		}
-	}()
+	if err := application.Run(ctx); err != nil {
+		log.Fatal("Server stopped with error:", err)
+	}
 }
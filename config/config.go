@@ -0,0 +1,209 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Config is the typed application configuration. Values are resolved in
+// increasing order of precedence from the defaults in Default, the optional
+// YAML or TOML file named by CONFIG_FILE, and the environment. A .env file
+// only fills environment variables that are not already set.
+//
+// Each field names its file key in the cfg tag and its environment variable
+// in the env tag.
+type Config struct {
+	HTTP        HTTPConfig        `cfg:"http" json:"http"`
+	Log         LogConfig         `cfg:"log" json:"log"`
+	Database    DatabaseConfig    `cfg:"database" json:"database"`
+	JWT         JWTConfig         `cfg:"jwt" json:"jwt"`
+	TwoFactor   TwoFactorConfig   `cfg:"two_factor" json:"two_factor"`
+	Login       LoginConfig       `cfg:"login" json:"login"`
+	Idempotency IdempotencyConfig `cfg:"idempotency" json:"idempotency"`
+	Metrics     MetricsConfig     `cfg:"metrics" json:"metrics"`
+	Tracing     TracingConfig     `cfg:"tracing" json:"tracing"`
+	Pharmacy    PharmacyConfig    `cfg:"pharmacy" json:"pharmacy"`
+}
+
+type HTTPConfig struct {
+	Port            int           `cfg:"port" env:"PORT" json:"port" validate:"min=1,max=65535"`
+	ReadTimeout     time.Duration `cfg:"read_timeout" env:"HTTP_READ_TIMEOUT" json:"read_timeout" validate:"gte=0"`
+	WriteTimeout    time.Duration `cfg:"write_timeout" env:"HTTP_WRITE_TIMEOUT" json:"write_timeout" validate:"gte=0"`
+	IdleTimeout     time.Duration `cfg:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" json:"idle_timeout" validate:"gte=0"`
+	ShutdownTimeout time.Duration `cfg:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" json:"shutdown_timeout" validate:"gt=0"`
+	// ShutdownDelay keeps serving after readiness starts failing so load
+	// balancers can deregister the instance before the listener closes.
+	ShutdownDelay time.Duration `cfg:"shutdown_delay" env:"SHUTDOWN_DELAY" json:"shutdown_delay" validate:"gte=0"`
+	// ExportTimeout replaces WriteTimeout for CSV and XLSX exports, which
+	// are streamed and can take far longer than other responses. Zero
+	// removes the deadline.
+	ExportTimeout time.Duration `cfg:"export_timeout" env:"HTTP_EXPORT_TIMEOUT" json:"export_timeout" validate:"gte=0"`
+	// BodyLimit is the maximum request body size in bytes.
+	BodyLimit int `cfg:"body_limit" env:"HTTP_BODY_LIMIT" json:"body_limit" validate:"gt=0"`
+	// ProxyHeader is the header holding the client address, such as
+	// X-Forwarded-For, when running behind a reverse proxy. It is only
+	// honoured for requests coming from TrustedProxies.
+	ProxyHeader    string   `cfg:"proxy_header" env:"HTTP_PROXY_HEADER" json:"proxy_header"`
+	TrustedProxies []string `cfg:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" json:"trusted_proxies" validate:"required_with=ProxyHeader,dive,ip|cidr"`
+}
+
+type LogConfig struct {
+	// Format is json for production log shippers or console for local runs.
+	Format string `cfg:"format" env:"LOG_FORMAT" json:"format" validate:"oneof=json console"`
+	Level  string `cfg:"level" env:"LOG_LEVEL" json:"level" validate:"oneof=trace debug info warn error"`
+}
+
+type DatabaseConfig struct {
+	Host     string `cfg:"host" env:"DB_HOST" json:"host" validate:"required"`
+	Port     int    `cfg:"port" env:"DB_PORT" json:"port" validate:"min=1,max=65535"`
+	User     string `cfg:"user" env:"DB_USER" json:"user" validate:"required"`
+	Password Secret `cfg:"password" env:"DB_PASSWORD" json:"password"`
+	Name     string `cfg:"name" env:"DB_NAME" json:"name" validate:"required"`
+	SSLMode  string `cfg:"ssl_mode" env:"DB_SSL_MODE" json:"ssl_mode" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+
+	MaxConns          int32         `cfg:"max_conns" env:"DB_MAX_CONNS" json:"max_conns" validate:"gt=0"`
+	MinConns          int32         `cfg:"min_conns" env:"DB_MIN_CONNS" json:"min_conns" validate:"gte=0,ltefield=MaxConns"`
+	MaxConnLifetime   time.Duration `cfg:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" json:"max_conn_lifetime" validate:"gt=0"`
+	MaxConnIdleTime   time.Duration `cfg:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" json:"max_conn_idle_time" validate:"gt=0"`
+	HealthCheckPeriod time.Duration `cfg:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" json:"health_check_period" validate:"gt=0"`
+	// StatementCacheMode is one of prepare, describe, exec or
+	// simple_protocol. Use exec or simple_protocol behind PgBouncer in
+	// transaction mode.
+	StatementCacheMode string `cfg:"statement_cache_mode" env:"DB_STATEMENT_CACHE_MODE" json:"statement_cache_mode" validate:"oneof=prepare describe exec simple_protocol"`
+}
+
+type JWTConfig struct {
+	KeysDir   string `cfg:"keys_dir" env:"JWT_KEYS_DIR" json:"keys_dir" validate:"required"`
+	ActiveKID string `cfg:"active_kid" env:"JWT_ACTIVE_KID" json:"active_kid"`
+}
+
+type TwoFactorConfig struct {
+	// EncryptionKey is the base64-encoded 32-byte key that encrypts TOTP
+	// secrets at rest. Enrolled users cannot sign in if it changes.
+	EncryptionKey Secret `cfg:"encryption_key" env:"TOTP_ENCRYPTION_KEY" json:"encryption_key" validate:"required"`
+}
+
+type LoginConfig struct {
+	AccountMaxAttempts int           `cfg:"account_max_attempts" env:"LOGIN_ACCOUNT_MAX_ATTEMPTS" json:"account_max_attempts" validate:"gt=0"`
+	IPMaxAttempts      int           `cfg:"ip_max_attempts" env:"LOGIN_IP_MAX_ATTEMPTS" json:"ip_max_attempts" validate:"gt=0"`
+	Window             time.Duration `cfg:"window" env:"LOGIN_WINDOW" json:"window" validate:"gt=0"`
+	BaseLockout        time.Duration `cfg:"base_lockout" env:"LOGIN_BASE_LOCKOUT" json:"base_lockout" validate:"gt=0"`
+	MaxLockout         time.Duration `cfg:"max_lockout" env:"LOGIN_MAX_LOCKOUT" json:"max_lockout" validate:"gtefield=BaseLockout"`
+}
+
+type IdempotencyConfig struct {
+	TTL         time.Duration `cfg:"ttl" env:"IDEMPOTENCY_TTL" json:"ttl" validate:"gt=0"`
+	LockTimeout time.Duration `cfg:"lock_timeout" env:"IDEMPOTENCY_LOCK_TIMEOUT" json:"lock_timeout" validate:"gt=0"`
+}
+
+type MetricsConfig struct {
+	// RefreshInterval is how often the inventory gauges are recomputed.
+	RefreshInterval time.Duration `cfg:"refresh_interval" env:"METRICS_REFRESH_INTERVAL" json:"refresh_interval" validate:"gt=0"`
+	// ExpiryWindow is how far ahead a product counts as expiring.
+	ExpiryWindow time.Duration `cfg:"expiry_window" env:"METRICS_EXPIRY_WINDOW" json:"expiry_window" validate:"gt=0"`
+}
+
+type TracingConfig struct {
+	// Exporter is none to disable tracing, stdout to print spans, or otlp to
+	// send them to a collector over OTLP/HTTP.
+	Exporter    string `cfg:"exporter" env:"TRACING_EXPORTER" json:"exporter" validate:"oneof=none stdout otlp"`
+	ServiceName string `cfg:"service_name" env:"OTEL_SERVICE_NAME" json:"service_name" validate:"required"`
+	// Endpoint is the collector URL for the otlp exporter, such as
+	// http://localhost:4318. An http scheme disables TLS.
+	Endpoint string `cfg:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" json:"endpoint" validate:"required_if=Exporter otlp,omitempty,url"`
+	// SampleRatio is the fraction of new traces recorded. Requests that
+	// arrive with a sampled trace context are always recorded.
+	SampleRatio float64 `cfg:"sample_ratio" env:"TRACING_SAMPLE_RATIO" json:"sample_ratio" validate:"gte=0,lte=1"`
+}
+
+type PharmacyConfig struct {
+	// Timezone is the IANA time zone the pharmacy keeps its calendar in. It
+	// decides where report days begin and on which day a batch expires.
+	Timezone string `cfg:"timezone" env:"PHARMACY_TIMEZONE" json:"timezone" validate:"required,timezone"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Port:            8080,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     time.Minute,
+			ExportTimeout:   10 * time.Minute,
+			ShutdownTimeout: 15 * time.Second,
+			BodyLimit:       4 * 1024 * 1024,
+		},
+		Log: LogConfig{
+			Format: "json",
+			Level:  "info",
+		},
+		Database: DatabaseConfig{
+			Host:               "localhost",
+			Port:               5432,
+			SSLMode:            "disable",
+			MaxConns:           10,
+			MinConns:           2,
+			MaxConnLifetime:    time.Hour,
+			MaxConnIdleTime:    30 * time.Minute,
+			HealthCheckPeriod:  time.Minute,
+			StatementCacheMode: "prepare",
+		},
+		Login: LoginConfig{
+			AccountMaxAttempts: 5,
+			IPMaxAttempts:      20,
+			Window:             15 * time.Minute,
+			BaseLockout:        time.Minute,
+			MaxLockout:         time.Hour,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         24 * time.Hour,
+			LockTimeout: time.Minute,
+		},
+		Metrics: MetricsConfig{
+			RefreshInterval: time.Minute,
+			ExpiryWindow:    90 * 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "pharmly-backend",
+			SampleRatio: 1,
+		},
+		Pharmacy: PharmacyConfig{
+			Timezone: "UTC",
+		},
+	}
+}
+
+// String renders the configuration as JSON with secrets redacted, so it is
+// safe to log.
+func (c *Config) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+const redacted = "[REDACTED]"
+
+// Secret is a string that never prints its value. Use Value to read it.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
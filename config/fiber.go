@@ -2,9 +2,16 @@ package config
 
 import "github.com/gofiber/fiber/v2"
 
-func NewFiberConfig() fiber.Config {
+func NewFiberConfig(cfg HTTPConfig) fiber.Config {
 	return fiber.Config{
-		AppName: "pharmly API",
+		AppName:                 "pharmly API",
+		ReadTimeout:             cfg.ReadTimeout,
+		WriteTimeout:            cfg.WriteTimeout,
+		IdleTimeout:             cfg.IdleTimeout,
+		BodyLimit:               cfg.BodyLimit,
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: len(cfg.TrustedProxies) > 0,
+		TrustedProxies:          cfg.TrustedProxies,
 	}
 }
 
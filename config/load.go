@@ -0,0 +1,223 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigFile names the optional YAML (.yaml, .yml) or TOML (.toml) file.
+const EnvConfigFile = "CONFIG_FILE"
+
+// Error aggregates every problem found while loading the configuration so
+// they can all be fixed in one go.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Load resolves the configuration from all sources and validates it.
+func Load() (*Config, error) {
+	cfg, problems := load()
+	problems = append(problems, validate(cfg)...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// LoadDatabase resolves the configuration like Load but only validates the
+// database section, for commands such as migrate that need nothing else.
+func LoadDatabase() (DatabaseConfig, error) {
+	cfg, problems := load()
+	problems = append(problems, validate(&cfg.Database)...)
+	if len(problems) > 0 {
+		return DatabaseConfig{}, &Error{Problems: problems}
+	}
+	return cfg.Database, nil
+}
+
+func load() (*Config, []string) {
+	var problems []string
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		problems = append(problems, fmt.Sprintf(".env: %v", err))
+	}
+
+	cfg := Default()
+
+	file := map[string]any{}
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+		}
+	}
+
+	problems = append(problems, apply(reflect.ValueOf(cfg).Elem(), file, "")...)
+	return cfg, problems
+}
+
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, errors.New("unsupported config file type, use .yaml, .yml or .toml")
+	}
+	return values, err
+}
+
+// apply walks the config struct and assigns each field from the file, then
+// from the environment, collecting parse errors instead of stopping early.
+func apply(v reflect.Value, file map[string]any, prefix string) []string {
+	var problems []string
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		target := v.Field(i)
+		key := prefix + field.Tag.Get("cfg")
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			section, _ := file[field.Tag.Get("cfg")].(map[string]any)
+			problems = append(problems, apply(target, section, key+".")...)
+			continue
+		}
+
+		if raw, ok := file[field.Tag.Get("cfg")]; ok {
+			if err := setValue(target, fileValue(raw)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			}
+		}
+
+		if name := field.Tag.Get("env"); name != "" {
+			if raw, ok := os.LookupEnv(name); ok && raw != "" {
+				if err := setValue(target, raw); err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// fileValue turns a decoded YAML or TOML value into the string form used by
+// the environment, so both sources share one parser.
+func fileValue(raw any) string {
+	if list, ok := raw.([]any); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(raw)
+}
+
+func setValue(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+var configValidator = newValidator()
+
+// newValidator reports fields by their environment variable, falling back to
+// the file key for sections.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if name := field.Tag.Get("env"); name != "" {
+			return name
+		}
+		return field.Tag.Get("cfg")
+	})
+	return v
+}
+
+func validate(section any) []string {
+	err := configValidator.Struct(section)
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	problems := make([]string, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		problems = append(problems, fmt.Sprintf("%s: failed %q validation", e.Field(), fieldRule(e)))
+	}
+	return problems
+}
+
+func fieldRule(e validator.FieldError) string {
+	if e.Param() == "" {
+		return e.Tag()
+	}
+	return e.Tag() + "=" + e.Param()
+}
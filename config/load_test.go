@@ -0,0 +1,333 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolateEnv clears every variable the configuration reads, so values set on
+// the machine running the tests cannot leak in.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv(EnvConfigFile, "")
+	var clear func(reflect.Type)
+	clear = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.Type.Kind() == reflect.Struct {
+				clear(field.Type)
+			} else if name := field.Tag.Get("env"); name != "" {
+				t.Setenv(name, "")
+			}
+		}
+	}
+	clear(reflect.TypeOf(Config{}))
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// required fills the settings without defaults, so Load succeeds.
+var required = map[string]string{
+	"DB_USER":             "pharmly",
+	"DB_NAME":             "pharmly",
+	"JWT_KEYS_DIR":        "keys",
+	"TOTP_ENCRYPTION_KEY": "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U=",
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name            string
+		fileName        string
+		file            string
+		env             map[string]string
+		wantPort        int
+		wantReadTimeout time.Duration
+		wantProxies     []string
+		wantDBHost      string
+	}{
+		{
+			name:            "defaults",
+			wantPort:        8080,
+			wantReadTimeout: 15 * time.Second,
+			wantDBHost:      "localhost",
+		},
+		{
+			name:     "yaml file",
+			fileName: "config.yaml",
+			file: `
+http:
+  port: 9090
+  read_timeout: 30s
+  trusted_proxies: [10.0.0.1, 10.0.0.0/8]
+database:
+  host: db.internal
+`,
+			wantPort:        9090,
+			wantReadTimeout: 30 * time.Second,
+			wantProxies:     []string{"10.0.0.1", "10.0.0.0/8"},
+			wantDBHost:      "db.internal",
+		},
+		{
+			name:     "toml file",
+			fileName: "config.toml",
+			file: `
+[http]
+port = 9090
+read_timeout = "1m30s"
+trusted_proxies = ["10.0.0.1"]
+
+[database]
+host = "db.internal"
+`,
+			wantPort:        9090,
+			wantReadTimeout: 90 * time.Second,
+			wantProxies:     []string{"10.0.0.1"},
+			wantDBHost:      "db.internal",
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				"PORT":                 " 7070 ",
+				"HTTP_READ_TIMEOUT":    "2m",
+				"HTTP_TRUSTED_PROXIES": "10.0.0.1, ,10.0.0.2",
+				"DB_HOST":              "db.internal",
+			},
+			wantPort:        7070,
+			wantReadTimeout: 2 * time.Minute,
+			wantProxies:     []string{"10.0.0.1", "10.0.0.2"},
+			wantDBHost:      "db.internal",
+		},
+		{
+			name:     "environment wins over the file",
+			fileName: "config.yaml",
+			file: `
+http:
+  port: 9090
+  read_timeout: 30s
+database:
+  host: db.internal
+`,
+			env: map[string]string{
+				"PORT":    "7070",
+				"DB_HOST": "",
+			},
+			wantPort:        7070,
+			wantReadTimeout: 30 * time.Second,
+			wantDBHost:      "db.internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnv(t)
+			for name, value := range required {
+				t.Setenv(name, value)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if tt.fileName != "" {
+				t.Setenv(EnvConfigFile, writeConfigFile(t, tt.fileName, tt.file))
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if cfg.HTTP.Port != tt.wantPort {
+				t.Errorf("HTTP.Port = %d, want %d", cfg.HTTP.Port, tt.wantPort)
+			}
+			if cfg.HTTP.ReadTimeout != tt.wantReadTimeout {
+				t.Errorf("HTTP.ReadTimeout = %s, want %s", cfg.HTTP.ReadTimeout, tt.wantReadTimeout)
+			}
+			if !reflect.DeepEqual(cfg.HTTP.TrustedProxies, tt.wantProxies) {
+				t.Errorf("HTTP.TrustedProxies = %q, want %q", cfg.HTTP.TrustedProxies, tt.wantProxies)
+			}
+			if cfg.Database.Host != tt.wantDBHost {
+				t.Errorf("Database.Host = %q, want %q", cfg.Database.Host, tt.wantDBHost)
+			}
+			if cfg.Database.User != "pharmly" {
+				t.Errorf("Database.User = %q, want the environment's pharmly", cfg.Database.User)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	tests := []struct {
+		name         string
+		fileName     string
+		file         string
+		env          map[string]string
+		wantProblems []string
+	}{
+		{
+			name:     "parse and validation problems together",
+			fileName: "config.yaml",
+			file: `
+http:
+  read_timeout: soon
+database:
+  max_conns: 2
+  min_conns: 5
+`,
+			env: map[string]string{
+				"PORT":            "eighty",
+				"DB_SSL_MODE":     "sometimes",
+				"LOGIN_WINDOW":    "15",
+				"HTTP_BODY_LIMIT": "0",
+			},
+			wantProblems: []string{
+				`http.read_timeout: invalid duration "soon"`,
+				`PORT: invalid integer "eighty"`,
+				`LOGIN_WINDOW: invalid duration "15"`,
+				`HTTP_BODY_LIMIT: failed "gt=0" validation`,
+				`DB_USER: failed "required" validation`,
+				`DB_SSL_MODE: failed "oneof=disable allow prefer require verify-ca verify-full" validation`,
+				`DB_MIN_CONNS: failed "ltefield=MaxConns" validation`,
+				`JWT_KEYS_DIR: failed "required" validation`,
+				`TOTP_ENCRYPTION_KEY: failed "required" validation`,
+			},
+		},
+		{
+			name:         "unsupported file type",
+			fileName:     "config.json",
+			file:         `{}`,
+			env:          required,
+			wantProblems: []string{"unsupported config file type"},
+		},
+		{
+			name:         "malformed file",
+			fileName:     "config.yaml",
+			file:         "http: [",
+			env:          required,
+			wantProblems: []string{"config.yaml: "},
+		},
+		{
+			name:         "missing file",
+			env:          map[string]string{EnvConfigFile: filepath.Join(os.TempDir(), "missing-pharmly.yaml")},
+			wantProblems: []string{"missing-pharmly.yaml: ", `DB_NAME: failed "required" validation`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if tt.fileName != "" {
+				t.Setenv(EnvConfigFile, writeConfigFile(t, tt.fileName, tt.file))
+			}
+
+			_, err := Load()
+			var cfgErr *Error
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("Load() error = %v, want *Error", err)
+			}
+
+			for _, want := range tt.wantProblems {
+				found := false
+				for _, problem := range cfgErr.Problems {
+					if strings.Contains(problem, want) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("problems %q do not include %q", cfgErr.Problems, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	var target struct {
+		Text     string
+		Int      int
+		Int32    int32
+		Float    float64
+		Bool     bool
+		Duration time.Duration
+		List     []string
+		Map      map[string]string
+	}
+	v := reflect.ValueOf(&target).Elem()
+
+	tests := []struct {
+		field   string
+		raw     string
+		want    any
+		wantErr string
+	}{
+		{"Text", "  padded ", "padded", ""},
+		{"Int", "42", 42, ""},
+		{"Int", "4.2", nil, `invalid integer "4.2"`},
+		{"Int32", "3000000000", nil, `invalid integer "3000000000"`},
+		{"Float", "0.25", 0.25, ""},
+		{"Float", "quarter", nil, `invalid number "quarter"`},
+		{"Bool", "true", true, ""},
+		{"Bool", "yes", nil, `invalid boolean "yes"`},
+		{"Duration", "1h30m", 90 * time.Minute, ""},
+		{"Duration", "90", nil, `invalid duration "90"`},
+		{"List", "a, b,,c ", []string{"a", "b", "c"}, ""},
+		{"Map", "a=b", nil, "unsupported type map[string]string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.raw, func(t *testing.T) {
+			field := v.FieldByName(tt.field)
+			err := setValue(field, tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("setValue() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setValue() error = %v", err)
+			}
+			if got := field.Interface(); !reflect.DeepEqual(got, reflect.ValueOf(tt.want).Convert(field.Type()).Interface()) {
+				t.Errorf("%s = %v, want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigStringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "hunter2"
+
+	out := cfg.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("String() = %s, leaks the password", out)
+	}
+	if !strings.Contains(out, `"password":"[REDACTED]"`) {
+		t.Errorf("String() = %s, want the password redacted", out)
+	}
+
+	cfg.Database.Password = ""
+	if out := cfg.String(); !strings.Contains(out, `"password":""`) {
+		t.Errorf("String() = %s, want an unset password left empty", out)
+	}
+
+	if got := Secret("hunter2").GoString(); got != redacted {
+		t.Errorf("GoString() = %q, want %q", got, redacted)
+	}
+}
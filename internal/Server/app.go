@@ -2,90 +2,386 @@ package server
 
 import (
 	"context"
-	"log"
-	"os"
+	"errors"
+	"fmt"
+	"net"
 	"pharmly-backend/config"
 	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/handler"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/metrics"
 	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/report"
 	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
 	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/utils"
+	"pharmly-backend/internal/validation"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
 )
 
+// idempotencyPurgeInterval is how often expired idempotency keys are deleted.
+const idempotencyPurgeInterval = time.Hour
+
 type App struct {
 	FiberApp *fiber.App
 	DB       *database.PostgresDB
+	Keys     *utils.KeySet
+	Config   *config.Config
+
+	health      *handler.HealthHandler
+	imports     usecase.ProductImportUsecase
+	stopTracing func(context.Context) error
+	stopJobs    context.CancelFunc
+	jobs        sync.WaitGroup
+	runningJobs sync.Map
 }
 
 type RoutesOpts struct {
-	AuthHandler     *handler.AuthHandler
-	UserHandler     *handler.UserHandler
-	CategoryHandler *handler.CategoryHandler
-	ProductHandler  *handler.ProductHandler
-	SupplierHandler *handler.SupplierHandler
+	AuthHandler      *handler.AuthHandler
+	UserHandler      *handler.UserHandler
+	CategoryHandler  *handler.CategoryHandler
+	ProductHandler   *handler.ProductHandler
+	SupplierHandler  *handler.SupplierHandler
+	TwoFactorHandler *handler.TwoFactorHandler
+	JWKSHandler      *handler.JWKSHandler
+	APIKeyHandler    *handler.APIKeyHandler
+	AuditLogHandler  *handler.AuditLogHandler
+	ImportHandler    *handler.ProductImportHandler
+	BranchHandler    *handler.BranchHandler
+	SaleHandler      *handler.SaleHandler
+	ReportHandler    *handler.ReportHandler
+	HealthHandler    *handler.HealthHandler
+	APIKeyAuth       middleware.APIKeyAuthenticator
+	IdempotencyStore middleware.IdempotencyStore
+	Idempotency      middleware.IdempotencyConfig
 }
 
-func NewApp() (*App, error) {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+func NewApp(cfg *config.Config) (*App, error) {
+	if err := logger.Configure(cfg.Log.Format, cfg.Log.Level); err != nil {
+		return nil, err
+	}
+	logger.Info().Stringer("config", cfg).Msg("Configuration loaded")
+
+	keys, err := utils.LoadKeySet(cfg.JWT.KeysDir, cfg.JWT.ActiveKID)
+	if err != nil {
+		return nil, err
+	}
+	utils.SetKeySet(keys)
+
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := database.NewPostgresDB()
+	db, err := database.NewPostgresDB(cfg.Database)
 	if err != nil {
 		return nil, err
 	}
+	metrics.Registry.MustRegister(db.Collector())
 
-	fiberConfig := config.NewFiberConfig()
+	fiberConfig := config.NewFiberConfig(cfg.HTTP)
 	fiberConfig.ErrorHandler = middleware.ErrorHandler()
 	fiberApp := fiber.New(fiberConfig)
 
 	return &App{
 		FiberApp: fiberApp,
 		DB:       db,
+		Keys:     keys,
+		Config:   cfg,
+
+		stopTracing: stopTracing,
 	}, nil
 }
 
 func (a *App) Initialize() error {
-	userRepo := repository.NewUserRepository(a.DB.Conn)
-	categoryRepo := repository.NewCategoryRepository(a.DB.Conn)
-	productRepo := repository.NewProductRepository(a.DB.Conn)
-	supplierRepo := repository.NewSupplierRepository(a.DB.Conn)
+	totpSecrets, err := utils.NewSecretBox(a.Config.TwoFactor.EncryptionKey.Value())
+	if err != nil {
+		return fmt.Errorf("TOTP_ENCRYPTION_KEY: %w", err)
+	}
 
-	authUsecase := usecase.NewAuthUsecase(userRepo)
+	userRepo := repository.NewUserRepository(a.DB.Pool)
+	categoryRepo := repository.NewCategoryRepository(a.DB.Pool)
+	productRepo := repository.NewProductRepository(a.DB.Pool)
+	supplierRepo := repository.NewSupplierRepository(a.DB.Pool)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(a.DB.Pool)
+	twoFactorRepo := repository.NewTwoFactorRepository(a.DB.Pool, totpSecrets)
+	apiKeyRepo := repository.NewAPIKeyRepository(a.DB.Pool)
+	auditLogRepo := repository.NewAuditLogRepository(a.DB.Pool)
+	idempotencyRepo := repository.NewIdempotencyRepository(a.DB.Pool)
+	importJobRepo := repository.NewImportJobRepository(a.DB.Pool)
+	branchRepo := repository.NewBranchRepository(a.DB.Pool)
+	batchRepo := repository.NewProductBatchRepository(a.DB.Pool)
+	saleRepo := repository.NewSaleRepository(a.DB.Pool)
+	reportRepo := repository.NewReportRepository(a.DB.Pool)
+	txManager := database.NewTxManager(a.DB.Pool)
+
+	validation.RegisterExists("category_exists", categoryRepo.Exists)
+	validation.RegisterExists("supplier_exists", supplierRepo.Exists)
+	validation.RegisterExists("branch_exists", branchRepo.Exists)
+
+	pharmacyLocation, err := report.LoadLocation(a.Config.Pharmacy.Timezone)
+	if err != nil {
+		return err
+	}
+
+	// ctx lives until Shutdown and bounds every background task.
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopJobs = cancel
+
+	authUsecase := usecase.NewAuthUsecase(userRepo, loginAttemptRepo, twoFactorRepo, txManager, usecase.LoginPolicy{
+		AccountMaxAttempts: a.Config.Login.AccountMaxAttempts,
+		IPMaxAttempts:      a.Config.Login.IPMaxAttempts,
+		Window:             a.Config.Login.Window,
+		BaseLockout:        a.Config.Login.BaseLockout,
+		MaxLockout:         a.Config.Login.MaxLockout,
+	})
 	userUsecase := usecase.NewUserUsecase(userRepo)
 	categoryUsecase := usecase.NewCategoryUsecase(categoryRepo)
-	productUsecase := usecase.NewProductusecase(productRepo)
+	productUsecase := usecase.NewProductusecase(productRepo, txManager)
 	supplierUsecase := usecase.NewSupplierUsecase(supplierRepo)
+	twoFactorUsecase := usecase.NewTwoFactorUsecase(userRepo, twoFactorRepo)
+	apiKeyUsecase := usecase.NewAPIKeyUsecase(apiKeyRepo)
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepo)
+	branchUsecase := usecase.NewBranchUsecase(branchRepo)
+	batchUsecase := usecase.NewProductBatchUsecase(batchRepo, productRepo, txManager)
+	saleUsecase := usecase.NewSaleUsecase(saleRepo, batchRepo, productRepo, txManager, pharmacyLocation)
+	reportUsecase := usecase.NewReportUsecase(reportRepo, a.Config.Pharmacy.Timezone)
+	a.imports = usecase.NewProductImportUsecase(ctx, importJobRepo, productRepo, categoryRepo, supplierRepo, productUsecase)
 
 	authHandler := handler.NewAuthHandler(authUsecase)
-	userHandler := handler.NewUserHandler(userUsecase)
-	categoryHandler := handler.NewCategoryHandler(categoryUsecase)
-	productHandler := handler.NewProductHandler(productUsecase)
-	supplierHandler := handler.NewSupplierHandler(supplierUsecase)
+	userHandler := handler.NewUserHandler(userUsecase, a.Config.HTTP.ExportTimeout)
+	categoryHandler := handler.NewCategoryHandler(categoryUsecase, a.Config.HTTP.ExportTimeout)
+	productHandler := handler.NewProductHandler(productUsecase, a.Config.HTTP.ExportTimeout)
+	supplierHandler := handler.NewSupplierHandler(supplierUsecase, a.Config.HTTP.ExportTimeout)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorUsecase)
+	jwksHandler := handler.NewJWKSHandler(a.Keys)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyUsecase)
+	auditLogHandler := handler.NewAuditLogHandler(auditLogUsecase, a.Config.HTTP.ExportTimeout)
+	importHandler := handler.NewProductImportHandler(a.imports)
+	branchHandler := handler.NewBranchHandler(branchUsecase)
+	saleHandler := handler.NewSaleHandler(saleUsecase, batchUsecase)
+	reportHandler := handler.NewReportHandler(reportUsecase, a.Config.HTTP.ExportTimeout)
+
+	migrator, err := database.NewMigrator(a.DB.Pool)
+	if err != nil {
+		return err
+	}
+
+	a.health = handler.NewHealthHandler(
+		handler.HealthCheck{Name: "database", Check: a.DB.Ping},
+		handler.HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations pending", pending)
+			}
+			return nil
+		}},
+		handler.HealthCheck{Name: "workers", Check: a.checkJobs},
+	)
 
 	SetupRouter(a.FiberApp, &RoutesOpts{
-		AuthHandler:     authHandler,
-		UserHandler:     userHandler,
-		CategoryHandler: categoryHandler,
-		ProductHandler:  productHandler,
-		SupplierHandler: supplierHandler,
+		AuthHandler:      authHandler,
+		UserHandler:      userHandler,
+		CategoryHandler:  categoryHandler,
+		ProductHandler:   productHandler,
+		SupplierHandler:  supplierHandler,
+		TwoFactorHandler: twoFactorHandler,
+		JWKSHandler:      jwksHandler,
+		APIKeyHandler:    apiKeyHandler,
+		AuditLogHandler:  auditLogHandler,
+		ImportHandler:    importHandler,
+		BranchHandler:    branchHandler,
+		SaleHandler:      saleHandler,
+		ReportHandler:    reportHandler,
+		HealthHandler:    a.health,
+		APIKeyAuth:       apiKeyUsecase,
+		IdempotencyStore: idempotencyRepo,
+		Idempotency: middleware.IdempotencyConfig{
+			TTL:         a.Config.Idempotency.TTL,
+			LockTimeout: a.Config.Idempotency.LockTimeout,
+		},
+	})
+
+	a.startJob(ctx, "idempotency_purge", func(ctx context.Context) {
+		purgeIdempotencyKeys(ctx, idempotencyRepo, idempotencyPurgeInterval)
+	})
+	a.startJob(ctx, "inventory_metrics", func(ctx context.Context) {
+		refreshInventoryMetrics(ctx, productUsecase, a.Config.Metrics)
+	})
+
+	return nil
+}
+
+// startJob runs a background job until ctx is cancelled and tracks whether
+// it is still running for the readiness probe.
+func (a *App) startJob(ctx context.Context, name string, run func(ctx context.Context)) {
+	a.jobs.Add(1)
+	a.runningJobs.Store(name, true)
+	go func() {
+		defer a.jobs.Done()
+		defer a.runningJobs.Store(name, false)
+		run(ctx)
+	}()
+}
+
+func (a *App) checkJobs(ctx context.Context) error {
+	var stopped []string
+	a.runningJobs.Range(func(name, running any) bool {
+		if !running.(bool) {
+			stopped = append(stopped, name.(string))
+		}
+		return true
 	})
 
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return fmt.Errorf("background jobs not running: %s", strings.Join(stopped, ", "))
+	}
 	return nil
 }
 
-func (a *App) Start() error {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+func purgeIdempotencyKeys(ctx context.Context, repo repository.IdempotencyRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := repo.PurgeExpired(ctx, now)
+			if err != nil {
+				logger.Error().Err(err).Msg("Failed to purge expired idempotency keys")
+				continue
+			}
+			logger.Info().Int64("purged", purged).Msg("Purged expired idempotency keys")
+		}
 	}
+}
+
+// refreshInventoryMetrics keeps the low-stock and expiring product gauges
+// current. Failures are logged by the usecase and retried on the next tick.
+func refreshInventoryMetrics(ctx context.Context, products usecase.ProductUsecase, cfg config.MetricsConfig) {
+	ticker := time.NewTicker(cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		_ = products.RefreshInventoryMetrics(ctx, cfg.ExpiryWindow)
 
-	return a.FiberApp.Listen(":" + port)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run serves requests on the configured port until ctx is cancelled,
+// typically by a termination signal, and then shuts the application down
+// gracefully.
+func (a *App) Run(ctx context.Context) error {
+	ln, err := net.Listen(a.FiberApp.Config().Network, ":"+strconv.Itoa(a.Config.HTTP.Port))
+	if err != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.HTTP.ShutdownTimeout)
+		defer cancel()
+		return errors.Join(err, a.Shutdown(shutdownCtx))
+	}
+	return a.Serve(ctx, ln)
 }
 
-func (a *App) Shutdown() error {
-	return a.DB.Close(context.Background())
+// Serve serves requests on ln until ctx is cancelled. Readiness then fails
+// for ShutdownDelay while requests are still served, after which Shutdown
+// gets the full ShutdownTimeout to drain them.
+func (a *App) Serve(ctx context.Context, ln net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- a.FiberApp.Listener(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.HTTP.ShutdownTimeout)
+		defer cancel()
+		return errors.Join(err, a.Shutdown(shutdownCtx))
+	case <-ctx.Done():
+	}
+
+	if a.health != nil {
+		a.health.SetShuttingDown()
+	}
+	if delay := a.Config.HTTP.ShutdownDelay; delay > 0 {
+		logger.Info().Dur("delay", delay).Msg("Readiness failing, waiting for load balancers to deregister")
+		time.Sleep(delay)
+	}
+
+	logger.Info().Dur("timeout", a.Config.HTTP.ShutdownTimeout).Msg("Shutdown signal received, draining in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.HTTP.ShutdownTimeout)
+	defer cancel()
+	return a.Shutdown(shutdownCtx)
+}
+
+// Shutdown fails readiness, stops accepting connections and waits for
+// in-flight requests to finish until ctx expires, then stops background jobs,
+// waits for running imports, flushes logs and closes the database.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	if a.health != nil {
+		a.health.SetShuttingDown()
+	}
+
+	if err := a.FiberApp.ShutdownWithContext(ctx); err != nil {
+		logger.Error().Err(err).Msg("Failed to drain in-flight requests")
+		errs = append(errs, err)
+	}
+
+	if a.stopJobs != nil {
+		a.stopJobs()
+	}
+
+	jobsDone := make(chan struct{})
+	go func() {
+		a.jobs.Wait()
+		close(jobsDone)
+	}()
+
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+		logger.Error().Err(ctx.Err()).Msg("Background jobs did not stop in time")
+		errs = append(errs, ctx.Err())
+	}
+
+	if a.imports != nil {
+		if err := a.imports.Wait(ctx); err != nil {
+			logger.Error().Err(err).Msg("Product imports did not finish in time")
+			errs = append(errs, err)
+		}
+	}
+
+	if a.stopTracing != nil {
+		if err := a.stopTracing(ctx); err != nil {
+			logger.Error().Err(err).Msg("Failed to flush traces")
+			errs = append(errs, err)
+		}
+	}
+
+	logger.Info().Msg("Server stopped, closing database")
+	logger.Flush()
+
+	if a.DB != nil {
+		a.DB.Close()
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"pharmly-backend/config"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestServeDrainsInFlightRequest(t *testing.T) {
+	// The request outlasts ShutdownTimeout when counted from the signal but
+	// not when counted from the end of ShutdownDelay, so it only completes
+	// if the drain gets its own deadline.
+	const (
+		delay   = 300 * time.Millisecond
+		timeout = 600 * time.Millisecond
+		work    = 750 * time.Millisecond
+	)
+
+	app := &App{
+		FiberApp: fiber.New(),
+		Config: &config.Config{HTTP: config.HTTPConfig{
+			ShutdownTimeout: timeout,
+			ShutdownDelay:   delay,
+		}},
+	}
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	app.FiberApp.Get("/slow", func(c *fiber.Ctx) error {
+		close(started)
+		time.Sleep(work)
+		finished.Store(true)
+		return c.SendString("done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Serve(ctx, ln)
+	}()
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request never reached the handler")
+	}
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after the context was cancelled")
+	}
+
+	if !finished.Load() {
+		t.Fatal("Serve returned before the in-flight request completed")
+	}
+
+	res := <-responses
+	if res.err != nil {
+		t.Fatalf("in-flight request failed: %v", res.err)
+	}
+	if res.status != fiber.StatusOK || res.body != "done" {
+		t.Errorf("in-flight request = %d %q, want 200 \"done\"", res.status, res.body)
+	}
+}
@@ -1,13 +1,26 @@
 package server
 
 import (
+	"pharmly-backend/internal/metrics"
 	"pharmly-backend/internal/middleware"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func SetupRouter(app *fiber.App, handlers *RoutesOpts) {
+	app.Use(middleware.Metrics())
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.AccessLog())
 	app.Use(middleware.ValidateRequest())
+	app.Use(middleware.AuditContext())
+
+	app.Get("/.well-known/jwks.json", handlers.JWKSHandler.GetJWKS)
+	app.Get("/healthz", handlers.HealthHandler.Liveness)
+	app.Get("/readyz", handlers.HealthHandler.Readiness)
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
 
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
@@ -15,21 +28,65 @@ func SetupRouter(app *fiber.App, handlers *RoutesOpts) {
 	auth := v1.Group("/auth")
 	auth.Post("/register", handlers.AuthHandler.Register)
 	auth.Post("/login", handlers.AuthHandler.Login)
+	auth.Post("/login/2fa", handlers.AuthHandler.VerifyTwoFactor)
+
+	twoFactor := auth.Group("/2fa", middleware.EnrollmentAuthMiddleware())
+	twoFactor.Get("/", handlers.TwoFactorHandler.GetStatus)
+	twoFactor.Post("/enroll", handlers.TwoFactorHandler.Enroll)
+	twoFactor.Post("/confirm", handlers.TwoFactorHandler.Confirm)
+	twoFactor.Post("/disable", middleware.AuthMiddleware(nil), handlers.TwoFactorHandler.Disable)
+	twoFactor.Post("/recovery-codes", middleware.AuthMiddleware(nil), handlers.TwoFactorHandler.RegenerateRecoveryCodes)
 
-	v1.Use(middleware.AuthMiddleware())
-	users := v1.Group("/users")
+	v1.Use(middleware.AuthMiddleware(handlers.APIKeyAuth))
+	v1.Use(middleware.Idempotency(handlers.IdempotencyStore, handlers.Idempotency))
+	users := v1.Group("/users", middleware.ScopeMiddleware("users"))
 	users.Get("/", handlers.UserHandler.GetUsers)
+	users.Patch("/:id", middleware.RoleMiddleware("admin"), handlers.UserHandler.PatchUser)
+	users.Delete("/:id/lockout", middleware.RoleMiddleware("admin"), handlers.AuthHandler.UnlockAccount)
 
-	categories := v1.Group("/categories")
+	categories := v1.Group("/categories", middleware.ScopeMiddleware("categories"))
 	categories.Get("/", handlers.CategoryHandler.GetCategories)
+	categories.Patch("/:id", handlers.CategoryHandler.PatchCategory)
 
-	products := v1.Group("/products")
+	products := v1.Group("/products", middleware.ScopeMiddleware("products"))
 	products.Post("/", handlers.ProductHandler.AddProduct)
+	products.Post("/imports", handlers.ImportHandler.ImportProducts)
+	products.Get("/imports/:id", handlers.ImportHandler.GetImportJob)
+	products.Get("/imports/:id/errors", handlers.ImportHandler.GetImportErrors)
 	products.Get("/:id", handlers.ProductHandler.GetProductByID)
 	products.Get("/", handlers.ProductHandler.GetProducts)
-	products.Put("/", handlers.ProductHandler.UpdateProduct)
-	products.Delete("/", handlers.ProductHandler.DeleteProduct)
+	products.Put("/:id", handlers.ProductHandler.UpdateProduct)
+	products.Patch("/:id", handlers.ProductHandler.PatchProduct)
+	products.Delete("/:id", handlers.ProductHandler.DeleteProduct)
+	products.Post("/:id/batches", middleware.RoleMiddleware("admin", "pharmacist", middleware.RoleAPIKey), handlers.SaleHandler.ReceiveBatch)
 
-	suppliers := v1.Group("/suppliers")
+	suppliers := v1.Group("/suppliers", middleware.ScopeMiddleware("suppliers"))
 	suppliers.Get("/", handlers.SupplierHandler.GetSuppliers)
+	suppliers.Patch("/:id", handlers.SupplierHandler.PatchSupplier)
+
+	branches := v1.Group("/branches", middleware.ScopeMiddleware("branches"))
+	branches.Get("/", handlers.BranchHandler.GetBranches)
+	branches.Post("/", middleware.RoleMiddleware("admin"), handlers.BranchHandler.CreateBranch)
+
+	sales := v1.Group("/sales", middleware.ScopeMiddleware("sales"))
+	sales.Post("/", middleware.RoleMiddleware("admin", "pharmacist", "cashier", middleware.RoleAPIKey), handlers.SaleHandler.CreateSale)
+	sales.Get("/:id", handlers.SaleHandler.GetSaleByID)
+
+	reports := v1.Group("/reports", middleware.RoleMiddleware("admin"))
+	reports.Get("/sales", handlers.ReportHandler.GetSalesReport)
+	reports.Get("/products", handlers.ReportHandler.GetProductReport)
+	reports.Get("/categories", handlers.ReportHandler.GetCategoryReport)
+	reports.Get("/cashiers", handlers.ReportHandler.GetCashierReport)
+
+	roles := v1.Group("/roles", middleware.RoleMiddleware("admin"))
+	roles.Get("/2fa", handlers.TwoFactorHandler.GetRolePolicies)
+	roles.Put("/:role/2fa", handlers.TwoFactorHandler.SetRolePolicy)
+
+	apiKeys := v1.Group("/api-keys", middleware.RoleMiddleware("admin"))
+	apiKeys.Get("/", handlers.APIKeyHandler.GetAPIKeys)
+	apiKeys.Post("/", handlers.APIKeyHandler.CreateAPIKey)
+	apiKeys.Delete("/:id", handlers.APIKeyHandler.RevokeAPIKey)
+
+	auditLogs := v1.Group("/audit-logs", middleware.RoleMiddleware("admin"))
+	auditLogs.Get("/", handlers.AuditLogHandler.GetAuditLogs)
 }
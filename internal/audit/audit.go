@@ -0,0 +1,144 @@
+// Package audit carries the acting principal through the request context and
+// turns entity snapshots into the before/after documents stored in the audit
+// log.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionRevoke = "revoke"
+	ActionUnlock = "unlock"
+)
+
+type Actor struct {
+	UserID    *int64
+	Username  string
+	Role      string
+	IPAddress string
+	RequestID string
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by the audit middleware, or an
+// anonymous actor for unauthenticated calls such as registration.
+func ActorFromContext(ctx context.Context) *Actor {
+	if actor, ok := ctx.Value(actorKey{}).(*Actor); ok && actor != nil {
+		return actor
+	}
+	return &Actor{}
+}
+
+// redacted lists snake_case field names never written to the audit log.
+var redacted = map[string]bool{
+	"password": true,
+	"key_hash": true,
+	"secret":   true,
+}
+
+// Diff snapshots before and after, either of which may be nil, and keeps
+// only the fields that differ. Field names are converted to snake_case.
+func Diff(before, after any) (json.RawMessage, json.RawMessage, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		for name, value := range beforeFields {
+			if reflect.DeepEqual(value, afterFields[name]) {
+				delete(beforeFields, name)
+				delete(afterFields, name)
+			}
+		}
+		delete(beforeFields, "updated_at")
+		delete(afterFields, "updated_at")
+	}
+
+	beforeJSON, err := marshal(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterJSON, err := marshal(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+func fields(v any) (map[string]any, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(decoded))
+	for name, value := range decoded {
+		key := snakeCase(name)
+		if redacted[key] {
+			continue
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func marshal(fields map[string]any) (json.RawMessage, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
+
+func snakeCase(name string) string {
+	if strings.ContainsRune(name, '_') || strings.ToLower(name) == name {
+		return name
+	}
+
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(runes[i-1])
+			// A lone trailing "s" pluralizes an acronym (AllowedIPs) rather
+			// than starting a new word.
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1]) &&
+				!(i+2 == len(runes) && runes[i+1] == 's')
+			if i > 0 && (prevLower || (nextLower && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
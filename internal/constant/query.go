@@ -6,12 +6,12 @@ const (
 			users (username, full_name, email, password, role, created_at, updated_at)
 		VALUES
 			($1, $2, $3, $4, $5,$6, $7)
-		RETURNING id
+		RETURNING id, version
 	`
 
 	QGetByEmail = `
 		SELECT
-			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at
+			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at, version
 		FROM
 			users
 		WHERE
@@ -20,7 +20,7 @@ const (
 
 	QGetByID = `
 		SELECT
-			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at
+			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at, version
 		FROM
 			users
 		WHERE
@@ -29,16 +29,9 @@ const (
 
 	QGetAllUsers = `
 		SELECT
-			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at
+			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at, version
 		FROM
 			users
-		ORDER BY
-			updated_at
-		DESC
-		LIMIT
-			$1
-		OFFSET
-			$2
 	`
 
 	QCountUserQuery = `
@@ -50,16 +43,9 @@ const (
 
 	QGetAllCategories = `
 		SELECT
-			id, name, description, parent_category_id, created_at, updated_at, deleted_at
+			id, name, description, parent_category_id, created_at, updated_at, deleted_at, version
 		FROM
 			categories
-		ORDER BY
-			updated_at
-		DESC
-		LIMIT
-			$1
-		OFFSET
-			$2
 	`
 
 	QCountCategoryQuery = `
@@ -74,12 +60,12 @@ const (
 			products (name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at)
 		VALUES
 			($1, $2, $3, $4, $5,$6, $7, $8, $9, $10, $11, $12, $13, $14)
-		RETURNING id
+		RETURNING id, version
 	`
 
 	QGetProductByID = `
 		SELECT
-			id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at
+			id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at, version
 		FROM
 			products
 		WHERE
@@ -88,30 +74,24 @@ const (
 
 	QGetAllProducts = `
 		SELECT
-			id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at
+			id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at, version
 		FROM
 			products
-		ORDER BY
-			updated_at
-		DESC
-		LIMIT
-			$1
-		OFFSET
-			$2
 	`
 
 	QUpdateProduct = `
 		UPDATE
 			products
 		SET
-			name = $1, category_id = $2, generic_name = $3, description = $4, price = $5, stock = $6, unit = $7, expiration_date = $8, barcode = $9, supplier_id = $10, min_stock = $11, is_active = $12, updated_at = $13
-		WHERE id = $14
+			name = $1, category_id = $2, generic_name = $3, description = $4, price = $5, stock = $6, unit = $7, expiration_date = $8, barcode = $9, supplier_id = $10, min_stock = $11, is_active = $12, updated_at = $13, version = version + 1
+		WHERE id = $14 AND version = $15
+		RETURNING version
 	`
 
 	QDeleteProduct = `
 		DELETE FROM
 			products
-		WHERE id = $1
+		WHERE id = $1 AND version = $2
 	`
 
 	QCountProductQuery = `
@@ -123,21 +103,661 @@ const (
 
 	QGetAllSuppliers = `
 		SELECT
-			id, name, contact_person, phone, address, email, created_at, updated_at, deleted_at
+			id, name, contact_person, phone, address, email, created_at, updated_at, deleted_at, version
 		FROM
 			suppliers
+	`
+
+	QCountSupplierQuery = `
+		SELECT COUNT(*)
+		FROM
+			suppliers
+	`
+
+	QCreateLoginAttempt = `
+		INSERT INTO
+			login_attempts (user_id, email, ip_address, user_agent, success, reason, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	QGetLoginLockout = `
+		SELECT
+			key, failed_count, locked_until, last_failed_at, updated_at
+		FROM
+			login_lockouts
+		WHERE
+			key = $1
+	`
+
+	QRecordLoginFailure = `
+		INSERT INTO
+			login_lockouts (key, failed_count, last_failed_at, updated_at)
+		VALUES
+			($1, 1, $2, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET
+			failed_count = CASE WHEN login_lockouts.last_failed_at < $3 THEN 1 ELSE login_lockouts.failed_count + 1 END,
+			last_failed_at = $2,
+			updated_at = $2
+		RETURNING failed_count
+	`
+
+	QLockLogin = `
+		UPDATE
+			login_lockouts
+		SET
+			locked_until = $1, updated_at = $2
+		WHERE key = $3
+	`
+
+	QResetLoginLockout = `
+		DELETE FROM
+			login_lockouts
+		WHERE key = $1
+	`
+
+	QUnlockLogin = `
+		DELETE FROM
+			login_lockouts
+		WHERE key = $1
+		RETURNING
+			key, failed_count, locked_until, last_failed_at, updated_at
+	`
+
+	QGetUserTOTP = `
+		SELECT
+			user_id, secret, confirmed_at, last_used_step, created_at, updated_at
+		FROM
+			user_totp
+		WHERE
+			user_id = $1
+	`
+
+	QSaveUserTOTP = `
+		INSERT INTO
+			user_totp (user_id, secret, confirmed_at, last_used_step, created_at, updated_at)
+		VALUES
+			($1, $2, NULL, 0, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET
+			secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0, updated_at = EXCLUDED.updated_at
+	`
+
+	QConfirmUserTOTP = `
+		UPDATE
+			user_totp
+		SET
+			confirmed_at = $1, last_used_step = $2, updated_at = $1
+		WHERE user_id = $3 AND confirmed_at IS NULL
+	`
+
+	QMarkTOTPStepUsed = `
+		UPDATE
+			user_totp
+		SET
+			last_used_step = $1, updated_at = $2
+		WHERE user_id = $3 AND last_used_step < $1
+	`
+
+	QDeleteUserTOTP = `
+		DELETE FROM
+			user_totp
+		WHERE user_id = $1
+	`
+
+	QCreateRecoveryCode = `
+		INSERT INTO
+			user_recovery_codes (user_id, code_hash, created_at)
+		VALUES
+			($1, $2, $3)
+	`
+
+	QDeleteRecoveryCodes = `
+		DELETE FROM
+			user_recovery_codes
+		WHERE user_id = $1
+	`
+
+	QUseRecoveryCode = `
+		UPDATE
+			user_recovery_codes
+		SET
+			used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+	`
+
+	QGetRoleTwoFactorPolicy = `
+		SELECT
+			role, required, updated_at
+		FROM
+			role_two_factor_policies
+		WHERE
+			role = $1
+	`
+
+	QGetAllRoleTwoFactorPolicies = `
+		SELECT
+			role, required, updated_at
+		FROM
+			role_two_factor_policies
+		ORDER BY
+			role
+	`
+
+	QSaveRoleTwoFactorPolicy = `
+		INSERT INTO
+			role_two_factor_policies (role, required, updated_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (role) DO UPDATE
+		SET
+			required = EXCLUDED.required, updated_at = EXCLUDED.updated_at
+	`
+
+	QCreateAPIKey = `
+		INSERT INTO
+			api_keys (name, prefix, key_hash, scopes, allowed_ips, expires_at, created_by, created_at, updated_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	QGetAPIKeyByPrefix = `
+		SELECT
+			id, name, prefix, key_hash, scopes, allowed_ips, expires_at, last_used_at, last_used_ip, created_by, revoked_at, created_at, updated_at
+		FROM
+			api_keys
+		WHERE
+			prefix = $1
+	`
+
+	QGetAllAPIKeys = `
+		SELECT
+			id, name, prefix, key_hash, scopes, allowed_ips, expires_at, last_used_at, last_used_ip, created_by, revoked_at, created_at, updated_at
+		FROM
+			api_keys
 		ORDER BY
-			updated_at
+			created_at
 		DESC
+	`
+
+	QTouchAPIKey = `
+		UPDATE
+			api_keys
+		SET
+			last_used_at = $1, last_used_ip = $2
+		WHERE id = $3
+	`
+
+	QRevokeAPIKey = `
+		UPDATE
+			api_keys
+		SET
+			revoked_at = $1, updated_at = $1
+		WHERE id = $2 AND revoked_at IS NULL
+	`
+
+	QCreateAuditLog = `
+		INSERT INTO
+			audit_logs (actor_id, actor_name, actor_role, action, entity_type, entity_id, before, after, ip_address, request_id, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+
+	QGetAllAuditLogs = `
+		SELECT
+			id, actor_id, actor_name, actor_role, action, entity_type, entity_id, before, after, ip_address, request_id, created_at
+		FROM
+			audit_logs
+	`
+
+	QCountAuditLogQuery = `
+		SELECT
+			COUNT(*)
+		FROM
+			audit_logs
+	`
+
+	QGetProductByIDForUpdate = `
+		SELECT
+			id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at, version
+		FROM
+			products
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	QGetAPIKeyByIDForUpdate = `
+		SELECT
+			id, name, prefix, key_hash, scopes, allowed_ips, expires_at, last_used_at, last_used_ip, created_by, revoked_at, created_at, updated_at
+		FROM
+			api_keys
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	QGetRoleTwoFactorPolicyForUpdate = `
+		SELECT
+			role, required, updated_at
+		FROM
+			role_two_factor_policies
+		WHERE
+			role = $1
+		FOR UPDATE
+	`
+
+	QGetUserByIDForUpdate = `
+		SELECT
+			id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at, version
+		FROM
+			users
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	QGetCategoryByIDForUpdate = `
+		SELECT
+			id, name, description, parent_category_id, created_at, updated_at, deleted_at, version
+		FROM
+			categories
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	QGetSupplierByIDForUpdate = `
+		SELECT
+			id, name, contact_person, phone, address, email, created_at, updated_at, deleted_at, version
+		FROM
+			suppliers
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	// The patch queries are templates: the SET list and placeholder numbers
+	// are filled in from the fields present in the merge patch document.
+	QPatchProduct = `
+		UPDATE
+			products
+		SET
+			%s, updated_at = $%d, version = version + 1
+		WHERE id = $%d AND version = $%d
+		RETURNING id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at, version
+	`
+
+	QPatchCategory = `
+		UPDATE
+			categories
+		SET
+			%s, updated_at = $%d, version = version + 1
+		WHERE id = $%d AND version = $%d
+		RETURNING id, name, description, parent_category_id, created_at, updated_at, deleted_at, version
+	`
+
+	QPatchSupplier = `
+		UPDATE
+			suppliers
+		SET
+			%s, updated_at = $%d, version = version + 1
+		WHERE id = $%d AND version = $%d
+		RETURNING id, name, contact_person, phone, address, email, created_at, updated_at, deleted_at, version
+	`
+
+	QPatchUser = `
+		UPDATE
+			users
+		SET
+			%s, updated_at = $%d, version = version + 1
+		WHERE id = $%d AND version = $%d
+		RETURNING id, username, full_name, email, password, role, status, created_at, updated_at, deleted_at, version
+	`
+
+	// A key is taken over only when its window has passed or the request
+	// holding it never completed; otherwise RETURNING yields no row.
+	QReserveIdempotencyKey = `
+		INSERT INTO
+			idempotency_keys (scope, key, request_hash, created_at, expires_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET
+			request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, response_body = NULL,
+			created_at = EXCLUDED.created_at, completed_at = NULL, expires_at = EXCLUDED.expires_at
+		WHERE
+			idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= $6)
+		RETURNING key
+	`
+
+	QGetIdempotencyKey = `
+		SELECT
+			scope, key, request_hash, status_code, COALESCE(content_type, ''), response_body, created_at, completed_at, expires_at
+		FROM
+			idempotency_keys
+		WHERE
+			scope = $1 AND key = $2
+	`
+
+	QCompleteIdempotencyKey = `
+		UPDATE
+			idempotency_keys
+		SET
+			status_code = $1, content_type = $2, response_body = $3, completed_at = $4
+		WHERE scope = $5 AND key = $6
+	`
+
+	QReleaseIdempotencyKey = `
+		DELETE FROM
+			idempotency_keys
+		WHERE scope = $1 AND key = $2 AND status_code IS NULL
+	`
+
+	QPurgeIdempotencyKeys = `
+		DELETE FROM
+			idempotency_keys
+		WHERE expires_at <= $1
+	`
+
+	QCountInventoryAlerts = `
+		SELECT
+			COUNT(*) FILTER (WHERE stock <= min_stock),
+			COUNT(*) FILTER (WHERE stock > 0 AND expiration_date <= $1)
+		FROM
+			products
+		WHERE
+			is_active AND deleted_at IS NULL
+	`
+
+	QCategoryExists = `
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM
+				categories
+			WHERE
+				id = $1 AND deleted_at IS NULL
+		)
+	`
+
+	QSupplierExists = `
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM
+				suppliers
+			WHERE
+				id = $1 AND deleted_at IS NULL
+		)
+	`
+
+	QGetProductsByBarcode = `
+		SELECT
+			id, name, category_id, generic_name, description, price, stock, unit, expiration_date, barcode, supplier_id, min_stock, is_active, created_at, updated_at, deleted_at, version
+		FROM
+			products
+		WHERE
+			barcode = $1 AND deleted_at IS NULL
+		ORDER BY
+			id
 		LIMIT
-			$1
-		OFFSET
-			$2
+			2
 	`
 
-	QCountSupplierQuery = `
-		SELECT COUNT(*)
+	QGetCategoryNames = `
+		SELECT
+			id, name
+		FROM
+			categories
+		WHERE
+			deleted_at IS NULL
+	`
+
+	QGetSupplierNames = `
+		SELECT
+			id, name
 		FROM
 			suppliers
+		WHERE
+			deleted_at IS NULL
+	`
+
+	QCreateImportJob = `
+		INSERT INTO
+			import_jobs (entity_type, file_name, status, dry_run, total_rows, created_by, created_at, updated_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	QGetImportJobByID = `
+		SELECT
+			id, entity_type, file_name, status, dry_run, total_rows, processed_rows, created_rows, updated_rows, failed_rows, errors, failure, created_by, created_at, updated_at, finished_at
+		FROM
+			import_jobs
+		WHERE
+			id = $1
+	`
+
+	QUpdateImportJob = `
+		UPDATE
+			import_jobs
+		SET
+			status = $1, processed_rows = $2, created_rows = $3, updated_rows = $4, failed_rows = $5, errors = $6, failure = $7, updated_at = $8, finished_at = $9
+		WHERE
+			id = $10
+	`
+
+	QCreateBranch = `
+		INSERT INTO
+			branches (name, created_at, updated_at)
+		VALUES
+			($1, $2, $3)
+		RETURNING id
+	`
+
+	QGetAllBranches = `
+		SELECT
+			id, name, created_at, updated_at
+		FROM
+			branches
+		ORDER BY
+			name
+	`
+
+	QBranchExists = `
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM
+				branches
+			WHERE
+				id = $1
+		)
+	`
+
+	QAdjustProductStock = `
+		UPDATE
+			products
+		SET
+			stock = stock + $1, updated_at = $2, version = version + 1
+		WHERE
+			id = $3 AND stock + $1 >= 0
+		RETURNING stock, version
+	`
+
+	QCreateProductBatch = `
+		INSERT INTO
+			product_batches (product_id, branch_id, batch_number, cost_price, quantity_received, quantity_remaining, expiration_date, received_by, received_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	// QGetSellableBatches locks the unexpired batches of a product at a
+	// branch, soonest to expire first.
+	QGetSellableBatches = `
+		SELECT
+			id, product_id, branch_id, batch_number, cost_price, quantity_received, quantity_remaining, expiration_date, received_by, received_at
+		FROM
+			product_batches
+		WHERE
+			product_id = $1 AND branch_id = $2 AND quantity_remaining > 0 AND expiration_date >= $3::date
+		ORDER BY
+			expiration_date, id
+		FOR UPDATE
+	`
+
+	QGetBatchedStock = `
+		SELECT
+			COALESCE(SUM(quantity_remaining), 0)
+		FROM
+			product_batches
+		WHERE
+			product_id = $1
+	`
+
+	QConsumeProductBatch = `
+		UPDATE
+			product_batches
+		SET
+			quantity_remaining = quantity_remaining - $1
+		WHERE
+			id = $2 AND quantity_remaining >= $1
+	`
+
+	QCreateSale = `
+		INSERT INTO
+			sales (branch_id, cashier_id, total_amount, total_cost, sold_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	QCreateSaleItem = `
+		INSERT INTO
+			sale_items (sale_id, product_id, batch_id, quantity, unit_price, unit_cost)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	QGetSaleByID = `
+		SELECT
+			id, branch_id, cashier_id, total_amount, total_cost, sold_at
+		FROM
+			sales
+		WHERE
+			id = $1
+	`
+
+	QGetSaleItems = `
+		SELECT
+			id, sale_id, product_id, batch_id, quantity, unit_price, unit_cost
+		FROM
+			sale_items
+		WHERE
+			sale_id = $1
+		ORDER BY
+			id
+	`
+
+	// The report queries select sales with start, end and an optional
+	// branch ID, in that order. A NULL branch ID selects every branch.
+
+	QReportSalesByPeriod = `
+		SELECT
+			date_trunc($1::text, s.sold_at AT TIME ZONE $2::text)::date AS period,
+			COUNT(DISTINCT s.id),
+			SUM(i.quantity),
+			SUM(i.quantity * i.unit_price),
+			SUM(i.quantity * i.unit_cost)
+		FROM
+			sales s
+			JOIN sale_items i ON i.sale_id = s.id
+		WHERE
+			s.sold_at >= $3 AND s.sold_at < $4 AND ($5::bigint IS NULL OR s.branch_id = $5)
+		GROUP BY
+			period
+		ORDER BY
+			period
+	`
+
+	QReportTopProducts = `
+		SELECT
+			p.id, p.name, SUM(i.quantity) AS units, SUM(i.quantity * i.unit_price) AS revenue, SUM(i.quantity * i.unit_cost)
+		FROM
+			sale_items i
+			JOIN sales s ON s.id = i.sale_id
+			JOIN products p ON p.id = i.product_id
+		WHERE
+			s.sold_at >= $1 AND s.sold_at < $2 AND ($3::bigint IS NULL OR s.branch_id = $3)
+		GROUP BY
+			p.id, p.name
+		ORDER BY
+			units DESC, revenue DESC, p.id
+		LIMIT $4
+	`
+
+	// QReportSlowProducts includes active products that did not sell at all,
+	// which are the slowest movers.
+	QReportSlowProducts = `
+		SELECT
+			p.id, p.name, COALESCE(SUM(t.quantity), 0) AS units, COALESCE(SUM(t.quantity * t.unit_price), 0) AS revenue, COALESCE(SUM(t.quantity * t.unit_cost), 0)
+		FROM
+			products p
+			LEFT JOIN (
+				SELECT
+					i.product_id, i.quantity, i.unit_price, i.unit_cost
+				FROM
+					sale_items i
+					JOIN sales s ON s.id = i.sale_id
+				WHERE
+					s.sold_at >= $1 AND s.sold_at < $2 AND ($3::bigint IS NULL OR s.branch_id = $3)
+			) t ON t.product_id = p.id
+		WHERE
+			p.is_active AND p.deleted_at IS NULL
+		GROUP BY
+			p.id, p.name
+		ORDER BY
+			units, revenue, p.id
+		LIMIT $4
+	`
+
+	QReportSalesByCategory = `
+		SELECT
+			c.id, c.name, SUM(i.quantity), SUM(i.quantity * i.unit_price) AS revenue, SUM(i.quantity * i.unit_cost)
+		FROM
+			sale_items i
+			JOIN sales s ON s.id = i.sale_id
+			JOIN products p ON p.id = i.product_id
+			JOIN categories c ON c.id = p.category_id
+		WHERE
+			s.sold_at >= $1 AND s.sold_at < $2 AND ($3::bigint IS NULL OR s.branch_id = $3)
+		GROUP BY
+			c.id, c.name
+		ORDER BY
+			revenue DESC, c.id
+	`
+
+	QReportSalesByCashier = `
+		SELECT
+			u.id, u.username, u.full_name, COUNT(DISTINCT s.id), SUM(i.quantity), SUM(i.quantity * i.unit_price) AS revenue, SUM(i.quantity * i.unit_cost)
+		FROM
+			sales s
+			JOIN sale_items i ON i.sale_id = s.id
+			JOIN users u ON u.id = s.cashier_id
+		WHERE
+			s.sold_at >= $1 AND s.sold_at < $2 AND ($3::bigint IS NULL OR s.branch_id = $3)
+		GROUP BY
+			u.id, u.username, u.full_name
+		ORDER BY
+			revenue DESC, u.id
 	`
 )
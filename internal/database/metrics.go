@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/metrics"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	at        time.Time
+	operation string
+	table     string
+}
+
+// queryTracer records the latency of every statement the repositories run,
+// labelled by SQL verb and the main table so the label set stays small.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation, table := classifyQuery(data.SQL)
+	return context.WithValue(ctx, queryStartKey{}, queryStart{at: time.Now(), operation: operation, table: table})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	outcome := "success"
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		outcome = "error"
+	}
+	metrics.DBQueryDuration.WithLabelValues(start.operation, start.table, outcome).Observe(time.Since(start.at).Seconds())
+}
+
+var queryOperations = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true,
+	"begin": true, "commit": true, "rollback": true, "savepoint": true, "release": true,
+}
+
+func classifyQuery(sql string) (string, string) {
+	fields := strings.Fields(strings.ToLower(sql))
+	if len(fields) == 0 {
+		return "other", "none"
+	}
+
+	operation := fields[0]
+	if !queryOperations[operation] {
+		operation = "other"
+	}
+
+	for i, field := range fields[:len(fields)-1] {
+		if field == "from" || field == "into" || (field == "update" && i == 0) {
+			return operation, strings.Trim(fields[i+1], "(),;")
+		}
+	}
+	return operation, "none"
+}
+
+var (
+	poolConnsDesc = prometheus.NewDesc("pharmly_db_pool_connections",
+		"Connections in the pool by state.", []string{"state"}, nil)
+	poolMaxConnsDesc = prometheus.NewDesc("pharmly_db_pool_max_connections",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquiresDesc = prometheus.NewDesc("pharmly_db_pool_acquires_total",
+		"Connection acquires by result.", []string{"result"}, nil)
+	poolAcquireSecondsDesc = prometheus.NewDesc("pharmly_db_pool_acquire_duration_seconds_total",
+		"Total time spent waiting to acquire connections.", nil, nil)
+	poolDestroyedDesc = prometheus.NewDesc("pharmly_db_pool_destroyed_connections_total",
+		"Connections closed by the pool by reason.", []string{"reason"}, nil)
+)
+
+type poolCollector struct {
+	db *PostgresDB
+}
+
+// Collector exposes the pool statistics from Stats to Prometheus, read at
+// scrape time.
+func (db *PostgresDB) Collector() prometheus.Collector {
+	return poolCollector{db: db}
+}
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnsDesc
+	ch <- poolMaxConnsDesc
+	ch <- poolAcquiresDesc
+	ch <- poolAcquireSecondsDesc
+	ch <- poolDestroyedDesc
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stats.AcquiredConns), "acquired")
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stats.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(poolConnsDesc, prometheus.GaugeValue, float64(stats.ConstructingConns), "constructing")
+	ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(stats.MaxConns))
+
+	succeeded := stats.AcquireCount - stats.EmptyAcquireCount
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(succeeded), "immediate")
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stats.EmptyAcquireCount), "waited")
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stats.CanceledAcquireCount), "canceled")
+	ch <- prometheus.MustNewConstMetric(poolAcquireSecondsDesc, prometheus.CounterValue, stats.AcquireDuration.Seconds())
+
+	ch <- prometheus.MustNewConstMetric(poolDestroyedDesc, prometheus.CounterValue, float64(stats.MaxLifetimeDestroyCount), "max_lifetime")
+	ch <- prometheus.MustNewConstMetric(poolDestroyedDesc, prometheus.CounterValue, float64(stats.MaxIdleDestroyCount), "max_idle")
+}
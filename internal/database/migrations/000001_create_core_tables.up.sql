@@ -0,0 +1,60 @@
+CREATE TABLE users (
+    id          BIGSERIAL PRIMARY KEY,
+    username    VARCHAR(50)  NOT NULL UNIQUE,
+    full_name   VARCHAR(150) NOT NULL,
+    email       VARCHAR(255) NOT NULL UNIQUE,
+    password    VARCHAR(255) NOT NULL,
+    role        VARCHAR(20)  NOT NULL CHECK (role IN ('admin', 'pharmacist', 'cashier')),
+    status      VARCHAR(20)  NOT NULL DEFAULT 'active',
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    deleted_at  TIMESTAMPTZ
+);
+
+CREATE TABLE categories (
+    id                  BIGSERIAL PRIMARY KEY,
+    name                VARCHAR(100) NOT NULL,
+    description         TEXT         NOT NULL DEFAULT '',
+    parent_category_id  BIGINT REFERENCES categories (id) ON DELETE SET NULL,
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    deleted_at          TIMESTAMPTZ
+);
+
+CREATE TABLE suppliers (
+    id              BIGSERIAL PRIMARY KEY,
+    name            VARCHAR(150) NOT NULL,
+    contact_person  VARCHAR(150),
+    phone           VARCHAR(30),
+    address         TEXT,
+    email           VARCHAR(255),
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    deleted_at      TIMESTAMPTZ
+);
+
+CREATE TABLE products (
+    id               BIGSERIAL PRIMARY KEY,
+    name             VARCHAR(150)   NOT NULL,
+    category_id      BIGINT         NOT NULL REFERENCES categories (id),
+    generic_name     VARCHAR(150)   NOT NULL DEFAULT '',
+    description      TEXT,
+    price            NUMERIC(14, 2) NOT NULL CHECK (price >= 0),
+    stock            INTEGER        NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    unit             VARCHAR(30)    NOT NULL,
+    expiration_date  DATE           NOT NULL,
+    barcode          VARCHAR(64)    NOT NULL DEFAULT '',
+    supplier_id      BIGINT         NOT NULL REFERENCES suppliers (id),
+    min_stock        INTEGER        NOT NULL DEFAULT 0 CHECK (min_stock >= 0),
+    is_active        BOOLEAN        NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    deleted_at       TIMESTAMPTZ
+);
+
+CREATE INDEX idx_products_category_id ON products (category_id);
+CREATE INDEX idx_products_supplier_id ON products (supplier_id);
+CREATE INDEX idx_products_updated_at ON products (updated_at DESC);
+CREATE INDEX idx_categories_updated_at ON categories (updated_at DESC);
+CREATE INDEX idx_suppliers_updated_at ON suppliers (updated_at DESC);
+CREATE INDEX idx_users_updated_at ON users (updated_at DESC);
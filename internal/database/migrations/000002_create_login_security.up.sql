@@ -0,0 +1,21 @@
+CREATE TABLE login_attempts (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    email       VARCHAR(255) NOT NULL,
+    ip_address  VARCHAR(64)  NOT NULL,
+    user_agent  TEXT         NOT NULL DEFAULT '',
+    success     BOOLEAN      NOT NULL,
+    reason      VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_attempts_email_created_at ON login_attempts (email, created_at DESC);
+CREATE INDEX idx_login_attempts_ip_created_at ON login_attempts (ip_address, created_at DESC);
+
+CREATE TABLE login_lockouts (
+    key             VARCHAR(300) PRIMARY KEY,
+    failed_count    INTEGER      NOT NULL DEFAULT 0,
+    locked_until    TIMESTAMPTZ,
+    last_failed_at  TIMESTAMPTZ  NOT NULL,
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
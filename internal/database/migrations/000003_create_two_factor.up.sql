@@ -0,0 +1,24 @@
+CREATE TABLE user_totp (
+    user_id         BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret          TEXT         NOT NULL,
+    confirmed_at    TIMESTAMPTZ,
+    last_used_step  BIGINT       NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE user_recovery_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash   CHAR(64)    NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes (user_id);
+
+CREATE TABLE role_two_factor_policies (
+    role        VARCHAR(20) PRIMARY KEY,
+    required    BOOLEAN     NOT NULL DEFAULT FALSE,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
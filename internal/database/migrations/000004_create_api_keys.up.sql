@@ -0,0 +1,15 @@
+CREATE TABLE api_keys (
+    id            BIGSERIAL PRIMARY KEY,
+    name          VARCHAR(100) NOT NULL,
+    prefix        VARCHAR(16)  NOT NULL UNIQUE,
+    key_hash      CHAR(64)     NOT NULL,
+    scopes        TEXT[]       NOT NULL DEFAULT '{}',
+    allowed_ips   TEXT[]       NOT NULL DEFAULT '{}',
+    expires_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    last_used_ip  VARCHAR(64),
+    created_by    BIGINT       NOT NULL REFERENCES users (id),
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
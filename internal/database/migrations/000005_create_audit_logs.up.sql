@@ -0,0 +1,18 @@
+CREATE TABLE audit_logs (
+    id           BIGSERIAL PRIMARY KEY,
+    actor_id     BIGINT,
+    actor_name   VARCHAR(150) NOT NULL DEFAULT '',
+    actor_role   VARCHAR(20)  NOT NULL DEFAULT '',
+    action       VARCHAR(20)  NOT NULL,
+    entity_type  VARCHAR(50)  NOT NULL,
+    entity_id    VARCHAR(100) NOT NULL,
+    before       JSONB,
+    after        JSONB,
+    ip_address   VARCHAR(64)  NOT NULL DEFAULT '',
+    request_id   VARCHAR(100) NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at DESC);
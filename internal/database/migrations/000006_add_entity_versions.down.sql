@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+ALTER TABLE suppliers DROP COLUMN IF EXISTS version;
+ALTER TABLE categories DROP COLUMN IF EXISTS version;
+ALTER TABLE products DROP COLUMN IF EXISTS version;
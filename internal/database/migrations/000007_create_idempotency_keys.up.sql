@@ -0,0 +1,14 @@
+CREATE TABLE idempotency_keys (
+    scope          VARCHAR(100) NOT NULL,
+    key            VARCHAR(255) NOT NULL,
+    request_hash   CHAR(64)     NOT NULL,
+    status_code    INTEGER,
+    content_type   VARCHAR(255),
+    response_body  BYTEA,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    completed_at   TIMESTAMPTZ,
+    expires_at     TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
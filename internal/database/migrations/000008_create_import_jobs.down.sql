@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_products_barcode;
+DROP TABLE IF EXISTS import_jobs;
@@ -0,0 +1,20 @@
+CREATE TABLE import_jobs (
+    id              BIGSERIAL PRIMARY KEY,
+    entity_type     VARCHAR(50)  NOT NULL,
+    file_name       VARCHAR(255) NOT NULL,
+    status          VARCHAR(20)  NOT NULL CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    dry_run         BOOLEAN      NOT NULL DEFAULT FALSE,
+    total_rows      INTEGER      NOT NULL DEFAULT 0,
+    processed_rows  INTEGER      NOT NULL DEFAULT 0,
+    created_rows    INTEGER      NOT NULL DEFAULT 0,
+    updated_rows    INTEGER      NOT NULL DEFAULT 0,
+    failed_rows     INTEGER      NOT NULL DEFAULT 0,
+    errors          JSONB        NOT NULL DEFAULT '[]',
+    failure         TEXT,
+    created_by      BIGINT       NOT NULL REFERENCES users (id),
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    finished_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_products_barcode ON products (barcode) WHERE barcode <> '' AND deleted_at IS NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS sale_items;
+DROP TABLE IF EXISTS sales;
+DROP TABLE IF EXISTS product_batches;
+DROP TABLE IF EXISTS branches;
@@ -0,0 +1,49 @@
+CREATE TABLE branches (
+    id          BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(100) NOT NULL UNIQUE,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO branches (name) VALUES ('Main');
+
+CREATE TABLE product_batches (
+    id                  BIGSERIAL PRIMARY KEY,
+    product_id          BIGINT         NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    branch_id           BIGINT         NOT NULL REFERENCES branches (id),
+    batch_number        VARCHAR(50)    NOT NULL,
+    cost_price          NUMERIC(14, 2) NOT NULL CHECK (cost_price >= 0),
+    quantity_received   INTEGER        NOT NULL CHECK (quantity_received > 0),
+    quantity_remaining  INTEGER        NOT NULL CHECK (quantity_remaining >= 0 AND quantity_remaining <= quantity_received),
+    expiration_date     DATE           NOT NULL,
+    received_by         BIGINT         NOT NULL REFERENCES users (id),
+    received_at         TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_product_batches_sellable ON product_batches (product_id, branch_id, expiration_date) WHERE quantity_remaining > 0;
+
+CREATE TABLE sales (
+    id            BIGSERIAL PRIMARY KEY,
+    branch_id     BIGINT         NOT NULL REFERENCES branches (id),
+    cashier_id    BIGINT         NOT NULL REFERENCES users (id),
+    total_amount  NUMERIC(14, 2) NOT NULL,
+    total_cost    NUMERIC(14, 2) NOT NULL,
+    sold_at       TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sales_sold_at ON sales (sold_at);
+CREATE INDEX idx_sales_branch_id_sold_at ON sales (branch_id, sold_at);
+CREATE INDEX idx_sales_cashier_id_sold_at ON sales (cashier_id, sold_at);
+
+CREATE TABLE sale_items (
+    id          BIGSERIAL PRIMARY KEY,
+    sale_id     BIGINT         NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    product_id  BIGINT         NOT NULL REFERENCES products (id),
+    batch_id    BIGINT         REFERENCES product_batches (id),
+    quantity    INTEGER        NOT NULL CHECK (quantity > 0),
+    unit_price  NUMERIC(14, 2) NOT NULL,
+    unit_cost   NUMERIC(14, 2) NOT NULL
+);
+
+CREATE INDEX idx_sale_items_sale_id ON sale_items (sale_id);
+CREATE INDEX idx_sale_items_product_id ON sale_items (product_id);
@@ -3,31 +3,111 @@ package database
 import (
 	"context"
 	"fmt"
-	"os"
+	"net"
+	"net/url"
+	"pharmly-backend/config"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostgresDB struct {
-	*pgx.Conn
+	*pgxpool.Pool
 }
 
-func NewPostgresDB() (*PostgresDB, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_NAME"))
+// PoolStats is a point-in-time snapshot of the connection pool, exposed for
+// monitoring.
+type PoolStats struct {
+	TotalConns              int32         `json:"total_conns"`
+	AcquiredConns           int32         `json:"acquired_conns"`
+	IdleConns               int32         `json:"idle_conns"`
+	ConstructingConns       int32         `json:"constructing_conns"`
+	MaxConns                int32         `json:"max_conns"`
+	AcquireCount            int64         `json:"acquire_count"`
+	AcquireDuration         time.Duration `json:"acquire_duration"`
+	EmptyAcquireCount       int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64         `json:"canceled_acquire_count"`
+	NewConnsCount           int64         `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64         `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64         `json:"max_idle_destroy_count"`
+}
+
+// NewPostgresDB opens a connection pool and verifies it with a ping. The
+// configuration is expected to have been validated by config.Load.
+func NewPostgresDB(cfg config.DatabaseConfig) (*PostgresDB, error) {
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password.Value()),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(connURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %v", err)
+	}
+
+	mode, err := parseStatementCacheMode(cfg.StatementCacheMode)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	poolConfig.ConnConfig.Tracer = chainTracer{queryTracer{}, spanTracer{}}
 
-	conn, err := pgx.Connect(context.Background(), connStr)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %v", err)
 	}
 
-	return &PostgresDB{conn}, nil
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("unable to connect to database: %v", err)
+	}
+
+	return &PostgresDB{pool}, nil
+}
+
+func parseStatementCacheMode(mode string) (pgx.QueryExecMode, error) {
+	switch mode {
+	case "", "prepare":
+		return pgx.QueryExecModeCacheStatement, nil
+	case "describe":
+		return pgx.QueryExecModeCacheDescribe, nil
+	case "exec":
+		return pgx.QueryExecModeExec, nil
+	case "simple_protocol":
+		return pgx.QueryExecModeSimpleProtocol, nil
+	}
+	return 0, fmt.Errorf("invalid DB_STATEMENT_CACHE_MODE %q", mode)
 }
 
 func (db *PostgresDB) BeginTx(ctx context.Context) (pgx.Tx, error) {
-	return db.Conn.BeginTx(ctx, pgx.TxOptions{})
+	return db.Pool.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (db *PostgresDB) Stats() PoolStats {
+	stat := db.Pool.Stat()
+	return PoolStats{
+		TotalConns:              stat.TotalConns(),
+		AcquiredConns:           stat.AcquiredConns(),
+		IdleConns:               stat.IdleConns(),
+		ConstructingConns:       stat.ConstructingConns(),
+		MaxConns:                stat.MaxConns(),
+		AcquireCount:            stat.AcquireCount(),
+		AcquireDuration:         stat.AcquireDuration(),
+		EmptyAcquireCount:       stat.EmptyAcquireCount(),
+		CanceledAcquireCount:    stat.CanceledAcquireCount(),
+		NewConnsCount:           stat.NewConnsCount(),
+		MaxLifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     stat.MaxIdleDestroyCount(),
+	}
 }
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanTracer starts a client span for every statement. Query arguments are
+// never recorded since they can hold credentials and personal data.
+type spanTracer struct{}
+
+func (spanTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation, table := classifyQuery(data.SQL)
+
+	name := operation
+	if table != "none" {
+		name += " " + table
+	}
+
+	ctx, _ = tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (spanTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if !errors.Is(data.Err, pgx.ErrNoRows) {
+		tracing.RecordError(span, data.Err)
+	}
+	span.End()
+}
+
+// chainTracer lets several tracers observe each statement. Each start hook
+// receives the context returned by the previous one.
+type chainTracer []pgx.QueryTracer
+
+func (t chainTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, tracer := range t {
+		ctx = tracer.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (t chainTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for i := len(t) - 1; i >= 0; i-- {
+		t[i].TraceQueryEnd(ctx, conn, data)
+	}
+}
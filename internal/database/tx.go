@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type txKey struct{}
+
+// Transactor runs fn inside a transaction carried by the context passed to
+// fn. Repositories called with that context join the transaction instead of
+// starting their own.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+type txConfig struct {
+	options    pgx.TxOptions
+	maxRetries int
+	backoff    time.Duration
+}
+
+type TxOption func(*txConfig)
+
+// WithIsolation sets the isolation level. It only applies to the outermost
+// transaction; nested calls run in a savepoint of the outer one.
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(c *txConfig) { c.options.IsoLevel = level }
+}
+
+func WithReadOnly() TxOption {
+	return func(c *txConfig) { c.options.AccessMode = pgx.ReadOnly }
+}
+
+// WithMaxRetries sets how often fn is re-run after a serialization failure
+// or deadlock, so fn must be safe to run more than once.
+func WithMaxRetries(n int) TxOption {
+	return func(c *txConfig) { c.maxRetries = n }
+}
+
+type TxManager struct {
+	pool *pgxpool.Pool
+}
+
+func NewTxManager(pool *pgxpool.Pool) *TxManager {
+	return &TxManager{pool: pool}
+}
+
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if tx, ok := TxFromContext(ctx); ok {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(context.WithValue(ctx, txKey{}, savepoint)); err != nil {
+			savepoint.Rollback(ctx)
+			return err
+		}
+		return savepoint.Commit(ctx)
+	}
+
+	cfg := txConfig{maxRetries: 3, backoff: 20 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = m.runOnce(ctx, cfg.options, fn)
+		if !IsRetryable(err) || attempt >= cfg.maxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.backoff << attempt):
+		}
+	}
+}
+
+func (m *TxManager) runOnce(ctx context.Context, options pgx.TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := m.pool.BeginTx(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// BeginTx starts the transaction a repository method works in. Inside
+// WithinTransaction it opens a savepoint on the ambient transaction, so the
+// repository's own Commit only releases the savepoint and the outer unit of
+// work decides the final outcome.
+func BeginTx(ctx context.Context, pool *pgxpool.Pool) (pgx.Tx, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.Begin(ctx)
+	}
+	return pool.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock,
+// after which the whole transaction can safely be retried.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+type APIKeyRequest struct {
+	Name       string     `json:"name" validate:"required,min=3,max=100"`
+	Scopes     []string   `json:"scopes" validate:"required,min=1,dive,oneof=products:read products:write categories:read suppliers:read users:read"`
+	AllowedIPs []string   `json:"allowed_ips" validate:"omitempty,dive,cidr|ip"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" validate:"omitempty,future_on_create"`
+}
+
+type APIKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	CreatedBy  int64      `json:"created_by"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyCreatedResponse is the only response that ever carries the full key.
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
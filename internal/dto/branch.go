@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type BranchRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type BranchResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -6,9 +6,15 @@ import (
 )
 
 type CategoryRequest struct {
-	Name             string `json:"name"`
-	Description      string `json:"description"`
-	ParentCategoryID int64  `json:"parent_category_id,omitempty"`
+	Name             string `json:"name" validate:"required,max=100"`
+	Description      string `json:"description" validate:"max=1000"`
+	ParentCategoryID int64  `json:"parent_category_id,omitempty" validate:"omitempty,gt=0,category_exists"`
+}
+
+type CategoryPatchRequest struct {
+	Name             Optional[string] `json:"name" validate:"omitempty,min=1,max=100"`
+	Description      Optional[string] `json:"description" validate:"omitempty,max=1000"`
+	ParentCategoryID Nullable[int64]  `json:"parent_category_id" validate:"omitempty,gt=0,category_exists"`
 }
 
 type CategoryResponse struct {
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+// ProductImportRequest describes an uploaded product file. Mapping names the
+// file column to read for each import field; fields left out are read from
+// the column with the same name.
+type ProductImportRequest struct {
+	FileName string            `json:"file_name" validate:"required,max=255"`
+	DryRun   bool              `json:"dry_run"`
+	Mapping  map[string]string `json:"mapping" validate:"omitempty,dive,keys,oneof=name category generic_name description price stock unit expiration_date barcode supplier min_stock is_active,endkeys,required,max=100"`
+}
+
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportJobResponse struct {
+	ID            int64            `json:"id"`
+	EntityType    string           `json:"entity_type"`
+	FileName      string           `json:"file_name"`
+	Status        string           `json:"status"`
+	DryRun        bool             `json:"dry_run"`
+	TotalRows     int              `json:"total_rows"`
+	ProcessedRows int              `json:"processed_rows"`
+	CreatedRows   int              `json:"created_rows"`
+	UpdatedRows   int              `json:"updated_rows"`
+	FailedRows    int              `json:"failed_rows"`
+	Errors        []ImportRowError `json:"errors"`
+	Failure       *string          `json:"failure,omitempty"`
+	CreatedBy     int64            `json:"created_by"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	FinishedAt    *time.Time       `json:"finished_at"`
+}
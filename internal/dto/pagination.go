@@ -1,17 +1,53 @@
 package dto
 
+const DefaultPageSize = 20
+
+// PaginationRequest holds the query parameters shared by all list endpoints.
+// A list is read either by page number or, for large lists, by the opaque
+// after/before cursors returned with the previous page, which do not slow
+// down as the client moves further into the list.
 type PaginationRequest struct {
-	Page     int `query:"page" validate:"min=1"`
-	PageSize int `query:"page_size" validate:"min=1,max=100"`
+	Page     int    `query:"page" validate:"omitempty,min=1,excluded_with=After Before"`
+	PageSize int    `query:"page_size" validate:"omitempty,min=1,max=100"`
+	After    string `query:"after" validate:"omitempty,max=128,excluded_with=Before"`
+	Before   string `query:"before" validate:"omitempty,max=128"`
+	// IncludeTotal requests total_items and total_pages. It defaults to true
+	// for page numbers and false for cursors, as counting scans every row.
+	IncludeTotal *bool `query:"include_total"`
+}
+
+// SetDefaults fills in the page and page size when they were not given.
+func (r *PaginationRequest) SetDefaults() {
+	if r.Page == 0 {
+		r.Page = 1
+	}
+	if r.PageSize == 0 {
+		r.PageSize = DefaultPageSize
+	}
+}
+
+// UsesCursor reports whether the request pages by cursor rather than page
+// number.
+func (r *PaginationRequest) UsesCursor() bool {
+	return r.After != "" || r.Before != ""
+}
+
+func (r *PaginationRequest) WithTotal() bool {
+	if r.IncludeTotal != nil {
+		return *r.IncludeTotal
+	}
+	return !r.UsesCursor()
 }
 
 type PaginationResponse struct {
-	TotalItems   int64 `json:"total_items"`
-	TotalPages   int   `json:"total_pages"`
-	CurrentPage  int   `json:"current_page"`
-	PageSize     int   `json:"page_size"`
-	HasNextPage  bool  `json:"has_next_page"`
-	HasPrevPage  bool  `json:"has_prev_page"`
-	NextPage     *int  `json:"next_page,omitempty"`
-	PreviousPage *int  `json:"previous_page,omitempty"`
+	TotalItems   *int64 `json:"total_items,omitempty"`
+	TotalPages   *int   `json:"total_pages,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size"`
+	HasNextPage  bool   `json:"has_next_page"`
+	HasPrevPage  bool   `json:"has_prev_page"`
+	NextPage     *int   `json:"next_page,omitempty"`
+	PreviousPage *int   `json:"previous_page,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
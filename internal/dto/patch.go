@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNullNotAllowed is returned when a JSON Merge Patch sets a required
+// field to null.
+var ErrNullNotAllowed = errors.New("null is not allowed for this field")
+
+// PatchField is implemented by the field types of merge patch requests. It
+// exposes a pointer to the supplied value to the validator, or nil when the
+// field was absent or null, so that "omitempty" skips only unsupplied fields
+// and explicit zero values are still validated.
+type PatchField interface {
+	PatchValue() any
+}
+
+// Optional is a merge patch field for a column that cannot be null. Set
+// reports whether the field appeared in the document.
+type Optional[T any] struct {
+	Set   bool
+	Value T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return ErrNullNotAllowed
+	}
+
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+func (o Optional[T]) PatchValue() any {
+	if !o.Set {
+		return nil
+	}
+	return &o.Value
+}
+
+// Nullable is a merge patch field for a nullable column. An explicit null
+// sets Set and Null and clears the column.
+type Nullable[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
+
+func (n Nullable[T]) PatchValue() any {
+	if !n.Set || n.Null {
+		return nil
+	}
+	return &n.Value
+}
+
+// Ptr returns the patched value as a pointer, nil for an explicit null.
+func (n Nullable[T]) Ptr() *T {
+	if n.Null {
+		return nil
+	}
+	return &n.Value
+}
@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testPatch struct {
+	Name        Optional[string] `json:"name"`
+	Stock       Optional[int]    `json:"stock"`
+	Description Nullable[string] `json:"description"`
+}
+
+func TestMergePatchDecoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    testPatch
+		wantErr error
+	}{
+		{
+			name: "absent fields stay unset",
+			body: `{}`,
+			want: testPatch{},
+		},
+		{
+			name: "supplied values are set",
+			body: `{"name":"Paracetamol","stock":0,"description":"Tablets"}`,
+			want: testPatch{
+				Name:        Optional[string]{Set: true, Value: "Paracetamol"},
+				Stock:       Optional[int]{Set: true, Value: 0},
+				Description: Nullable[string]{Set: true, Value: "Tablets"},
+			},
+		},
+		{
+			name: "null clears a nullable field",
+			body: `{"description":null}`,
+			want: testPatch{Description: Nullable[string]{Set: true, Null: true}},
+		},
+		{
+			name:    "null is rejected for a required field",
+			body:    `{"name":null}`,
+			wantErr: ErrNullNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testPatch
+			err := json.Unmarshal([]byte(tt.body), &got)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Unmarshal() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchWrongType(t *testing.T) {
+	var got testPatch
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal([]byte(`{"stock":"ten"}`), &got); !errors.As(err, &typeErr) {
+		t.Errorf("Unmarshal() error = %v, want *json.UnmarshalTypeError", err)
+	}
+}
+
+func TestPatchValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		field PatchField
+		want  any
+	}{
+		{"unset optional", Optional[int]{}, nil},
+		{"set optional zero", Optional[int]{Set: true}, 0},
+		{"unset nullable", Nullable[string]{}, nil},
+		{"null nullable", Nullable[string]{Set: true, Null: true}, nil},
+		{"set nullable", Nullable[string]{Set: true, Value: "x"}, "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.field.PatchValue()
+			switch value := got.(type) {
+			case nil:
+				if tt.want != nil {
+					t.Errorf("PatchValue() = nil, want %v", tt.want)
+				}
+			case *int:
+				if tt.want != *value {
+					t.Errorf("PatchValue() = %v, want %v", *value, tt.want)
+				}
+			case *string:
+				if tt.want != *value {
+					t.Errorf("PatchValue() = %v, want %v", *value, tt.want)
+				}
+			default:
+				t.Errorf("PatchValue() = %T, want a pointer", got)
+			}
+		})
+	}
+
+	if ptr := (Nullable[string]{Set: true, Null: true}).Ptr(); ptr != nil {
+		t.Errorf("Ptr() of null = %v, want nil", *ptr)
+	}
+	if ptr := (Nullable[string]{Set: true, Value: "x"}).Ptr(); ptr == nil || *ptr != "x" {
+		t.Errorf("Ptr() = %v, want x", ptr)
+	}
+}
@@ -8,20 +8,35 @@ import (
 )
 
 type ProductRequest struct {
-	Name           string          `json:"name"`
-	CategoryID     int64           `json:"category_id"`
-	GenericName    string          `json:"generic_name"`
-	Description    string          `json:"description,omitempty"`
-	Price          decimal.Decimal `json:"price"`
-	Stock          int             `json:"stock"`
-	Unit           string          `json:"unit"`
-	ExpirationDate time.Time       `json:"expiration_date"`
-	Barcode        string          `json:"barcode"`
-	SupplierID     int64           `json:"supplier_id"`
-	MinStock       int             `json:"min_stock"`
+	Name           string          `json:"name" validate:"required,max=150"`
+	CategoryID     int64           `json:"category_id" validate:"required,gt=0,category_exists"`
+	GenericName    string          `json:"generic_name" validate:"max=150"`
+	Description    string          `json:"description,omitempty" validate:"max=2000"`
+	Price          decimal.Decimal `json:"price" validate:"decimal_gt=0"`
+	Stock          int             `json:"stock" validate:"gte=0"`
+	Unit           string          `json:"unit" validate:"required,max=30"`
+	ExpirationDate time.Time       `json:"expiration_date" validate:"required,future_on_create"`
+	Barcode        string          `json:"barcode" validate:"omitempty,barcode"`
+	SupplierID     int64           `json:"supplier_id" validate:"required,gt=0,supplier_exists"`
+	MinStock       int             `json:"min_stock" validate:"gte=0"`
 	IsActive       bool            `json:"is_active,omitempty"`
 }
 
+type ProductPatchRequest struct {
+	Name           Optional[string]          `json:"name" validate:"omitempty,min=1,max=150"`
+	CategoryID     Optional[int64]           `json:"category_id" validate:"omitempty,gt=0,category_exists"`
+	GenericName    Optional[string]          `json:"generic_name" validate:"omitempty,max=150"`
+	Description    Nullable[string]          `json:"description" validate:"omitempty,max=2000"`
+	Price          Optional[decimal.Decimal] `json:"price" validate:"omitempty,decimal_gt=0"`
+	Stock          Optional[int]             `json:"stock" validate:"omitempty,gte=0"`
+	Unit           Optional[string]          `json:"unit" validate:"omitempty,min=1,max=30"`
+	ExpirationDate Optional[time.Time]       `json:"expiration_date"`
+	Barcode        Optional[string]          `json:"barcode" validate:"omitempty,barcode"`
+	SupplierID     Optional[int64]           `json:"supplier_id" validate:"omitempty,gt=0,supplier_exists"`
+	MinStock       Optional[int]             `json:"min_stock" validate:"omitempty,gte=0"`
+	IsActive       Optional[bool]            `json:"is_active"`
+}
+
 type ProductResponse struct {
 	ID             int64           `json:"id"`
 	Name           string          `json:"name"`
@@ -39,4 +54,5 @@ type ProductResponse struct {
 	CreatedAt      time.Time       `json:"created_at"`
 	UpdatedAt      time.Time       `json:"updated_at"`
 	DeletedAt      sql.NullTime    `json:"deleted_at"`
+	Version        int64           `json:"version"`
 }
@@ -0,0 +1,60 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+// ReportRequest holds the query parameters of the sales reports. From and
+// To are inclusive YYYY-MM-DD dates in Timezone. GroupBy only applies to the
+// sales report, and Sort and Limit to the product report.
+type ReportRequest struct {
+	From     string `query:"from"`
+	To       string `query:"to"`
+	BranchID *int64 `query:"branch_id" validate:"omitempty,gt=0,branch_exists"`
+	Timezone string `query:"timezone" validate:"omitempty,timezone"`
+	GroupBy  string `query:"group_by" validate:"omitempty,oneof=day week month"`
+	Sort     string `query:"sort" validate:"omitempty,oneof=top slow"`
+	Limit    int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// ReportResponse is a report together with the range it was computed for.
+type ReportResponse[T any] struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone"`
+	BranchID *int64 `json:"branch_id"`
+	GroupBy  string `json:"group_by,omitempty"`
+	Sort     string `json:"sort,omitempty"`
+	Rows     []T    `json:"rows"`
+}
+
+type SalesTotals struct {
+	Units       int64           `json:"units"`
+	Revenue     decimal.Decimal `json:"revenue"`
+	Cost        decimal.Decimal `json:"cost"`
+	GrossProfit decimal.Decimal `json:"gross_profit"`
+}
+
+type PeriodSalesResponse struct {
+	Period string `json:"period"`
+	Sales  int64  `json:"sales"`
+	SalesTotals
+}
+
+type ProductSalesResponse struct {
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	SalesTotals
+}
+
+type CategorySalesResponse struct {
+	CategoryID int64  `json:"category_id"`
+	Name       string `json:"name"`
+	SalesTotals
+}
+
+type CashierSalesResponse struct {
+	CashierID int64  `json:"cashier_id"`
+	Username  string `json:"username"`
+	FullName  string `json:"full_name"`
+	Sales     int64  `json:"sales"`
+	SalesTotals
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ProductBatchRequest struct {
+	BranchID       int64           `json:"branch_id" validate:"required,gt=0,branch_exists"`
+	BatchNumber    string          `json:"batch_number" validate:"required,max=50"`
+	CostPrice      decimal.Decimal `json:"cost_price" validate:"decimal_gt=0"`
+	Quantity       int             `json:"quantity" validate:"required,gt=0"`
+	ExpirationDate time.Time       `json:"expiration_date" validate:"required,future_on_create"`
+}
+
+type ProductBatchResponse struct {
+	ID                int64           `json:"id"`
+	ProductID         int64           `json:"product_id"`
+	BranchID          int64           `json:"branch_id"`
+	BatchNumber       string          `json:"batch_number"`
+	CostPrice         decimal.Decimal `json:"cost_price"`
+	QuantityReceived  int             `json:"quantity_received"`
+	QuantityRemaining int             `json:"quantity_remaining"`
+	ExpirationDate    time.Time       `json:"expiration_date"`
+	ReceivedBy        int64           `json:"received_by"`
+	ReceivedAt        time.Time       `json:"received_at"`
+	ProductStock      int             `json:"product_stock"`
+}
+
+type SaleRequest struct {
+	BranchID int64             `json:"branch_id" validate:"required,gt=0,branch_exists"`
+	Items    []SaleItemRequest `json:"items" validate:"required,min=1,max=100,unique=ProductID,dive"`
+}
+
+type SaleItemRequest struct {
+	ProductID int64 `json:"product_id" validate:"required,gt=0"`
+	Quantity  int   `json:"quantity" validate:"required,gt=0"`
+}
+
+// SaleResponse leaves out what the items cost, which only reports show.
+type SaleResponse struct {
+	ID          int64              `json:"id"`
+	BranchID    int64              `json:"branch_id"`
+	CashierID   int64              `json:"cashier_id"`
+	TotalAmount decimal.Decimal    `json:"total_amount"`
+	SoldAt      time.Time          `json:"sold_at"`
+	Items       []SaleItemResponse `json:"items"`
+}
+
+type SaleItemResponse struct {
+	ProductID int64           `json:"product_id"`
+	BatchID   *int64          `json:"batch_id"`
+	Quantity  int             `json:"quantity"`
+	UnitPrice decimal.Decimal `json:"unit_price"`
+	Subtotal  decimal.Decimal `json:"subtotal"`
+}
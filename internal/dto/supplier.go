@@ -6,11 +6,19 @@ import (
 )
 
 type SupplierRequest struct {
-	Name          string `json:"name,omitempty"`
-	ContactPerson string `json:"contact_person,omitempty"`
-	Phone         string `json:"phone,omitempty"`
-	Address       string `json:"address,omitempty"`
-	Email         string `json:"email,omitempty"`
+	Name          string `json:"name,omitempty" validate:"required,max=150"`
+	ContactPerson string `json:"contact_person,omitempty" validate:"max=150"`
+	Phone         string `json:"phone,omitempty" validate:"omitempty,max=30,phone"`
+	Address       string `json:"address,omitempty" validate:"max=500"`
+	Email         string `json:"email,omitempty" validate:"omitempty,email,max=255"`
+}
+
+type SupplierPatchRequest struct {
+	Name          Optional[string] `json:"name" validate:"omitempty,min=1,max=150"`
+	ContactPerson Nullable[string] `json:"contact_person" validate:"omitempty,max=150"`
+	Phone         Nullable[string] `json:"phone" validate:"omitempty,max=30,phone"`
+	Address       Nullable[string] `json:"address" validate:"omitempty,max=500"`
+	Email         Nullable[string] `json:"email" validate:"omitempty,email,max=255"`
 }
 
 type SupplierResponse struct {
@@ -0,0 +1,50 @@
+package dto
+
+import "time"
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code"`
+	IPAddress      string `json:"-"`
+	UserAgent      string `json:"-"`
+}
+
+type TwoFactorEnrollRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Token         string   `json:"token,omitempty"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled     bool       `json:"enabled"`
+	Required    bool       `json:"required"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+}
+
+type RoleTwoFactorPolicyRequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+type RoleTwoFactorPolicyResponse struct {
+	Role      string    `json:"role"`
+	Required  bool      `json:"required"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
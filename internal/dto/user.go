@@ -6,13 +6,22 @@ import (
 )
 
 type UserRequest struct {
-	Username string `json:"username" validate:"required,min=3"`
-	FullName string `json:"full_name" validate:"required,min=3"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password"  validate:"required,min=6"`
+	Username string `json:"username" validate:"required,min=3,max=50"`
+	FullName string `json:"full_name" validate:"required,min=3,max=150"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password"  validate:"required,password"`
 	Role     string `json:"role"  validate:"required,oneof=admin pharmacist cashier"`
 }
 
+type UserPatchRequest struct {
+	Username Optional[string] `json:"username" validate:"omitempty,min=3,max=50"`
+	FullName Optional[string] `json:"full_name" validate:"omitempty,min=3,max=150"`
+	Email    Optional[string] `json:"email" validate:"omitempty,email,max=255"`
+	Password Optional[string] `json:"password" validate:"omitempty,password"`
+	Role     Optional[string] `json:"role" validate:"omitempty,oneof=admin pharmacist cashier"`
+	Status   Optional[string] `json:"status" validate:"omitempty,oneof=active inactive"`
+}
+
 type UserResponse struct {
 	ID        int64        `json:"id"`
 	Username  string       `json:"username"`
@@ -24,14 +33,20 @@ type UserResponse struct {
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
 	DeletedAt sql.NullTime `json:"deleted_at"`
+	Version   int64        `json:"version"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type AuthResponse struct {
-	Token string        `json:"token"`
-	User  *UserResponse `json:"user"`
+	Token                       string        `json:"token,omitempty"`
+	User                        *UserResponse `json:"user,omitempty"`
+	TwoFactorRequired           bool          `json:"two_factor_required,omitempty"`
+	TwoFactorEnrollmentRequired bool          `json:"two_factor_enrollment_required,omitempty"`
+	ChallengeToken              string        `json:"challenge_token,omitempty"`
 }
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+type APIKey struct {
+	ID         int64
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP *string
+	CreatedBy  int64
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
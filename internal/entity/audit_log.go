@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditLog struct {
+	ID         int64
+	ActorID    *int64
+	ActorName  string
+	ActorRole  string
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     json.RawMessage
+	After      json.RawMessage
+	IPAddress  string
+	RequestID  string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+type Branch struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -13,4 +13,5 @@ type Category struct {
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 	DeletedAt        sql.NullTime
+	Version          int64
 }
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// IdempotencyKey is a client-supplied Idempotency-Key reserved by the first
+// request that used it. StatusCode stays nil while that request is running.
+type IdempotencyKey struct {
+	Scope        string
+	Key          string
+	RequestHash  string
+	StatusCode   *int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportJob struct {
+	ID            int64
+	EntityType    string
+	FileName      string
+	Status        string
+	DryRun        bool
+	TotalRows     int
+	ProcessedRows int
+	CreatedRows   int
+	UpdatedRows   int
+	FailedRows    int
+	Errors        []ImportRowError
+	Failure       *string
+	CreatedBy     int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FinishedAt    *time.Time
+}
+
+// ImportRowError is a problem with one row of an import file. It is stored
+// as JSON on the job.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
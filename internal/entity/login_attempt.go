@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+type LoginAttempt struct {
+	ID        int64
+	UserID    *int64
+	Email     string
+	IPAddress string
+	UserAgent string
+	Success   bool
+	Reason    string
+	CreatedAt time.Time
+}
+
+type LoginLockout struct {
+	Key          string
+	FailedCount  int
+	LockedUntil  *time.Time
+	LastFailedAt time.Time
+	UpdatedAt    time.Time
+}
@@ -24,4 +24,5 @@ type Product struct {
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	DeletedAt      sql.NullTime
+	Version        int64
 }
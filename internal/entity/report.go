@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SalesTotals are the sums every sales report row carries. GrossProfit is
+// Revenue less Cost.
+type SalesTotals struct {
+	Units       int64
+	Revenue     decimal.Decimal
+	Cost        decimal.Decimal
+	GrossProfit decimal.Decimal
+}
+
+// PeriodSales totals the sales of the day, week or month starting on Period.
+type PeriodSales struct {
+	Period time.Time
+	Sales  int64
+	SalesTotals
+}
+
+type ProductSales struct {
+	ProductID int64
+	Name      string
+	SalesTotals
+}
+
+type CategorySales struct {
+	CategoryID int64
+	Name       string
+	SalesTotals
+}
+
+type CashierSales struct {
+	CashierID int64
+	Username  string
+	FullName  string
+	Sales     int64
+	SalesTotals
+}
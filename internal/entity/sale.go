@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ProductBatch is one delivery of a product to a branch. Its cost price is
+// what each unit sold from it cost the pharmacy.
+type ProductBatch struct {
+	ID                int64
+	ProductID         int64
+	BranchID          int64
+	BatchNumber       string
+	CostPrice         decimal.Decimal
+	QuantityReceived  int
+	QuantityRemaining int
+	ExpirationDate    time.Time
+	ReceivedBy        int64
+	ReceivedAt        time.Time
+}
+
+type Sale struct {
+	ID          int64
+	BranchID    int64
+	CashierID   int64
+	TotalAmount decimal.Decimal
+	TotalCost   decimal.Decimal
+	SoldAt      time.Time
+	Items       []SaleItem
+}
+
+// SaleItem is the part of a sale line taken from one batch. BatchID is nil
+// for units sold from stock that no batch accounts for.
+type SaleItem struct {
+	ID        int64
+	SaleID    int64
+	ProductID int64
+	BatchID   *int64
+	Quantity  int
+	UnitPrice decimal.Decimal
+	UnitCost  decimal.Decimal
+}
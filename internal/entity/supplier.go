@@ -15,4 +15,5 @@ type Supplier struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeletedAt     sql.NullTime
+	Version       int64
 }
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+type UserTOTP struct {
+	UserID       int64
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type RoleTwoFactorPolicy struct {
+	Role      string
+	Required  bool
+	UpdatedAt time.Time
+}
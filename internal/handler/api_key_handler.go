@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/utils"
+	"pharmly-backend/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	usecase usecase.APIKeyUsecase
+}
+
+func NewAPIKeyHandler(usecase usecase.APIKeyUsecase) *APIKeyHandler {
+	return &APIKeyHandler{usecase: usecase}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	var req dto.APIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.ValidateCreate(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	response, err := h.usecase.CreateAPIKey(c.UserContext(), claims.UserID, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to create api key")
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "API key created, copy it now as it will not be shown again",
+		"data":    response,
+	})
+}
+
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.usecase.GetAllAPIKeys(c.UserContext())
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get api keys")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "API keys retrieved successfully",
+		"data":    keys,
+	})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := parseID(c, "api key")
+	if err != nil {
+		return err
+	}
+
+	if err := h.usecase.RevokeAPIKey(c.UserContext(), id); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to revoke api key")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditLogHandler struct {
+	usecase       usecase.AuditLogUsecase
+	exportTimeout time.Duration
+}
+
+func NewAuditLogHandler(usecase usecase.AuditLogUsecase, exportTimeout time.Duration) *AuditLogHandler {
+	return &AuditLogHandler{usecase: usecase, exportTimeout: exportTimeout}
+}
+
+func (h *AuditLogHandler) GetAuditLogs(c *fiber.Ctx) error {
+	var filter dto.AuditLogFilterRequest
+	if err := c.QueryParser(&filter); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Invalid audit log filter")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid audit log filter")
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &filter); err != nil {
+		return err
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		return sendExport(c, h.exportTimeout, "audit-logs", format, func(ctx context.Context, w spreadsheet.Writer) error {
+			return h.usecase.ExportAuditLogs(ctx, &filter, w)
+		})
+	}
+
+	page, err := parsePagination(c)
+	if err != nil {
+		return err
+	}
+
+	logs, pagination, err := h.usecase.GetAllAuditLogs(c.UserContext(), &filter, page)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get audit logs")
+		return err
+	}
+
+	setPaginationLinks(c, pagination)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":     "success",
+		"message":    "Audit logs retrieved successfully",
+		"data":       logs,
+		"pagination": pagination,
+	})
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/middleware"
 	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -21,31 +24,25 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req dto.UserRequest
 
 	if err := c.BodyParser(&req); err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Interface("body", c.Body()).
 			Msg("Failed to parse request body")
-		return err
+		return middleware.InvalidBody(err)
 	}
 
-	if err := middleware.Validate.Struct(&req); err != nil {
-		logger.Error().
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Interface("errors", middleware.GetValidationErrors(err)).
+			Interface("errors", validation.GetValidationErrors(err, validation.LangEnglish)).
 			Msg("Validation failed")
 		return err
 	}
 
-	response, err := h.usecase.Register(c.Context(), &req)
+	response, err := h.usecase.Register(c.UserContext(), &req)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Msg("Failed to register user")
 		return err
 	}
@@ -60,26 +57,33 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req dto.LoginRequest
 	if err := c.BodyParser(&req); err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Interface("body", c.Body()).
 			Msg("Failed to parse request body")
-		return err
+		return middleware.InvalidBody(err)
 	}
 
-	if err := middleware.Validate.Struct(&req); err != nil {
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
 		return err
 	}
 
-	response, err := h.usecase.Login(c.Context(), &req)
+	req.IPAddress = c.IP()
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+
+	response, err := h.usecase.Login(c.UserContext(), &req)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
+			Str("ip", req.IPAddress).
 			Msg("Failed to login")
+
+		var locked *usecase.AccountLockedError
+		if errors.As(err, &locked) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(locked.RetryAfter().Seconds())+1))
+			return fiber.NewError(fiber.StatusTooManyRequests, locked.Error())
+		}
+
 		return err
 	}
 
@@ -89,3 +93,62 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		"data":    response,
 	})
 }
+
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req dto.TwoFactorLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	req.IPAddress = c.IP()
+	req.UserAgent = c.Get(fiber.HeaderUserAgent)
+
+	response, err := h.usecase.VerifyTwoFactor(c.UserContext(), &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Str("ip", req.IPAddress).
+			Msg("Failed to verify two-factor login")
+
+		var locked *usecase.AccountLockedError
+		if errors.As(err, &locked) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(locked.RetryAfter().Seconds())+1))
+			return fiber.NewError(fiber.StatusTooManyRequests, locked.Error())
+		}
+
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Login successful",
+		"data":    response,
+	})
+}
+
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	id, err := parseID(c, "user")
+	if err != nil {
+		return err
+	}
+
+	if err := h.usecase.UnlockAccount(c.UserContext(), id); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to unlock account")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Account unlocked successfully",
+	})
+}
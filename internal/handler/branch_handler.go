@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BranchHandler struct {
+	usecase usecase.BranchUsecase
+}
+
+func NewBranchHandler(usecase usecase.BranchUsecase) *BranchHandler {
+	return &BranchHandler{usecase: usecase}
+}
+
+func (h *BranchHandler) CreateBranch(c *fiber.Ctx) error {
+	var req dto.BranchRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.ValidateCreate(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	response, err := h.usecase.CreateBranch(c.UserContext(), &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to create branch")
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Branch created successfully",
+		"data":    response,
+	})
+}
+
+func (h *BranchHandler) GetBranches(c *fiber.Ctx) error {
+	branches, err := h.usecase.GetAllBranches(c.UserContext())
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get branches")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Branches retrieved successfully",
+		"data":    branches,
+	})
+}
@@ -1,54 +1,47 @@
 package handler
 
 import (
+	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/usecase"
-	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type CategoryHandler struct {
-	usecase usecase.CategoryUsecase
+	usecase       usecase.CategoryUsecase
+	exportTimeout time.Duration
 }
 
-func NewCategoryHandler(usecase usecase.CategoryUsecase) *CategoryHandler {
-	return &CategoryHandler{usecase: usecase}
+func NewCategoryHandler(usecase usecase.CategoryUsecase, exportTimeout time.Duration) *CategoryHandler {
+	return &CategoryHandler{usecase: usecase, exportTimeout: exportTimeout}
 }
 
 func (h *CategoryHandler) GetCategories(c *fiber.Ctx) error {
-	page, err := strconv.Atoi(c.Query("page", "2"))
+	format, err := exportFormat(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page", c.Query("page")).
-			Msg("Invalid page number")
 		return err
 	}
+	if format != "" {
+		return sendExport(c, h.exportTimeout, "categories", format, h.usecase.ExportCategories)
+	}
 
-	pageSize, err := strconv.Atoi(c.Query("page_size", "3"))
+	page, err := parsePagination(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page_size", c.Query("page_size")).
-			Msg("Invalid page size")
 		return err
 	}
 
-	categories, pagination, err := h.usecase.GetAllCategories(c.Context(), page, pageSize)
+	categories, pagination, err := h.usecase.GetAllCategories(c.UserContext(), page)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Msg("Failed to get categories")
 		return err
 	}
 
+	setPaginationLinks(c, pagination)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":     "success",
 		"message":    "Categories retrieved successfully",
@@ -56,3 +49,36 @@ func (h *CategoryHandler) GetCategories(c *fiber.Ctx) error {
 		"pagination": pagination,
 	})
 }
+
+func (h *CategoryHandler) PatchCategory(c *fiber.Ctx) error {
+	id, err := parseID(c, "category")
+	if err != nil {
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.CategoryPatchRequest
+	if err := parsePatch(c, &req); err != nil {
+		return err
+	}
+
+	category, err := h.usecase.PatchCategory(c.UserContext(), id, version, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to patch category")
+		return versionConflictError(err)
+	}
+
+	c.Set(fiber.HeaderETag, etag(category.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Category updated successfully",
+		"data":    category,
+	})
+}
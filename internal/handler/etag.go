@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"pharmly-backend/internal/repository"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// etag renders a record version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion reads the version a client expects to modify from the
+// If-Match header. Updates and deletes must send it, so a missing header is
+// rejected with 428 and an unparseable one with 412. "*" matches any
+// version of an existing record and yields repository.AnyVersion.
+func ifMatchVersion(c *fiber.Ctx) (int64, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return 0, fiber.NewError(fiber.StatusPreconditionRequired, "If-Match header with the resource ETag is required")
+	}
+	if header == "*" {
+		return repository.AnyVersion, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version <= 0 || strings.HasPrefix(header, "W/") {
+		return 0, fiber.NewError(fiber.StatusPreconditionFailed, "If-Match header does not match the current resource version")
+	}
+	return version, nil
+}
+
+// versionConflictError answers a write whose If-Match version is no longer
+// current with 412, and passes any other error through.
+func versionConflictError(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return fiber.NewError(fiber.StatusPreconditionFailed, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"pharmly-backend/internal/repository"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		want       int64
+		wantStatus int
+	}{
+		{"strong tag", `"3"`, 3, 0},
+		{"unquoted", "3", 3, 0},
+		{"wildcard matches any version", "*", repository.AnyVersion, 0},
+		{"missing", "", 0, fiber.StatusPreconditionRequired},
+		{"weak tag", `W/"3"`, 0, fiber.StatusPreconditionFailed},
+		{"not a number", `"abc"`, 0, fiber.StatusPreconditionFailed},
+		{"zero is not a version", `"0"`, 0, fiber.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			var got int64
+			var gotErr error
+			app.Patch("/", func(c *fiber.Ctx) error {
+				got, gotErr = ifMatchVersion(c)
+				return nil
+			})
+
+			req := httptest.NewRequest(fiber.MethodPatch, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderIfMatch, tt.header)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantStatus != 0 {
+				fiberErr, ok := gotErr.(*fiber.Error)
+				if !ok || fiberErr.Code != tt.wantStatus {
+					t.Fatalf("ifMatchVersion() error = %v, want status %d", gotErr, tt.wantStatus)
+				}
+				return
+			}
+			if gotErr != nil || got != tt.want {
+				t.Errorf("ifMatchVersion() = %d, %v, want %d", got, gotErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	tests := []struct {
+		current, expected int64
+		want              bool
+	}{
+		{3, 3, true},
+		{3, 2, false},
+		{3, repository.AnyVersion, true},
+	}
+
+	for _, tt := range tests {
+		if got := repository.VersionMatches(tt.current, tt.expected); got != tt.want {
+			t.Errorf("VersionMatches(%d, %d) = %v, want %v", tt.current, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestVersionConflictError(t *testing.T) {
+	err := versionConflictError(fmt.Errorf("update product: %w", repository.ErrVersionConflict))
+	if fiberErr, ok := err.(*fiber.Error); !ok || fiberErr.Code != fiber.StatusPreconditionFailed {
+		t.Errorf("versionConflictError(conflict) = %v, want status %d", err, fiber.StatusPreconditionFailed)
+	}
+
+	other := errors.New("boom")
+	if err := versionConflictError(other); err != other {
+		t.Errorf("versionConflictError(other) = %v, want it passed through", err)
+	}
+}
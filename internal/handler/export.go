@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/spreadsheet"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var ErrInvalidExportFormat = apperror.Validation("invalid_format", "format must be json, csv or xlsx")
+
+// exportFunc writes every row of a list to w.
+type exportFunc func(ctx context.Context, w spreadsheet.Writer) error
+
+// exportFormat returns the spreadsheet format asked for with the format
+// query parameter or, without one, the Accept header. It returns "" when the
+// list should be sent as JSON.
+func exportFormat(c *fiber.Ctx) (string, error) {
+	switch format := c.Query("format"); format {
+	case "":
+	case "json":
+		return "", nil
+	case spreadsheet.FormatCSV, spreadsheet.FormatXLSX:
+		return format, nil
+	default:
+		return "", ErrInvalidExportFormat
+	}
+
+	switch c.Accepts(fiber.MIMEApplicationJSON, spreadsheet.MIMECSV, spreadsheet.MIMEXLSX) {
+	case spreadsheet.MIMECSV:
+		return spreadsheet.FormatCSV, nil
+	case spreadsheet.MIMEXLSX:
+		return spreadsheet.FormatXLSX, nil
+	}
+	return "", nil
+}
+
+// sendExport answers with a file of every row of a list. Rows are written
+// while the response is sent, so a failure part way through can only be
+// logged and leaves the client with a truncated file. timeout bounds how
+// long sending the file may take and replaces the server's WriteTimeout,
+// which fasthttp applies to the whole streamed response. Zero removes the
+// deadline.
+func sendExport(c *fiber.Ctx, timeout time.Duration, resource, format string, export exportFunc) error {
+	// The request context must not be used once the handler returns, so
+	// the stream writer keeps its own references.
+	ctx := c.UserContext()
+	conn := c.Context().Conn()
+
+	c.Attachment(fmt.Sprintf("%s-%s.%s", resource, time.Now().UTC().Format("20060102-150405"), format))
+	c.Set(fiber.HeaderContentType, spreadsheet.ContentType(format))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("resource", resource).Msg("Failed to extend export write deadline")
+		}
+
+		sheet, err := spreadsheet.NewWriter(w, format)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("resource", resource).Msg("Failed to start export")
+			return
+		}
+
+		if err := export(ctx, sheet); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("resource", resource).Msg("Failed to export rows")
+		}
+		if err := sheet.Close(); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("resource", resource).Msg("Failed to finish export")
+		}
+	})
+	return nil
+}
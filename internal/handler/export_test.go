@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"pharmly-backend/internal/spreadsheet"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSendExportOutlivesWriteTimeout(t *testing.T) {
+	const rows = 30
+	cell := strings.Repeat("x", 16*1024)
+
+	tests := []struct {
+		name          string
+		exportTimeout time.Duration
+		wantComplete  bool
+	}{
+		{"export timeout replaces write timeout", 10 * time.Second, true},
+		{"no deadline", 0, true},
+		{"export timeout still applies", 50 * time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{WriteTimeout: 100 * time.Millisecond, DisableStartupMessage: true})
+			app.Get("/export", func(c *fiber.Ctx) error {
+				return sendExport(c, tt.exportTimeout, "products", spreadsheet.FormatCSV, func(ctx context.Context, w spreadsheet.Writer) error {
+					for i := 0; i < rows; i++ {
+						time.Sleep(10 * time.Millisecond)
+						if err := w.Write(i, cell); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			})
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go app.Listener(ln)
+			defer app.Shutdown()
+
+			resp, err := http.Get("http://" + ln.Addr().String() + "/export")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			complete := strings.Count(string(body), "\n") == rows
+			if complete != tt.wantComplete {
+				t.Errorf("received %d of %d rows, want complete = %v", strings.Count(string(body), "\n"), rows, tt.wantComplete)
+			}
+		})
+	}
+}
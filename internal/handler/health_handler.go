@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"pharmly-backend/internal/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// healthCheckTimeout bounds each readiness check so a hung dependency fails
+// the probe instead of stalling it.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthCheck is a named readiness dependency. Check returns nil when the
+// dependency can serve traffic.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthCheckResult is the public outcome of a check. Failure details are
+// logged rather than returned, since they can name internal hosts or state.
+type HealthCheckResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type HealthHandler struct {
+	checks       []HealthCheck
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing new
+// requests while in-flight ones drain.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness reports that the process is up and serving requests. It checks no
+// dependencies, so an outage elsewhere does not get the process restarted.
+func (h *HealthHandler) Liveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Service is alive",
+	})
+}
+
+// Readiness runs every check concurrently and reports 503 if any fails or the
+// application is shutting down.
+func (h *HealthHandler) Readiness(c *fiber.Ctx) error {
+	if h.shuttingDown.Load() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service is shutting down",
+		})
+	}
+
+	results := make([]HealthCheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runHealthCheck(c.UserContext(), check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Status != "ok" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Service is not ready",
+				"data":    results,
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Service is ready",
+		"data":    results,
+	})
+}
+
+func runHealthCheck(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := HealthCheckResult{
+		Name:       check.Name,
+		Status:     "ok",
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error().
+			Err(err).
+			Str("check", check.Name).
+			Msg("Readiness check failed")
+		result.Status = "failed"
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReadiness(t *testing.T) {
+	ok := HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }}
+	failing := HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+		return errors.New("dial tcp 10.1.2.3:5432: connection refused")
+	}}
+
+	tests := []struct {
+		name         string
+		checks       []HealthCheck
+		shuttingDown bool
+		wantStatus   int
+		wantChecks   map[string]string
+	}{
+		{"all checks pass", []HealthCheck{ok}, false, fiber.StatusOK, map[string]string{"database": "ok"}},
+		{"a check fails", []HealthCheck{ok, failing}, false, fiber.StatusServiceUnavailable, map[string]string{"database": "ok", "migrations": "failed"}},
+		{"shutting down", []HealthCheck{ok}, true, fiber.StatusServiceUnavailable, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(tt.checks...)
+			if tt.shuttingDown {
+				h.SetShuttingDown()
+			}
+			app := fiber.New()
+			app.Get("/readyz", h.Readiness)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			raw, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(raw), "10.1.2.3") {
+				t.Errorf("response leaks the check error: %s", raw)
+			}
+
+			var body struct {
+				Data []map[string]any `json:"data"`
+			}
+			if err := json.Unmarshal(raw, &body); err != nil {
+				t.Fatal(err)
+			}
+			for _, result := range body.Data {
+				name, _ := result["name"].(string)
+				if got := result["status"]; got != tt.wantChecks[name] {
+					t.Errorf("check %s status = %v, want %s", name, got, tt.wantChecks[name])
+				}
+				if _, leaked := result["error"]; leaked {
+					t.Errorf("check %s exposes its error: %v", name, result)
+				}
+			}
+			if len(body.Data) != len(tt.wantChecks) {
+				t.Errorf("got %d check results, want %d", len(body.Data), len(tt.wantChecks))
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/pagination"
+	"pharmly-backend/internal/utils"
+	"pharmly-backend/internal/validation"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseID reads the :id route parameter and returns a 400 naming resource
+// when it is not a positive integer.
+func parseID(c *fiber.Ctx, resource string) (int64, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, apperror.Validation("invalid_id", "Invalid "+resource+" ID").Wrap(err)
+	}
+	return id, nil
+}
+
+// requestClaims returns the caller AuthMiddleware stored on the request, or
+// a 401 when the route was reached without one.
+func requestClaims(c *fiber.Ctx) (*utils.Claims, error) {
+	claims, ok := c.Locals("user").(*utils.Claims)
+	if !ok {
+		return nil, middleware.ErrNotAuthenticated
+	}
+	return claims, nil
+}
+
+// parsePagination reads the list query parameters shared by every list
+// endpoint and fills in their defaults.
+func parsePagination(c *fiber.Ctx) (*dto.PaginationRequest, error) {
+	var req dto.PaginationRequest
+	if err := c.QueryParser(&req); err != nil {
+		return nil, apperror.Validation("invalid_pagination", "Invalid pagination parameters").Wrap(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return nil, err
+	}
+
+	req.SetDefaults()
+	return &req, nil
+}
+
+// setPaginationLinks advertises the first, neighbouring and last pages of a
+// list in the Link header.
+func setPaginationLinks(c *fiber.Ctx, response *dto.PaginationResponse) {
+	if link := pagination.LinkHeader(c.OriginalURL(), response); link != "" {
+		c.Set(fiber.HeaderLink, link)
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parsePatch decodes a JSON Merge Patch body and validates the fields it
+// supplies. Both application/json and application/merge-patch+json bodies
+// are accepted.
+func parsePatch(c *fiber.Ctx, req any) error {
+	if err := c.BodyParser(req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse merge patch")
+		return middleware.InvalidBody(err)
+	}
+
+	return validation.Validate.StructCtx(c.UserContext(), req)
+}
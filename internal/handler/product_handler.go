@@ -5,40 +5,39 @@ import (
 	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/middleware"
 	"pharmly-backend/internal/usecase"
-	"strconv"
+	"pharmly-backend/internal/validation"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type ProductHandler struct {
-	usecase usecase.ProductUsecase
+	usecase       usecase.ProductUsecase
+	exportTimeout time.Duration
 }
 
-func NewProductHandler(usecase usecase.ProductUsecase) *ProductHandler {
-	return &ProductHandler{usecase: usecase}
+func NewProductHandler(usecase usecase.ProductUsecase, exportTimeout time.Duration) *ProductHandler {
+	return &ProductHandler{usecase: usecase, exportTimeout: exportTimeout}
 }
 
 func (h *ProductHandler) AddProduct(c *fiber.Ctx) error {
 	var req dto.ProductRequest
 	if err := c.BodyParser(&req); err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
 			Interface("body", c.Body()).
 			Msg("Failed to parse request body")
-		return err
+		return middleware.InvalidBody(err)
 	}
 
-	if err := middleware.Validate.Struct(&req); err != nil {
+	if err := validation.ValidateCreate(c.UserContext(), &req); err != nil {
 		return err
 	}
 
-	response, err := h.usecase.CreateProduct(c.Context(), &req)
+	response, err := h.usecase.CreateProduct(c.UserContext(), &req)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Msg("Failed to add product")
 		return err
 	}
@@ -51,28 +50,25 @@ func (h *ProductHandler) AddProduct(c *fiber.Ctx) error {
 }
 
 func (h *ProductHandler) GetProductByID(c *fiber.Ctx) error {
-	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	id, err := parseID(c, "product")
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("id", c.Params("id")).
-			Msg("Invalid product ID")
-		return nil
+		return err
 	}
 
-	product, err := h.usecase.GetProductByID(c.Context(), id)
+	product, err := h.usecase.GetProductByID(c.UserContext(), id)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Int64("id", id).
 			Msg("Failed to get product")
 		return err
 	}
 
+	c.Set(fiber.HeaderETag, etag(product.Version))
+	if c.Get(fiber.HeaderIfNoneMatch) == etag(product.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "Product retrieved successfully",
@@ -81,38 +77,29 @@ func (h *ProductHandler) GetProductByID(c *fiber.Ctx) error {
 }
 
 func (h *ProductHandler) GetProducts(c *fiber.Ctx) error {
-	page, err := strconv.Atoi(c.Query("page", "2"))
+	format, err := exportFormat(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page", c.Query("page")).
-			Msg("Invalid page number")
 		return err
 	}
+	if format != "" {
+		return sendExport(c, h.exportTimeout, "products", format, h.usecase.ExportProducts)
+	}
 
-	pageSize, err := strconv.Atoi(c.Query("page_size", "3"))
+	page, err := parsePagination(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page_size", c.Query("page_size")).
-			Msg("Invalid page size")
 		return err
 	}
 
-	products, pagination, err := h.usecase.GetAllProducts(c.Context(), page, pageSize)
+	products, pagination, err := h.usecase.GetAllProducts(c.UserContext(), page)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Msg("Failed to get products")
 		return err
 	}
 
+	setPaginationLinks(c, pagination)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":     "success",
 		"message":    "Products retrieved successfully",
@@ -122,43 +109,39 @@ func (h *ProductHandler) GetProducts(c *fiber.Ctx) error {
 }
 
 func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
-	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	id, err := parseID(c, "product")
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("id", c.Params("id")).
-			Msg("Invalid product ID")
-		return nil
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
 	}
 
 	var req dto.ProductRequest
 	if err := c.BodyParser(&req); err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Interface("body", c.Body()).
 			Msg("Failed to parse request body")
-		return err
+		return middleware.InvalidBody(err)
 	}
 
-	if err := middleware.Validate.Struct(&req); err != nil {
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
 		return err
 	}
 
-	product, err := h.usecase.UpdateProduct(c.Context(), id, &req)
+	product, err := h.usecase.UpdateProduct(c.UserContext(), id, version, &req)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Int64("id", id).
 			Msg("Failed to update product")
-		return err
+		return versionConflictError(err)
 	}
 
+	c.Set(fiber.HeaderETag, etag(product.Version))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "Product updated successfully",
@@ -167,25 +150,22 @@ func (h *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
 }
 
 func (h *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
-	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	id, err := parseID(c, "product")
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("id", c.Params("id")).
-			Msg("Invalid product ID")
-		return nil
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
 	}
 
-	if err := h.usecase.DeleteProduct(c.Context(), id); err != nil {
-		logger.Error().
+	if err := h.usecase.DeleteProduct(c.UserContext(), id, version); err != nil {
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Int64("id", id).
 			Msg("Failed to delete product")
-		return err
+		return versionConflictError(err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -193,3 +173,36 @@ func (h *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
 		"message": "Product deleted successfully",
 	})
 }
+
+func (h *ProductHandler) PatchProduct(c *fiber.Ctx) error {
+	id, err := parseID(c, "product")
+	if err != nil {
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.ProductPatchRequest
+	if err := parsePatch(c, &req); err != nil {
+		return err
+	}
+
+	product, err := h.usecase.PatchProduct(c.UserContext(), id, version, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to patch product")
+		return versionConflictError(err)
+	}
+
+	c.Set(fiber.HeaderETag, etag(product.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Product updated successfully",
+		"data":    product,
+	})
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrMissingImportFile = apperror.Validation("missing_file", "A .csv or .xlsx file is required in the file field")
+	ErrInvalidDryRun     = apperror.Validation("invalid_dry_run", "dry_run must be true or false")
+	ErrInvalidMapping    = apperror.Validation("invalid_mapping", "mapping must be a JSON object of field names to column names")
+)
+
+type ProductImportHandler struct {
+	usecase usecase.ProductImportUsecase
+}
+
+func NewProductImportHandler(usecase usecase.ProductImportUsecase) *ProductImportHandler {
+	return &ProductImportHandler{usecase: usecase}
+}
+
+// ImportProducts accepts a multipart upload with the file in "file", an
+// optional "dry_run" flag and an optional JSON "mapping" of import fields to
+// file columns. The rows are processed in the background and 202 points at
+// the job, which lists every row error; a dry run changes no products.
+func (h *ProductImportHandler) ImportProducts(c *fiber.Ctx) error {
+	claims, err := requestClaims(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return ErrMissingImportFile.Wrap(err)
+	}
+
+	dryRun, err := strconv.ParseBool(c.FormValue("dry_run", "false"))
+	if err != nil {
+		return ErrInvalidDryRun.Wrap(err)
+	}
+
+	req := dto.ProductImportRequest{FileName: file.Filename, DryRun: dryRun}
+	if mapping := c.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			return ErrInvalidMapping.Wrap(err)
+		}
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to open uploaded file")
+		return err
+	}
+	defer content.Close()
+
+	job, err := h.usecase.StartImport(c.UserContext(), claims.UserID, &req, content)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to import products")
+		return err
+	}
+
+	message := "Product import started"
+	if req.DryRun {
+		message = "Dry run started, no products will be changed"
+	}
+
+	c.Location(fmt.Sprintf("%s/%d", c.Path(), job.ID))
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+		"data":    job,
+	})
+}
+
+func (h *ProductImportHandler) GetImportJob(c *fiber.Ctx) error {
+	job, err := h.importJob(c)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Import job retrieved successfully",
+		"data":    job,
+	})
+}
+
+// GetImportErrors downloads the row errors of an import job as CSV.
+func (h *ProductImportHandler) GetImportErrors(c *fiber.Ctx) error {
+	job, err := h.importJob(c)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment(fmt.Sprintf("import-%d-errors.csv", job.ID))
+
+	report := csv.NewWriter(c)
+	_ = report.Write([]string{"row", "column", "message"})
+	for _, rowError := range job.Errors {
+		_ = report.Write([]string{strconv.Itoa(rowError.Row), rowError.Column, rowError.Message})
+	}
+	report.Flush()
+	return report.Error()
+}
+
+// importJob returns the job named in the URL. A job is visible to the user
+// who started it and to admins; anyone else is told it does not exist, so
+// job IDs reveal nothing about other users' imports.
+func (h *ProductImportHandler) importJob(c *fiber.Ctx) (*dto.ImportJobResponse, error) {
+	claims, err := requestClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := parseID(c, "import job")
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := h.usecase.GetImportJob(c.UserContext(), id)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to get import job")
+		return nil, err
+	}
+
+	if job.CreatedBy != claims.UserID && claims.Role != "admin" {
+		logger.Ctx(c.UserContext()).Warn().
+			Int64("id", id).
+			Int64("user_id", claims.UserID).
+			Msg("Import job belongs to another user")
+		return nil, repository.ErrImportJobNotFound
+	}
+	return job, nil
+}
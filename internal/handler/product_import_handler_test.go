@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/utils"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type fakeImportUsecase struct {
+	usecase.ProductImportUsecase
+	jobs map[int64]*dto.ImportJobResponse
+}
+
+func (f *fakeImportUsecase) GetImportJob(ctx context.Context, id int64) (*dto.ImportJobResponse, error) {
+	if job, ok := f.jobs[id]; ok {
+		return job, nil
+	}
+	return nil, repository.ErrImportJobNotFound
+}
+
+func TestImportJobVisibility(t *testing.T) {
+	h := NewProductImportHandler(&fakeImportUsecase{jobs: map[int64]*dto.ImportJobResponse{
+		5: {ID: 5, CreatedBy: 1, Errors: []dto.ImportRowError{{Row: 2, Column: "Price", Message: "Must be a number"}}},
+	}})
+
+	tests := []struct {
+		name       string
+		claims     *utils.Claims
+		path       string
+		wantStatus int
+	}{
+		{"owner reads the job", &utils.Claims{UserID: 1, Role: "pharmacist"}, "/imports/5", fiber.StatusOK},
+		{"owner downloads the errors", &utils.Claims{UserID: 1, Role: "pharmacist"}, "/imports/5/errors", fiber.StatusOK},
+		{"admin reads any job", &utils.Claims{UserID: 2, Role: "admin"}, "/imports/5", fiber.StatusOK},
+		{"other user cannot read the job", &utils.Claims{UserID: 3, Role: "pharmacist"}, "/imports/5", fiber.StatusNotFound},
+		{"other user cannot download the errors", &utils.Claims{UserID: 3, Role: "pharmacist"}, "/imports/5/errors", fiber.StatusNotFound},
+		{"missing job", &utils.Claims{UserID: 1, Role: "admin"}, "/imports/6", fiber.StatusNotFound},
+		{"unauthenticated", nil, "/imports/5", fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler()})
+			app.Use(func(c *fiber.Ctx) error {
+				if tt.claims != nil {
+					c.Locals("user", tt.claims)
+				}
+				return c.Next()
+			})
+			app.Get("/imports/:id", h.GetImportJob)
+			app.Get("/imports/:id/errors", h.GetImportErrors)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var salesTotalsColumns = []any{"units", "revenue", "cost", "gross_profit"}
+
+type ReportHandler struct {
+	usecase       usecase.ReportUsecase
+	exportTimeout time.Duration
+}
+
+func NewReportHandler(usecase usecase.ReportUsecase, exportTimeout time.Duration) *ReportHandler {
+	return &ReportHandler{usecase: usecase, exportTimeout: exportTimeout}
+}
+
+func (h *ReportHandler) GetSalesReport(c *fiber.Ctx) error {
+	req, format, err := parseReportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.usecase.SalesReport(c.UserContext(), req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get sales report")
+		return err
+	}
+
+	return sendReport(c, h.exportTimeout, "sales-report", format, "Sales report retrieved successfully", report,
+		append([]any{"period", "sales"}, salesTotalsColumns...),
+		func(row dto.PeriodSalesResponse) []any {
+			return append([]any{row.Period, row.Sales}, salesTotalsCells(row.SalesTotals)...)
+		})
+}
+
+func (h *ReportHandler) GetProductReport(c *fiber.Ctx) error {
+	req, format, err := parseReportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.usecase.ProductReport(c.UserContext(), req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get product report")
+		return err
+	}
+
+	return sendReport(c, h.exportTimeout, "product-report", format, "Product report retrieved successfully", report,
+		append([]any{"product_id", "name"}, salesTotalsColumns...),
+		func(row dto.ProductSalesResponse) []any {
+			return append([]any{row.ProductID, row.Name}, salesTotalsCells(row.SalesTotals)...)
+		})
+}
+
+func (h *ReportHandler) GetCategoryReport(c *fiber.Ctx) error {
+	req, format, err := parseReportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.usecase.CategoryReport(c.UserContext(), req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get category report")
+		return err
+	}
+
+	return sendReport(c, h.exportTimeout, "category-report", format, "Category report retrieved successfully", report,
+		append([]any{"category_id", "name"}, salesTotalsColumns...),
+		func(row dto.CategorySalesResponse) []any {
+			return append([]any{row.CategoryID, row.Name}, salesTotalsCells(row.SalesTotals)...)
+		})
+}
+
+func (h *ReportHandler) GetCashierReport(c *fiber.Ctx) error {
+	req, format, err := parseReportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.usecase.CashierReport(c.UserContext(), req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get cashier report")
+		return err
+	}
+
+	return sendReport(c, h.exportTimeout, "cashier-report", format, "Cashier report retrieved successfully", report,
+		append([]any{"cashier_id", "username", "full_name", "sales"}, salesTotalsColumns...),
+		func(row dto.CashierSalesResponse) []any {
+			return append([]any{row.CashierID, row.Username, row.FullName, row.Sales}, salesTotalsCells(row.SalesTotals)...)
+		})
+}
+
+// parseReportRequest reads the report filter and the format the report is
+// to be sent in before any query runs.
+func parseReportRequest(c *fiber.Ctx) (*dto.ReportRequest, string, error) {
+	var req dto.ReportRequest
+	if err := c.QueryParser(&req); err != nil {
+		return nil, "", apperror.Validation("invalid_report_filter", "Invalid report filter").Wrap(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return nil, "", err
+	}
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return &req, format, nil
+}
+
+// sendReport answers with report as JSON or, for a spreadsheet format, with
+// a file of its rows under a header row.
+func sendReport[T any](c *fiber.Ctx, exportTimeout time.Duration, resource, format, message string, report *dto.ReportResponse[T], header []any, cells func(T) []any) error {
+	if format == "" {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": message,
+			"data":    report,
+		})
+	}
+
+	return sendExport(c, exportTimeout, resource, format, func(ctx context.Context, w spreadsheet.Writer) error {
+		if err := w.Write(header...); err != nil {
+			return err
+		}
+		for _, row := range report.Rows {
+			if err := w.Write(cells(row)...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func salesTotalsCells(totals dto.SalesTotals) []any {
+	return []any{totals.Units, totals.Revenue, totals.Cost, totals.GrossProfit}
+}
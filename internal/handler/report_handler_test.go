@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	validation.RegisterExists("branch_exists", func(ctx context.Context, id int64) (bool, error) { return true, nil })
+}
+
+type fakeReportUsecase struct {
+	usecase.ReportUsecase
+	calls int
+}
+
+func (f *fakeReportUsecase) SalesReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.PeriodSalesResponse], error) {
+	f.calls++
+	return &dto.ReportResponse[dto.PeriodSalesResponse]{
+		From:     "2026-03-01",
+		To:       "2026-03-02",
+		Timezone: "UTC",
+		GroupBy:  "day",
+		Rows: []dto.PeriodSalesResponse{
+			{Period: "2026-03-01"},
+			{Period: "2026-03-02", Sales: 2, SalesTotals: dto.SalesTotals{
+				Units:       3,
+				Revenue:     decimal.RequireFromString("30.50"),
+				Cost:        decimal.RequireFromString("40"),
+				GrossProfit: decimal.RequireFromString("-9.50"),
+			}},
+		},
+	}, nil
+}
+
+func TestGetSalesReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCalls  int
+		wantBody   string
+	}{
+		{
+			name:       "csv",
+			query:      "?format=csv",
+			wantStatus: fiber.StatusOK,
+			wantCalls:  1,
+			wantBody:   "period,sales,units,revenue,cost,gross_profit\n2026-03-01,0,0,0,0,0\n2026-03-02,2,3,30.5,40,-9.5\n",
+		},
+		{
+			name:       "json",
+			wantStatus: fiber.StatusOK,
+			wantCalls:  1,
+		},
+		{
+			name:       "unknown format is rejected before the report runs",
+			query:      "?format=pdf",
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "unknown grouping",
+			query:      "?group_by=year",
+			wantStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:       "unknown time zone",
+			query:      "?timezone=Mars/Olympus_Mons",
+			wantStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := &fakeReportUsecase{}
+			app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler()})
+			app.Get("/reports/sales", NewReportHandler(reports, 0).GetSalesReport)
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/reports/sales"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+			if reports.calls != tt.wantCalls {
+				t.Errorf("report ran %d times, want %d", reports.calls, tt.wantCalls)
+			}
+
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if tt.query == "" {
+				var response struct {
+					Data dto.ReportResponse[dto.PeriodSalesResponse] `json:"data"`
+				}
+				if err := json.Unmarshal(body, &response); err != nil {
+					t.Fatal(err)
+				}
+				if len(response.Data.Rows) != 2 || response.Data.Rows[1].GrossProfit.String() != "-9.5" {
+					t.Errorf("data = %+v, want both days", response.Data)
+				}
+			}
+		})
+	}
+}
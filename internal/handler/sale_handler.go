@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SaleHandler struct {
+	sales   usecase.SaleUsecase
+	batches usecase.ProductBatchUsecase
+}
+
+func NewSaleHandler(sales usecase.SaleUsecase, batches usecase.ProductBatchUsecase) *SaleHandler {
+	return &SaleHandler{sales: sales, batches: batches}
+}
+
+func (h *SaleHandler) CreateSale(c *fiber.Ctx) error {
+	claims, err := requestClaims(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.SaleRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.ValidateCreate(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	response, err := h.sales.CreateSale(c.UserContext(), claims.UserID, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to create sale")
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Sale recorded successfully",
+		"data":    response,
+	})
+}
+
+func (h *SaleHandler) GetSaleByID(c *fiber.Ctx) error {
+	id, err := parseID(c, "sale")
+	if err != nil {
+		return err
+	}
+
+	response, err := h.sales.GetSaleByID(c.UserContext(), id)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to get sale")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Sale retrieved successfully",
+		"data":    response,
+	})
+}
+
+// ReceiveBatch records a delivery of the product named in the path.
+func (h *SaleHandler) ReceiveBatch(c *fiber.Ctx) error {
+	claims, err := requestClaims(c)
+	if err != nil {
+		return err
+	}
+
+	productID, err := parseID(c, "product")
+	if err != nil {
+		return err
+	}
+
+	var req dto.ProductBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.ValidateCreate(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	response, err := h.batches.ReceiveBatch(c.UserContext(), claims.UserID, productID, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("product_id", productID).
+			Msg("Failed to receive product batch")
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Product batch received successfully",
+		"data":    response,
+	})
+}
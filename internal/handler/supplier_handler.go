@@ -1,54 +1,47 @@
 package handler
 
 import (
+	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/usecase"
-	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type SupplierHandler struct {
-	usecase usecase.SupplierUsecase
+	usecase       usecase.SupplierUsecase
+	exportTimeout time.Duration
 }
 
-func NewSupplierHandler(usecase usecase.SupplierUsecase) *SupplierHandler {
-	return &SupplierHandler{usecase: usecase}
+func NewSupplierHandler(usecase usecase.SupplierUsecase, exportTimeout time.Duration) *SupplierHandler {
+	return &SupplierHandler{usecase: usecase, exportTimeout: exportTimeout}
 }
 
 func (h *SupplierHandler) GetSuppliers(c *fiber.Ctx) error {
-	page, err := strconv.Atoi(c.Query("page", "1"))
+	format, err := exportFormat(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page", c.Query("page")).
-			Msg("Invalid page number")
 		return err
 	}
+	if format != "" {
+		return sendExport(c, h.exportTimeout, "suppliers", format, h.usecase.ExportSuppliers)
+	}
 
-	pageSize, err := strconv.Atoi(c.Query("page_size", "10"))
+	page, err := parsePagination(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page_size", c.Query("page_size")).
-			Msg("Invalid page size")
 		return err
 	}
 
-	suppliers, pagination, err := h.usecase.GetAllSuppliers(c.Context(), page, pageSize)
+	suppliers, pagination, err := h.usecase.GetAllSuppliers(c.UserContext(), page)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Msg("Failed to get suppliers")
 		return err
 	}
 
+	setPaginationLinks(c, pagination)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":     "success",
 		"message":    "Suppliers retrieved successfully",
@@ -56,3 +49,36 @@ func (h *SupplierHandler) GetSuppliers(c *fiber.Ctx) error {
 		"pagination": pagination,
 	})
 }
+
+func (h *SupplierHandler) PatchSupplier(c *fiber.Ctx) error {
+	id, err := parseID(c, "supplier")
+	if err != nil {
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.SupplierPatchRequest
+	if err := parsePatch(c, &req); err != nil {
+		return err
+	}
+
+	supplier, err := h.usecase.PatchSupplier(c.UserContext(), id, version, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to patch supplier")
+		return versionConflictError(err)
+	}
+
+	c.Set(fiber.HeaderETag, etag(supplier.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Supplier updated successfully",
+		"data":    supplier,
+	})
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/middleware"
+	"pharmly-backend/internal/usecase"
+	"pharmly-backend/internal/utils"
+	"pharmly-backend/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TwoFactorHandler struct {
+	usecase usecase.TwoFactorUsecase
+}
+
+func NewTwoFactorHandler(usecase usecase.TwoFactorUsecase) *TwoFactorHandler {
+	return &TwoFactorHandler{usecase: usecase}
+}
+
+func (h *TwoFactorHandler) GetStatus(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	status, err := h.usecase.GetStatus(c.UserContext(), claims.UserID)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("user_id", claims.UserID).
+			Msg("Failed to get two-factor status")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Two-factor status retrieved successfully",
+		"data":    status,
+	})
+}
+
+func (h *TwoFactorHandler) Enroll(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	var req dto.TwoFactorEnrollRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	response, err := h.usecase.Enroll(c.UserContext(), claims.UserID, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("user_id", claims.UserID).
+			Msg("Failed to start two-factor enrollment")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Scan the secret with an authenticator app and confirm with a code",
+		"data":    response,
+	})
+}
+
+func (h *TwoFactorHandler) Confirm(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	var req dto.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	issueToken := claims.Purpose == utils.TokenPurposeEnrollment
+	response, err := h.usecase.Confirm(c.UserContext(), claims.UserID, &req, issueToken)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("user_id", claims.UserID).
+			Msg("Failed to confirm two-factor enrollment")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Two-factor authentication enabled, store the recovery codes safely",
+		"data":    response,
+	})
+}
+
+func (h *TwoFactorHandler) Disable(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	var req dto.TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	if err := h.usecase.Disable(c.UserContext(), claims.UserID, &req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("user_id", claims.UserID).
+			Msg("Failed to disable two-factor authentication")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.Claims)
+
+	var req dto.TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	codes, err := h.usecase.RegenerateRecoveryCodes(c.UserContext(), claims.UserID, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("user_id", claims.UserID).
+			Msg("Failed to regenerate recovery codes")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Recovery codes regenerated successfully",
+		"data":    dto.TwoFactorConfirmResponse{RecoveryCodes: codes},
+	})
+}
+
+func (h *TwoFactorHandler) GetRolePolicies(c *fiber.Ctx) error {
+	policies, err := h.usecase.GetRolePolicies(c.UserContext())
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to get role two-factor policies")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Role two-factor policies retrieved successfully",
+		"data":    policies,
+	})
+}
+
+func (h *TwoFactorHandler) SetRolePolicy(c *fiber.Ctx) error {
+	var req dto.RoleTwoFactorPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to parse request body")
+		return middleware.InvalidBody(err)
+	}
+
+	if err := validation.Validate.StructCtx(c.UserContext(), &req); err != nil {
+		return err
+	}
+
+	policy, err := h.usecase.SetRolePolicy(c.UserContext(), c.Params("role"), *req.Required)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Str("role", c.Params("role")).
+			Msg("Failed to set role two-factor policy")
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Role two-factor policy updated successfully",
+		"data":    policy,
+	})
+}
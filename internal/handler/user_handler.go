@@ -1,54 +1,47 @@
 package handler
 
 import (
+	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/usecase"
-	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserHandler struct {
-	usecase usecase.UserUsecase
+	usecase       usecase.UserUsecase
+	exportTimeout time.Duration
 }
 
-func NewUserHandler(usecase usecase.UserUsecase) *UserHandler {
-	return &UserHandler{usecase: usecase}
+func NewUserHandler(usecase usecase.UserUsecase, exportTimeout time.Duration) *UserHandler {
+	return &UserHandler{usecase: usecase, exportTimeout: exportTimeout}
 }
 
 func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
-	page, err := strconv.Atoi(c.Query("page", "1"))
+	format, err := exportFormat(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page", c.Query("page")).
-			Msg("Invalid page number")
 		return err
 	}
+	if format != "" {
+		return sendExport(c, h.exportTimeout, "users", format, h.usecase.ExportUsers)
+	}
 
-	pageSize, err := strconv.Atoi(c.Query("page_size", "10"))
+	page, err := parsePagination(c)
 	if err != nil {
-		logger.Error().
-			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Str("page_size", c.Query("page_size")).
-			Msg("Invalid page size")
 		return err
 	}
 
-	users, pagination, err := h.usecase.GetAllUsers(c.Context(), page, pageSize)
+	users, pagination, err := h.usecase.GetAllUsers(c.UserContext(), page)
 	if err != nil {
-		logger.Error().
+		logger.Ctx(c.UserContext()).Error().
 			Err(err).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
 			Msg("Failed to get users")
 		return err
 	}
 
+	setPaginationLinks(c, pagination)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":     "success",
 		"message":    "Users retrieved successfully",
@@ -56,3 +49,36 @@ func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
 		"pagination": pagination,
 	})
 }
+
+func (h *UserHandler) PatchUser(c *fiber.Ctx) error {
+	id, err := parseID(c, "user")
+	if err != nil {
+		return err
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.UserPatchRequest
+	if err := parsePatch(c, &req); err != nil {
+		return err
+	}
+
+	user, err := h.usecase.PatchUser(c.UserContext(), id, version, &req)
+	if err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Int64("id", id).
+			Msg("Failed to patch user")
+		return versionConflictError(err)
+	}
+
+	c.Set(fiber.HeaderETag, etag(user.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "User updated successfully",
+		"data":    user,
+	})
+}
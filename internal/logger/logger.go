@@ -1,24 +1,84 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
-var log zerolog.Logger
+var (
+	log zerolog.Logger
+	out = os.Stdout
+)
 
 func init() {
-	zerolog.TimeFieldFormat = time.RFC3339
-	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
-	log = zerolog.New(output).With().Timestamp().Logger()
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	log = zerolog.New(out).With().Timestamp().Logger()
+}
+
+// Configure sets the output format, json or console, and the minimum level.
+// JSON is meant for production log shippers; console is for local runs.
+func Configure(format, level string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer
+	switch format {
+	case "json":
+		w = out
+	case "console":
+		w = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	zerolog.SetGlobalLevel(lvl)
+	log = zerolog.New(w).With().Timestamp().Logger()
+	return nil
+}
+
+// Flush commits buffered log output to the underlying file. Errors from
+// outputs that cannot be synced, such as terminals and pipes, are ignored.
+func Flush() {
+	_ = out.Sync()
+}
+
+// Ctx returns the request-scoped logger stored in ctx by NewContext, falling
+// back to the global logger outside of a request.
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zerolog.Logger); ok {
+		return l
+	}
+	return &log
+}
+
+// NewContext returns a copy of ctx carrying l, so code further down the call
+// chain logs with its fields through Ctx.
+func NewContext(ctx context.Context, l zerolog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, &l)
+}
+
+// With starts a child of the global logger for building a request logger.
+func With() zerolog.Context {
+	return log.With()
 }
 
+type loggerKey struct{}
+
 func Info() *zerolog.Event {
 	return log.Info()
 }
 
+func Warn() *zerolog.Event {
+	return log.Warn()
+}
+
 func Error() *zerolog.Event {
 	return log.Error()
 }
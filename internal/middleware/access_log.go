@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"pharmly-backend/internal/logger"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// AccessLog writes one line per request with its status and latency. Like
+// Metrics it runs the error handler itself to see the final status code.
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				c.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		log := logger.Ctx(c.UserContext())
+
+		var event *zerolog.Event
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			event = log.Error()
+		case status >= fiber.StatusBadRequest:
+			event = log.Warn()
+		default:
+			event = log.Info()
+		}
+
+		// Reading a streamed body would buffer all of it, so its size is
+		// not logged.
+		if !c.Response().IsBodyStream() {
+			event = event.Int("bytes", len(c.Response().Body()))
+		}
+
+		event.
+			Str("route", c.Route().Path).
+			Int("status", status).
+			Dur("latency_ms", time.Since(start)).
+			Str("ip", c.IP()).
+			Str("user_agent", c.Get(fiber.HeaderUserAgent)).
+			Msg("Request completed")
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditContext stores the caller's address and request ID in the user
+// context so repositories can attribute audit entries. AuthMiddleware
+// replaces the actor once the caller has been authenticated.
+func AuditContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		setAuditActor(c, nil)
+		return c.Next()
+	}
+}
+
+func setAuditActor(c *fiber.Ctx, claims *utils.Claims) {
+	actor := &audit.Actor{
+		IPAddress: c.IP(),
+		RequestID: GetRequestID(c),
+	}
+
+	if claims != nil {
+		userID := claims.UserID
+		actor.UserID = &userID
+		actor.Username = claims.Username
+		actor.Role = claims.Role
+	}
+
+	c.SetUserContext(audit.WithActor(c.UserContext(), actor))
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"context"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/utils"
 	"strings"
@@ -8,46 +11,99 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func AuthMiddleware() fiber.Handler {
+const (
+	HeaderAPIKey = "X-API-Key"
+	RoleAPIKey   = "api_key"
+)
+
+var (
+	ErrMissingAuthorization    = apperror.Unauthenticated("missing_authorization", "Authorization header is required")
+	ErrMalformedAuthorization  = apperror.Unauthenticated("malformed_authorization", "Invalid authorization header format")
+	ErrNotAuthenticated        = apperror.Unauthenticated("not_authenticated", "User not authenticated")
+	ErrInsufficientPermissions = apperror.Forbidden("insufficient_permissions", "Insufficient permissions")
+)
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey, ip string) (*entity.APIKey, error)
+}
+
+// AuthMiddleware accepts a Bearer access token, or an X-API-Key header when
+// apiKeys is non-nil. API key callers get claims with the api_key role so
+// RoleMiddleware keeps them out of user-only routes.
+func AuthMiddleware(apiKeys APIKeyAuthenticator) fiber.Handler {
+	bearer := authenticate(utils.ValidateToken)
+
+	return func(c *fiber.Ctx) error {
+		rawKey := c.Get(HeaderAPIKey)
+		if rawKey == "" || apiKeys == nil {
+			return bearer(c)
+		}
+
+		key, err := apiKeys.Authenticate(c.UserContext(), rawKey, c.IP())
+		if err != nil {
+			logger.Ctx(c.UserContext()).Error().
+				Str("ip", c.IP()).
+				Err(err).
+				Msg("Failed to validate api key")
+			return err
+		}
+
+		claims := &utils.Claims{
+			UserID:   key.CreatedBy,
+			Username: "api-key:" + key.Name,
+			Role:     RoleAPIKey,
+		}
+		c.Locals("api_key", key)
+		setRequestUser(c, claims)
+		return c.Next()
+	}
+}
+
+// EnrollmentAuthMiddleware accepts a regular access token or the enrollment
+// challenge issued at login to users whose role requires two-factor
+// authentication but who have not enrolled yet.
+func EnrollmentAuthMiddleware() fiber.Handler {
+	return authenticate(func(token string) (*utils.Claims, error) {
+		claims, err := utils.ValidateToken(token)
+		if err == nil {
+			return claims, nil
+		}
+
+		if challenge, challengeErr := utils.ValidateChallengeToken(token, utils.TokenPurposeEnrollment); challengeErr == nil {
+			return challenge, nil
+		}
+		return nil, err
+	})
+}
+
+func authenticate(validate func(string) (*utils.Claims, error)) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
-			logger.Error().
-				Str("path", c.Path()).
-				Str("method", c.Method()).
+			logger.Ctx(c.UserContext()).Error().
 				Msg("Authorization header is required")
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Authorization header is required",
-			})
+			return ErrMissingAuthorization
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			logger.Error().
-				Str("path", c.Path()).
-				Str("method", c.Method()).
+			logger.Ctx(c.UserContext()).Error().
 				Msg("Invalid authorization header format")
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Invalid authorization header format",
-			})
+			return ErrMalformedAuthorization
 		}
 
-		claims, err := utils.ValidateToken(parts[1])
+		claims, err := validate(parts[1])
 		if err != nil {
-			logger.Error().
-				Str("path", c.Path()).
-				Str("method", c.Method()).
+			logger.Ctx(c.UserContext()).Error().
 				Err(err).
 				Msg("Failed to validate token")
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": err.Error(),
-			})
+			if _, ok := apperror.As(err); !ok {
+				err = utils.ErrInvalidToken.Wrap(err)
+			}
+			return err
 		}
 
-		c.Locals("user", claims)
+		setRequestUser(c, claims)
 		return c.Next()
 	}
 }
@@ -56,10 +112,7 @@ func RoleMiddleware(roles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		claims, ok := c.Locals("user").(*utils.Claims)
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": "User not authenticated",
-			})
+			return ErrNotAuthenticated
 		}
 
 		for _, role := range roles {
@@ -68,9 +121,29 @@ func RoleMiddleware(roles ...string) fiber.Handler {
 			}
 		}
 
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Insufficient permissions",
-		})
+		return ErrInsufficientPermissions
+	}
+}
+
+// ScopeMiddleware restricts API key callers to the scopes granted on the key:
+// <resource>:read for safe methods and <resource>:write otherwise. Requests
+// authenticated with a user token are left to RoleMiddleware.
+func ScopeMiddleware(resource string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := c.Locals("api_key").(*entity.APIKey)
+		if !ok {
+			return c.Next()
+		}
+
+		scope := resource + ":write"
+		if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			scope = resource + ":read"
+		}
+
+		if !key.HasScope(scope) {
+			return apperror.Forbidden("missing_scope", "API key is missing scope "+scope)
+		}
+
+		return c.Next()
 	}
 }
@@ -1,136 +1,115 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pharmly-backend/internal/apperror"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/validation"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
-var Validate *validator.Validate
+// ContentTypeProblemJSON is the media type of RFC 7807 error responses.
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code is a stable identifier
+// clients can branch on; Errors lists per-field validation messages.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// errInvalidBody marks errors returned while decoding a request body.
+var errInvalidBody = errors.New("invalid request body")
 
-func init() {
-	Validate = validator.New()
-	Validate.RegisterValidation("password", validatePassword)
+// InvalidBody wraps an error from decoding the request body so it renders as
+// an invalid_body problem instead of a 500.
+func InvalidBody(err error) error {
+	return fmt.Errorf("%w: %w", errInvalidBody, err)
 }
 
-func validatePassword(fl validator.FieldLevel) bool {
-	password := fl.Field().String()
+// ErrorHandler renders every error as problem+json. Domain errors keep their
+// message and code, other errors become a generic 500 so internals such as
+// SQL errors never reach the client.
+func ErrorHandler() fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		problem := problemFor(err, RequestLanguage(c))
+		problem.Instance = c.Path()
+		problem.RequestID = GetRequestID(c)
+
+		log := logger.Ctx(c.UserContext())
+		event := log.Warn()
+		if problem.Status >= fiber.StatusInternalServerError {
+			event = log.Error()
+		}
+		if problem.Errors != nil {
+			event = event.Interface("errors", problem.Errors)
+		}
+		event.
+			Err(err).
+			Int("status_code", problem.Status).
+			Str("code", problem.Code).
+			Msg("Request failed")
 
-	if len(password) < 8 {
-		return false
+		return c.Status(problem.Status).JSON(problem, ContentTypeProblemJSON)
 	}
+}
 
-	if !strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
-		return false
+func problemFor(err error, lang string) Problem {
+	if validationErrors, ok := err.(validator.ValidationErrors); ok {
+		return newProblem(fiber.StatusBadRequest, "validation_failed", validation.FailedMessage(lang), validation.GetValidationErrors(validationErrors, lang))
 	}
 
-	if !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz") {
-		return false
+	if e, ok := apperror.As(err); ok {
+		return newProblem(e.Kind.Status(), e.Code, e.Message, nil)
 	}
 
-	if !strings.ContainsAny(password, "0123456789") {
-		return false
+	if isBodyError(err) {
+		return newProblem(fiber.StatusBadRequest, "invalid_body", "Invalid request body", map[string]string{
+			"body": "Request body is invalid or malformed",
+		})
 	}
 
-	if !strings.ContainsAny(password, "!@#$%^&*()_+-=[]{}|;:,.<>?") {
-		return false
+	if e, ok := err.(*fiber.Error); ok {
+		return newProblem(e.Code, statusCode(e.Code), e.Message, nil)
 	}
 
-	return true
+	return newProblem(fiber.StatusInternalServerError, "internal_error", "An unexpected error occurred", nil)
 }
 
-func GetValidationErrors(err error) map[string]string {
-	errs := make(map[string]string)
+// isBodyError reports whether err came from decoding the request body. The
+// decoder's own message is not shown because it names Go types.
+func isBodyError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.Is(err, errInvalidBody) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
 
-	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		for _, e := range validationErrors {
-			field := strings.ToLower(e.Field())
-			switch e.Tag() {
-			case "required":
-				errs[field] = "This field is required"
-			case "email":
-				errs[field] = "Invalid email format"
-			case "password":
-				errs[field] = "Password must be at least 8 characters long and contain uppercase, lowercase, number, and special character"
-			case "len":
-				errs[field] = "Invalid length"
-			case "gte":
-				errs[field] = "Value must be greater than or equal to " + e.Param()
-			case "lte":
-				errs[field] = "Value must be less than or equal to " + e.Param()
-			default:
-				errs[field] = "Invalid value"
-			}
-		}
+func newProblem(status int, code, detail string, errs map[string]string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  utils.StatusMessage(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: errs,
 	}
-
-	return errs
 }
 
-func ErrorHandler() fiber.ErrorHandler {
-	return func(c *fiber.Ctx, err error) error {
-		code := fiber.StatusInternalServerError
-
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			logger.Error().
-				Err(err).
-				Interface("errors", GetValidationErrors(validationErrors)).
-				Str("path", c.Path()).
-				Str("method", c.Method()).
-				Msg("Validation failed")
-
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Validation failed",
-				"errors":  GetValidationErrors(validationErrors),
-			})
-		}
-
-		if e, ok := err.(*fiber.Error); ok && e.Code == fiber.StatusBadRequest {
-			logger.Error().
-				Err(err).
-				Str("path", c.Path()).
-				Str("method", c.Method()).
-				Msg("Invalid request body")
-
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Invalid request body",
-				"errors": map[string]string{
-					"body": "Request body is invalid or malformed",
-				},
-			})
-		}
-
-		if e, ok := err.(*fiber.Error); ok {
-			code = e.Code
-			logger.Error().
-				Err(err).
-				Int("status_code", code).
-				Str("path", c.Path()).
-				Str("method", c.Method()).
-				Msg("Request failed")
-
-			return c.Status(code).JSON(fiber.Map{
-				"status":  "error",
-				"message": e.Message,
-			})
-		}
-
-		logger.Error().
-			Err(err).
-			Int("status_code", code).
-			Str("path", c.Path()).
-			Str("method", c.Method()).
-			Msg("Request failed")
-
-		return c.Status(code).JSON(fiber.Map{
-			"status":  "error",
-			"message": err.Error(),
-		})
-	}
+// statusCode derives a code such as "precondition_failed" from a status.
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(utils.StatusMessage(status)), " ", "_")
 }
 
 func ValidateRequest() fiber.Handler {
@@ -139,20 +118,22 @@ func ValidateRequest() fiber.Handler {
 			return c.Next()
 		}
 
+		// Multipart uploads cannot be decoded into a map; handlers check the
+		// parts they expect.
+		if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+			return c.Next()
+		}
+
 		var body map[string]interface{}
 		if err := c.BodyParser(&body); err != nil {
-			logger.Error().
+			logger.Ctx(c.UserContext()).Error().
 				Err(err).
-				Str("path", c.Path()).
-				Str("method", c.Method()).
 				Msg("Failed to parse request body")
-			return err
+			return InvalidBody(err)
 		}
 
 		if len(body) == 0 {
-			logger.Error().
-				Str("path", c.Path()).
-				Str("method", c.Method()).
+			logger.Ctx(c.UserContext()).Error().
 				Msg("Request body cannot be empty")
 			return fiber.NewError(fiber.StatusBadRequest, "Request body cannot be empty")
 		}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pharmly-backend/internal/apperror"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProblemFor(t *testing.T) {
+	var typeErr error = &json.UnmarshalTypeError{Value: "string", Field: "price"}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{
+			name:       "domain error keeps code and message",
+			err:        apperror.NotFound("product_not_found", "Product not found"),
+			wantStatus: fiber.StatusNotFound,
+			wantCode:   "product_not_found",
+			wantDetail: "Product not found",
+		},
+		{
+			name:       "fiber 400 keeps its message",
+			err:        fiber.NewError(fiber.StatusBadRequest, "Idempotency-Key must be at most 255 characters"),
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "bad_request",
+			wantDetail: "Idempotency-Key must be at most 255 characters",
+		},
+		{
+			name:       "fiber 412 derives its code from the status",
+			err:        fiber.NewError(fiber.StatusPreconditionFailed, "version mismatch"),
+			wantStatus: fiber.StatusPreconditionFailed,
+			wantCode:   "precondition_failed",
+			wantDetail: "version mismatch",
+		},
+		{
+			name:       "wrapped decode error",
+			err:        InvalidBody(errors.New("unexpected end of JSON input")),
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_body",
+			wantDetail: "Invalid request body",
+		},
+		{
+			name:       "json syntax error",
+			err:        &json.SyntaxError{Offset: 3},
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_body",
+			wantDetail: "Invalid request body",
+		},
+		{
+			name:       "json type error",
+			err:        fmt.Errorf("decode: %w", typeErr),
+			wantStatus: fiber.StatusBadRequest,
+			wantCode:   "invalid_body",
+			wantDetail: "Invalid request body",
+		},
+		{
+			name:       "unknown error is hidden",
+			err:        errors.New("pq: relation does not exist"),
+			wantStatus: fiber.StatusInternalServerError,
+			wantCode:   "internal_error",
+			wantDetail: "An unexpected error occurred",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := problemFor(tt.err, "en")
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode || got.Detail != tt.wantDetail {
+				t.Errorf("problemFor() = %d %q %q, want %d %q %q",
+					got.Status, got.Code, got.Detail, tt.wantStatus, tt.wantCode, tt.wantDetail)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// IdempotencyStore persists Idempotency-Key reservations and the responses
+// they produced. repository.IdempotencyRepository satisfies it.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (*entity.IdempotencyKey, error)
+	Complete(ctx context.Context, record *entity.IdempotencyKey) error
+	Release(ctx context.Context, scope, key string) error
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long a stored response is replayed for the same key.
+	TTL time.Duration
+	// LockTimeout is how long an unfinished request holds its key before a
+	// retry may take it over, e.g. after the instance serving it crashed.
+	LockTimeout time.Duration
+}
+
+// Idempotency makes POST requests carrying an Idempotency-Key header safe to
+// retry. The first request reserves the key and its response is stored;
+// retries with the same body get that response replayed, retries with a
+// different body are rejected with 422, and duplicates arriving while the
+// first request is still running get 409. Keys are scoped to the caller, so
+// it must run after AuthMiddleware. Server errors release the key so the
+// client can retry.
+func Idempotency(store IdempotencyStore, cfg IdempotencyConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if c.Method() != fiber.MethodPost || key == "" {
+			return c.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return fiber.NewError(fiber.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+		}
+
+		now := time.Now()
+		record := &entity.IdempotencyKey{
+			Scope:       idempotencyScope(c),
+			Key:         key,
+			RequestHash: idempotencyRequestHash(c),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(cfg.TTL),
+		}
+
+		existing, err := store.Reserve(c.UserContext(), record, now.Add(-cfg.LockTimeout))
+		if err != nil {
+			logger.Ctx(c.UserContext()).Error().
+				Err(err).
+				Msg("Failed to reserve idempotency key")
+			return err
+		}
+
+		if existing != nil {
+			return replayIdempotent(c, existing, record.RequestHash)
+		}
+
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				releaseIdempotencyKey(c, store, record)
+				return handlerErr
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			releaseIdempotencyKey(c, store, record)
+			return nil
+		}
+
+		completedAt := time.Now()
+		record.StatusCode = &status
+		record.ContentType = string(c.Response().Header.ContentType())
+		record.ResponseBody = append([]byte(nil), c.Response().Body()...)
+		record.CompletedAt = &completedAt
+
+		if err := store.Complete(c.UserContext(), record); err != nil {
+			logger.Ctx(c.UserContext()).Error().
+				Err(err).
+				Msg("Failed to store idempotent response")
+		}
+		return nil
+	}
+}
+
+func replayIdempotent(c *fiber.Ctx, existing *entity.IdempotencyKey, requestHash string) error {
+	if existing.RequestHash != requestHash {
+		logger.Ctx(c.UserContext()).Error().
+			Msg("Idempotency key reused with a different request")
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+	}
+
+	if existing.StatusCode == nil {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return fiber.NewError(fiber.StatusConflict, "A request with this Idempotency-Key is still being processed")
+	}
+
+	logger.Ctx(c.UserContext()).Info().
+		Msg("Replaying idempotent response")
+
+	c.Set(HeaderIdempotentReplayed, "true")
+	if existing.ContentType != "" {
+		c.Set(fiber.HeaderContentType, existing.ContentType)
+	}
+	return c.Status(*existing.StatusCode).Send(existing.ResponseBody)
+}
+
+func releaseIdempotencyKey(c *fiber.Ctx, store IdempotencyStore, record *entity.IdempotencyKey) {
+	if err := store.Release(c.UserContext(), record.Scope, record.Key); err != nil {
+		logger.Ctx(c.UserContext()).Error().
+			Err(err).
+			Msg("Failed to release idempotency key")
+	}
+}
+
+// idempotencyScope keeps keys from different callers apart, so one client
+// cannot replay another client's response by guessing its key.
+func idempotencyScope(c *fiber.Ctx) string {
+	if key, ok := c.Locals("api_key").(*entity.APIKey); ok {
+		return "api_key:" + strconv.FormatInt(key.ID, 10)
+	}
+	if claims, ok := c.Locals("user").(*utils.Claims); ok {
+		return "user:" + strconv.FormatInt(claims.UserID, 10)
+	}
+	return "ip:" + c.IP()
+}
+
+func idempotencyRequestHash(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.OriginalURL()))
+	h.Write([]byte{0})
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"pharmly-backend/internal/repository"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newIdempotencyApp(handler fiber.Handler) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler()})
+	app.Use(Idempotency(repository.NewInMemoryIdempotencyRepository(), IdempotencyConfig{
+		TTL:         time.Hour,
+		LockTimeout: time.Minute,
+	}))
+	app.Post("/orders", handler)
+	return app
+}
+
+func postOrder(t *testing.T, app *fiber.App, key, body string) (*http.Response, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	return resp, string(respBody)
+}
+
+func TestIdempotency(t *testing.T) {
+	type call struct {
+		key          string
+		body         string
+		wantStatus   int
+		wantReplayed bool
+	}
+
+	tests := []struct {
+		name      string
+		status    []int
+		calls     []call
+		wantCalls int
+	}{
+		{
+			name: "retry replays the stored response",
+			calls: []call{
+				{"k1", `{"qty":1}`, fiber.StatusCreated, false},
+				{"k1", `{"qty":1}`, fiber.StatusCreated, true},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "key reused with a different body",
+			calls: []call{
+				{"k1", `{"qty":1}`, fiber.StatusCreated, false},
+				{"k1", `{"qty":2}`, fiber.StatusUnprocessableEntity, false},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "different keys run separately",
+			calls: []call{
+				{"k1", `{"qty":1}`, fiber.StatusCreated, false},
+				{"k2", `{"qty":1}`, fiber.StatusCreated, false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "requests without a key always run",
+			calls: []call{
+				{"", `{"qty":1}`, fiber.StatusCreated, false},
+				{"", `{"qty":1}`, fiber.StatusCreated, false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:   "server error releases the key",
+			status: []int{fiber.StatusInternalServerError},
+			calls: []call{
+				{"k1", `{"qty":1}`, fiber.StatusInternalServerError, false},
+				{"k1", `{"qty":1}`, fiber.StatusCreated, false},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:   "client error is replayed",
+			status: []int{fiber.StatusBadRequest},
+			calls: []call{
+				{"k1", `{"qty":1}`, fiber.StatusBadRequest, false},
+				{"k1", `{"qty":1}`, fiber.StatusBadRequest, true},
+			},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			app := newIdempotencyApp(func(c *fiber.Ctx) error {
+				calls++
+				status := fiber.StatusCreated
+				if calls <= len(tt.status) {
+					status = tt.status[calls-1]
+				}
+				return c.Status(status).JSON(fiber.Map{"order": calls})
+			})
+
+			var first string
+			for i, call := range tt.calls {
+				resp, body := postOrder(t, app, call.key, call.body)
+				if resp.StatusCode != call.wantStatus {
+					t.Fatalf("call %d status = %d, want %d: %s", i, resp.StatusCode, call.wantStatus, body)
+				}
+
+				replayed := resp.Header.Get(HeaderIdempotentReplayed) == "true"
+				if replayed != call.wantReplayed {
+					t.Errorf("call %d replayed = %v, want %v", i, replayed, call.wantReplayed)
+				}
+				if replayed {
+					if body != first {
+						t.Errorf("call %d body = %s, want the stored %s", i, body, first)
+					}
+					if ct := resp.Header.Get(fiber.HeaderContentType); ct != fiber.MIMEApplicationJSON {
+						t.Errorf("call %d content type = %q, want %q", i, ct, fiber.MIMEApplicationJSON)
+					}
+				}
+				if i == 0 {
+					first = body
+				}
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	app := newIdempotencyApp(func(c *fiber.Ctx) error {
+		n := calls.Add(1)
+		if n == 1 {
+			close(started)
+			<-release
+		}
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"order": n})
+	})
+
+	type result struct {
+		resp *http.Response
+		body string
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, body := postOrder(t, app, "k1", `{"qty":1}`)
+		done <- result{resp, body}
+	}()
+	<-started
+
+	// Duplicates arriving while the first request runs are turned away.
+	for range 3 {
+		resp, body := postOrder(t, app, "k1", `{"qty":1}`)
+		if resp.StatusCode != fiber.StatusConflict {
+			t.Fatalf("duplicate status = %d, want %d: %s", resp.StatusCode, fiber.StatusConflict, body)
+		}
+		if retry, _ := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter)); retry <= 0 {
+			t.Errorf("Retry-After = %q, want a positive delay", resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+	}
+
+	close(release)
+	first := <-done
+	if first.resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("first status = %d, want %d: %s", first.resp.StatusCode, fiber.StatusCreated, first.body)
+	}
+
+	resp, body := postOrder(t, app, "k1", `{"qty":1}`)
+	if resp.StatusCode != fiber.StatusCreated || body != first.body {
+		t.Errorf("retry = %d %s, want the first response %s replayed", resp.StatusCode, body, first.body)
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"pharmly-backend/internal/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestLanguage picks the best supported language from Accept-Language,
+// defaulting to English.
+func RequestLanguage(c *fiber.Ctx) string {
+	if lang := c.AcceptsLanguages(validation.LangEnglish, validation.LangIndonesian); lang != "" {
+		return lang
+	}
+	return validation.LangEnglish
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"pharmly-backend/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records request counts and latency by route template, so
+// /products/1 and /products/2 share one series. It runs the error handler
+// itself to observe the final status code, and must be registered first.
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		metrics.HTTPRequestsInFlight.Inc()
+		defer metrics.HTTPRequestsInFlight.Dec()
+
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				c.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		// Requests that match no handler end on the last middleware's
+		// prefix, so unknown paths cannot grow the series count.
+		route := c.Route().Path
+
+		status := strconv.Itoa(c.Response().StatusCode())
+		metrics.HTTPRequestsTotal.WithLabelValues(c.Method(), route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Method(), route, status).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	localRequestID     = "request_id"
+	maxRequestIDLength = 128
+)
+
+// RequestID propagates the caller's X-Request-ID, or generates one, echoes it
+// in the response and stores a logger tagged with it in the user context, so
+// every log line written while serving the request carries the ID.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Locals(localRequestID, id)
+		c.Set(fiber.HeaderXRequestID, id)
+
+		log := logger.With().
+			Str("request_id", id).
+			Str("method", c.Method()).
+			Str("path", c.Path()).
+			Logger()
+		c.SetUserContext(logger.NewContext(c.UserContext(), log))
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned by RequestID.
+func GetRequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(localRequestID).(string)
+	return id
+}
+
+// validRequestID accepts IDs made of characters that are safe to log and
+// echo back, rejecting anything a client could use to forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// setRequestUser records the authenticated caller for handlers, audit
+// entries and the request logger.
+func setRequestUser(c *fiber.Ctx, claims *utils.Claims) {
+	c.Locals("user", claims)
+	setAuditActor(c, claims)
+
+	ctx := c.UserContext()
+	log := logger.Ctx(ctx).With().
+		Int64("user_id", claims.UserID).
+		Str("role", claims.Role).
+		Logger()
+	c.SetUserContext(logger.NewContext(ctx, log))
+}
@@ -0,0 +1,39 @@
+// Package pagination encodes the opaque cursors used for keyset pagination.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"pharmly-backend/internal/apperror"
+)
+
+var ErrInvalidCursor = apperror.Validation("invalid_cursor", "Invalid pagination cursor")
+
+// cursor is the position of a row in a list ordered by ID. It is encoded as
+// base64 JSON so clients treat it as opaque and fields can be added later.
+type cursor struct {
+	ID int64 `json:"id"`
+}
+
+// EncodeCursor returns the cursor pointing at the row with the given ID.
+func EncodeCursor(id int64) string {
+	data, _ := json.Marshal(cursor{ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the row ID a cursor points at.
+func DecodeCursor(token string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidCursor.Wrap(err)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return 0, ErrInvalidCursor.Wrap(err)
+	}
+	if c.ID <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return c.ID, nil
+}
@@ -0,0 +1,54 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"pharmly-backend/internal/dto"
+	"strconv"
+	"strings"
+)
+
+// LinkHeader renders the RFC 5988 Link header value for a list response.
+// Links are relative to requestURI and keep its other query parameters, such
+// as filters and page_size. Page number links are preferred over cursors
+// when the request paged by number.
+func LinkHeader(requestURI string, response *dto.PaginationResponse) string {
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return ""
+	}
+
+	var links []string
+	add := func(rel, key, value string) {
+		query := u.Query()
+		query.Del("page")
+		query.Del("after")
+		query.Del("before")
+		if key != "" {
+			query.Set(key, value)
+		}
+
+		link := *u
+		link.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, link.RequestURI(), rel))
+	}
+
+	add("first", "", "")
+	switch {
+	case response.PreviousPage != nil:
+		add("prev", "page", strconv.Itoa(*response.PreviousPage))
+	case response.PrevCursor != "":
+		add("prev", "before", response.PrevCursor)
+	}
+	switch {
+	case response.NextPage != nil:
+		add("next", "page", strconv.Itoa(*response.NextPage))
+	case response.NextCursor != "":
+		add("next", "after", response.NextCursor)
+	}
+	if response.CurrentPage > 0 && response.TotalPages != nil && *response.TotalPages > 0 {
+		add("last", "page", strconv.Itoa(*response.TotalPages))
+	}
+
+	return strings.Join(links, ", ")
+}
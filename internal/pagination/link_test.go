@@ -0,0 +1,141 @@
+package pagination
+
+import (
+	"net/url"
+	"pharmly-backend/internal/dto"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+var linkPattern = regexp.MustCompile(`<([^>]*)>; rel="([^"]*)"`)
+
+// parseLinks returns the query of each link in a Link header by rel.
+func parseLinks(t *testing.T, header string) map[string]url.Values {
+	t.Helper()
+
+	links := map[string]url.Values{}
+	for _, match := range linkPattern.FindAllStringSubmatch(header, -1) {
+		u, err := url.ParseRequestURI(match[1])
+		if err != nil {
+			t.Fatalf("invalid link %q: %v", match[1], err)
+		}
+		if u.Path != "/api/v1/products" {
+			t.Errorf("link %q has path %q, want /api/v1/products", match[2], u.Path)
+		}
+		links[match[2]] = u.Query()
+	}
+	return links
+}
+
+func TestLinkHeader(t *testing.T) {
+	const requestURI = "/api/v1/products?page=2&page_size=10&search=para%20cet&category_id=3"
+	base := url.Values{"page_size": {"10"}, "search": {"para cet"}, "category_id": {"3"}}
+	with := func(key, value string) url.Values {
+		query := url.Values{}
+		for k, v := range base {
+			query[k] = v
+		}
+		if key != "" {
+			query.Set(key, value)
+		}
+		return query
+	}
+
+	tests := []struct {
+		name     string
+		response dto.PaginationResponse
+		want     map[string]url.Values
+	}{
+		{
+			name:     "empty result",
+			response: dto.PaginationResponse{TotalPages: ptr(0), CurrentPage: 1},
+			want:     map[string]url.Values{"first": with("", "")},
+		},
+		{
+			name: "first page",
+			response: dto.PaginationResponse{
+				TotalPages: ptr(3), CurrentPage: 1, NextPage: ptr(2), NextCursor: EncodeCursor(16),
+			},
+			want: map[string]url.Values{
+				"first": with("", ""),
+				"next":  with("page", "2"),
+				"last":  with("page", "3"),
+			},
+		},
+		{
+			name: "middle page",
+			response: dto.PaginationResponse{
+				TotalPages: ptr(3), CurrentPage: 2, NextPage: ptr(3), PreviousPage: ptr(1),
+				NextCursor: EncodeCursor(6), PrevCursor: EncodeCursor(15),
+			},
+			want: map[string]url.Values{
+				"first": with("", ""),
+				"prev":  with("page", "1"),
+				"next":  with("page", "3"),
+				"last":  with("page", "3"),
+			},
+		},
+		{
+			name: "last page",
+			response: dto.PaginationResponse{
+				TotalPages: ptr(3), CurrentPage: 3, PreviousPage: ptr(2), PrevCursor: EncodeCursor(5),
+			},
+			want: map[string]url.Values{
+				"first": with("", ""),
+				"prev":  with("page", "2"),
+				"last":  with("page", "3"),
+			},
+		},
+		{
+			name:     "page past the end",
+			response: dto.PaginationResponse{TotalPages: ptr(3), CurrentPage: 7, PreviousPage: ptr(3)},
+			want: map[string]url.Values{
+				"first": with("", ""),
+				"prev":  with("page", "3"),
+				"last":  with("page", "3"),
+			},
+		},
+		{
+			name: "cursors without total",
+			response: dto.PaginationResponse{
+				NextCursor: EncodeCursor(16), PrevCursor: EncodeCursor(25),
+			},
+			want: map[string]url.Values{
+				"first": with("", ""),
+				"prev":  with("before", EncodeCursor(25)),
+				"next":  with("after", EncodeCursor(16)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLinks(t, LinkHeader(requestURI, &tt.response))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LinkHeader() links = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkHeaderDropsCursorOfRequest(t *testing.T) {
+	requestURI := "/api/v1/products?after=" + EncodeCursor(26) + "&page_size=10"
+	response := dto.PaginationResponse{NextCursor: EncodeCursor(16), PrevCursor: EncodeCursor(25)}
+
+	links := parseLinks(t, LinkHeader(requestURI, &response))
+	want := map[string]url.Values{
+		"first": {"page_size": {"10"}},
+		"prev":  {"page_size": {"10"}, "before": {EncodeCursor(25)}},
+		"next":  {"page_size": {"10"}, "after": {EncodeCursor(16)}},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("LinkHeader() links = %v, want %v", links, want)
+	}
+}
+
+func TestLinkHeaderInvalidURI(t *testing.T) {
+	if got := LinkHeader("not a uri", &dto.PaginationResponse{}); got != "" {
+		t.Errorf("LinkHeader() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,91 @@
+package pagination
+
+import "pharmly-backend/internal/dto"
+
+// Query selects one page of a list ordered by ID, newest first. The page
+// starts Offset rows in or, when paging by cursor, just past the row with
+// AfterID or BeforeID, so deep pages cost no more than the first.
+type Query struct {
+	Limit     int
+	Offset    int
+	AfterID   int64
+	BeforeID  int64
+	WithTotal bool
+}
+
+// NewQuery turns a list request into a page query, decoding the cursor when
+// one was given.
+func NewQuery(req *dto.PaginationRequest) (Query, error) {
+	q := Query{Limit: req.PageSize, WithTotal: req.WithTotal()}
+
+	var err error
+	switch {
+	case req.After != "":
+		q.AfterID, err = DecodeCursor(req.After)
+	case req.Before != "":
+		q.BeforeID, err = DecodeCursor(req.Before)
+	default:
+		q.Offset = (req.Page - 1) * req.PageSize
+	}
+	return q, err
+}
+
+// Page is one page of a list. HasMore reports whether more rows follow in the
+// direction the page was read. Total is nil unless the query asked for it.
+type Page[T any] struct {
+	Items   []T
+	Total   *int64
+	HasMore bool
+}
+
+// Response describes a fetched page for the client. id returns the ID an
+// item is ordered by. Cursors to the neighbouring pages are returned for page
+// number requests too, so a client can switch to cursors at any point.
+func Response[T any](req *dto.PaginationRequest, page *Page[T], id func(T) int64) *dto.PaginationResponse {
+	response := &dto.PaginationResponse{
+		TotalItems: page.Total,
+		PageSize:   req.PageSize,
+	}
+
+	totalPages := -1
+	if page.Total != nil {
+		totalPages = int((*page.Total + int64(req.PageSize) - 1) / int64(req.PageSize))
+		response.TotalPages = &totalPages
+	}
+
+	switch {
+	case req.Before != "":
+		response.HasNextPage = true
+		response.HasPrevPage = page.HasMore
+	case req.After != "":
+		response.HasNextPage = page.HasMore
+		response.HasPrevPage = true
+	default:
+		response.CurrentPage = req.Page
+		response.HasNextPage = page.HasMore
+		response.HasPrevPage = req.Page > 1
+		if response.HasNextPage {
+			nextPage := req.Page + 1
+			response.NextPage = &nextPage
+		}
+		if response.HasPrevPage {
+			// A page beyond the end links back to the last page that has
+			// items rather than to another empty one.
+			prevPage := req.Page - 1
+			if totalPages >= 0 && prevPage > totalPages {
+				prevPage = max(totalPages, 1)
+			}
+			response.PreviousPage = &prevPage
+		}
+	}
+
+	if len(page.Items) > 0 {
+		if response.HasNextPage {
+			response.NextCursor = EncodeCursor(id(page.Items[len(page.Items)-1]))
+		}
+		if response.HasPrevPage {
+			response.PrevCursor = EncodeCursor(id(page.Items[0]))
+		}
+	}
+	return response
+}
@@ -0,0 +1,212 @@
+package pagination
+
+import (
+	"fmt"
+	"pharmly-backend/internal/dto"
+	"reflect"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func ids(from, to int64) []int64 {
+	var items []int64
+	for id := from; id >= to; id-- {
+		items = append(items, id)
+	}
+	return items
+}
+
+func TestNewQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     dto.PaginationRequest
+		want    Query
+		wantErr bool
+	}{
+		{
+			name: "first page",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10},
+			want: Query{Limit: 10, WithTotal: true},
+		},
+		{
+			name: "third page",
+			req:  dto.PaginationRequest{Page: 3, PageSize: 10},
+			want: Query{Limit: 10, Offset: 20, WithTotal: true},
+		},
+		{
+			name: "after cursor",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10, After: EncodeCursor(42)},
+			want: Query{Limit: 10, AfterID: 42},
+		},
+		{
+			name: "before cursor with total",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10, Before: EncodeCursor(7), IncludeTotal: ptr(true)},
+			want: Query{Limit: 10, BeforeID: 7, WithTotal: true},
+		},
+		{
+			name:    "invalid cursor",
+			req:     dto.PaginationRequest{Page: 1, PageSize: 10, After: "not-a-cursor"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewQuery(&tt.req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewQuery() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewQuery() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NewQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		req  dto.PaginationRequest
+		page Page[int64]
+		want dto.PaginationResponse
+	}{
+		{
+			name: "empty result",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10},
+			page: Page[int64]{Total: ptr(int64(0))},
+			want: dto.PaginationResponse{
+				TotalItems:  ptr(int64(0)),
+				TotalPages:  ptr(0),
+				CurrentPage: 1,
+				PageSize:    10,
+			},
+		},
+		{
+			name: "first page",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10},
+			page: Page[int64]{Items: ids(25, 16), Total: ptr(int64(25)), HasMore: true},
+			want: dto.PaginationResponse{
+				TotalItems:  ptr(int64(25)),
+				TotalPages:  ptr(3),
+				CurrentPage: 1,
+				PageSize:    10,
+				HasNextPage: true,
+				NextPage:    ptr(2),
+				NextCursor:  EncodeCursor(16),
+			},
+		},
+		{
+			name: "last page",
+			req:  dto.PaginationRequest{Page: 3, PageSize: 10},
+			page: Page[int64]{Items: ids(5, 1), Total: ptr(int64(25))},
+			want: dto.PaginationResponse{
+				TotalItems:   ptr(int64(25)),
+				TotalPages:   ptr(3),
+				CurrentPage:  3,
+				PageSize:     10,
+				HasPrevPage:  true,
+				PreviousPage: ptr(2),
+				PrevCursor:   EncodeCursor(5),
+			},
+		},
+		{
+			name: "page past the end links back to the last page",
+			req:  dto.PaginationRequest{Page: 7, PageSize: 10},
+			page: Page[int64]{Total: ptr(int64(25))},
+			want: dto.PaginationResponse{
+				TotalItems:   ptr(int64(25)),
+				TotalPages:   ptr(3),
+				CurrentPage:  7,
+				PageSize:     10,
+				HasPrevPage:  true,
+				PreviousPage: ptr(3),
+			},
+		},
+		{
+			name: "after cursor",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10, After: EncodeCursor(26)},
+			page: Page[int64]{Items: ids(25, 16), HasMore: true},
+			want: dto.PaginationResponse{
+				PageSize:    10,
+				HasNextPage: true,
+				HasPrevPage: true,
+				NextCursor:  EncodeCursor(16),
+				PrevCursor:  EncodeCursor(25),
+			},
+		},
+		{
+			name: "after cursor on the last page",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10, After: EncodeCursor(6)},
+			page: Page[int64]{Items: ids(5, 1)},
+			want: dto.PaginationResponse{
+				PageSize:    10,
+				HasPrevPage: true,
+				PrevCursor:  EncodeCursor(5),
+			},
+		},
+		{
+			name: "before cursor on the first page",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10, Before: EncodeCursor(15)},
+			page: Page[int64]{Items: ids(25, 16)},
+			want: dto.PaginationResponse{
+				PageSize:    10,
+				HasNextPage: true,
+				NextCursor:  EncodeCursor(16),
+			},
+		},
+		{
+			name: "before cursor with more before",
+			req:  dto.PaginationRequest{Page: 1, PageSize: 10, Before: EncodeCursor(5)},
+			page: Page[int64]{Items: ids(15, 6), HasMore: true},
+			want: dto.PaginationResponse{
+				PageSize:    10,
+				HasNextPage: true,
+				HasPrevPage: true,
+				NextCursor:  EncodeCursor(6),
+				PrevCursor:  EncodeCursor(15),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Response(&tt.req, &tt.page, func(id int64) int64 { return id })
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Response() = %s, want %s", describe(*got), describe(tt.want))
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	if id, err := DecodeCursor(EncodeCursor(42)); err != nil || id != 42 {
+		t.Fatalf("DecodeCursor(EncodeCursor(42)) = %d, %v", id, err)
+	}
+
+	for _, token := range []string{"", "%%%", EncodeCursor(0), "bnVsbA"} {
+		if _, err := DecodeCursor(token); err == nil {
+			t.Errorf("DecodeCursor(%q) error = nil, want error", token)
+		}
+	}
+}
+
+// describe prints a response with its pointer fields dereferenced.
+func describe(r dto.PaginationResponse) string {
+	deref := func(v any) any {
+		rv := reflect.ValueOf(v)
+		if rv.IsNil() {
+			return nil
+		}
+		return rv.Elem().Interface()
+	}
+	return fmt.Sprintf("{total_items:%v total_pages:%v current:%d size:%d next:%v prev:%v next_page:%v prev_page:%v next_cursor:%q prev_cursor:%q}",
+		deref(r.TotalItems), deref(r.TotalPages), r.CurrentPage, r.PageSize, r.HasNextPage, r.HasPrevPage,
+		deref(r.NextPage), deref(r.PreviousPage), r.NextCursor, r.PrevCursor)
+}
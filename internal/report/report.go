@@ -0,0 +1,195 @@
+// Package report resolves the dates a sales report covers. Reports count
+// whole days of the pharmacy's local calendar, so a range of dates and the
+// days, weeks or months it is grouped by are turned into instants in the
+// report's time zone before they reach the database.
+package report
+
+import (
+	"pharmly-backend/internal/apperror"
+	"time"
+
+	// Embeds the time zone database so reports work on hosts without one.
+	_ "time/tzdata"
+)
+
+const (
+	GroupByDay   = "day"
+	GroupByWeek  = "week"
+	GroupByMonth = "month"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// defaultDays is how many days a report covers when no start is given.
+	defaultDays = 30
+
+	// MaxDays bounds the range of a single report.
+	MaxDays = 366
+)
+
+var (
+	ErrInvalidDate     = apperror.Validation("invalid_report_date", "from and to must be dates formatted as YYYY-MM-DD")
+	ErrInvalidRange    = apperror.Validation("invalid_report_range", "from must not be after to")
+	ErrRangeTooLong    = apperror.Validation("report_range_too_long", "a report can cover at most 366 days")
+	ErrInvalidTimezone = apperror.Validation("invalid_timezone", "timezone must be an IANA time zone such as Asia/Jakarta")
+)
+
+// Range is the span of whole local days from From to To, both inclusive.
+// From and To are dates, held as midnight UTC, and Location is the time zone
+// whose midnights bound each day.
+type Range struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+// NewRange parses the from and to dates of a report in the named time zone.
+// Without to the range ends today, and without from it starts defaultDays
+// before its end.
+func NewRange(from, to, timezone string, now time.Time) (Range, error) {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return Range{}, err
+	}
+
+	r := Range{Location: loc, To: date(now.In(loc))}
+	if to != "" {
+		if r.To, err = parseDate(to); err != nil {
+			return Range{}, err
+		}
+	}
+
+	r.From = r.To.AddDate(0, 0, 1-defaultDays)
+	if from != "" {
+		if r.From, err = parseDate(from); err != nil {
+			return Range{}, err
+		}
+	}
+
+	if r.From.After(r.To) {
+		return Range{}, ErrInvalidRange
+	}
+	if r.Days() > MaxDays {
+		return Range{}, ErrRangeTooLong
+	}
+	return r, nil
+}
+
+// LoadLocation returns the IANA time zone with the given name.
+func LoadLocation(name string) (*time.Location, error) {
+	// time.LoadLocation treats "" as UTC and "Local" as the host's zone,
+	// neither of which names a zone the database understands.
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone.Wrap(err)
+	}
+	return loc, nil
+}
+
+// Days is the number of days in the range.
+func (r Range) Days() int {
+	return int(r.To.Sub(r.From).Hours()/24) + 1
+}
+
+// Start is the first instant of the range.
+func (r Range) Start() time.Time {
+	return r.midnight(r.From)
+}
+
+// End is the first instant after the range, so a sale belongs to the range
+// when Start <= sold_at < End.
+func (r Range) End() time.Time {
+	return r.midnight(r.To.AddDate(0, 0, 1))
+}
+
+// midnight is the first instant of day d in the range's time zone. On a day
+// that skips midnight for daylight saving this is the first instant after
+// the gap.
+func (r Range) midnight(d time.Time) time.Time {
+	t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, r.Location)
+	if t.Day() != d.Day() {
+		// time.Date resolved the missing midnight to an instant on the day
+		// before, which ends where the gap does.
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
+
+// Periods returns the first day of every period the range touches, in
+// order. The first and last periods may start before or end after the
+// range.
+func (r Range) Periods(groupBy string) []time.Time {
+	var periods []time.Time
+	for p := PeriodStart(r.From, groupBy); !p.After(r.To); p = nextPeriod(p, groupBy) {
+		periods = append(periods, p)
+	}
+	return periods
+}
+
+// PeriodStart returns the first day of the period containing date d. Weeks
+// start on Monday, as they do for date_trunc in PostgreSQL.
+func PeriodStart(d time.Time, groupBy string) time.Time {
+	d = date(d)
+	switch groupBy {
+	case GroupByWeek:
+		return d.AddDate(0, 0, -(int(d.Weekday())+6)%7)
+	case GroupByMonth:
+		return d.AddDate(0, 0, 1-d.Day())
+	default:
+		return d
+	}
+}
+
+func nextPeriod(p time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case GroupByWeek:
+		return p.AddDate(0, 0, 7)
+	case GroupByMonth:
+		return p.AddDate(0, 1, 0)
+	default:
+		return p.AddDate(0, 0, 1)
+	}
+}
+
+// Filter returns what the report queries select sales on.
+func (r Range) Filter(branchID *int64) Filter {
+	return Filter{
+		Start:    r.Start(),
+		End:      r.End(),
+		Timezone: r.Location.String(),
+		BranchID: branchID,
+	}
+}
+
+// Filter selects the sales made from Start up to but excluding End, at
+// BranchID when it is set. Timezone names the zone whose calendar groups
+// sales into periods.
+type Filter struct {
+	Start    time.Time
+	End      time.Time
+	Timezone string
+	BranchID *int64
+}
+
+// FormatDate formats a date the way report requests give them.
+func FormatDate(d time.Time) string {
+	return d.Format(dateLayout)
+}
+
+func parseDate(value string) (time.Time, error) {
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate.Wrap(err)
+	}
+	return d, nil
+}
+
+// date returns the calendar date of t as midnight UTC.
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,230 @@
+package report
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewRange(t *testing.T) {
+	// 2026-03-29 23:30 UTC is already 2026-03-30 in Jakarta (UTC+7).
+	now := time.Date(2026, 3, 29, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		timezone string
+		wantFrom string
+		wantTo   string
+		wantErr  error
+	}{
+		{
+			name:     "defaults to the last 30 days of the local calendar",
+			timezone: "Asia/Jakarta",
+			wantFrom: "2026-03-01",
+			wantTo:   "2026-03-30",
+		},
+		{
+			name:     "today follows the time zone",
+			timezone: "UTC",
+			wantFrom: "2026-02-28",
+			wantTo:   "2026-03-29",
+		},
+		{
+			name:     "from without to ends today",
+			from:     "2026-03-15",
+			timezone: "UTC",
+			wantFrom: "2026-03-15",
+			wantTo:   "2026-03-29",
+		},
+		{
+			name:     "to without from covers the 30 days before",
+			to:       "2026-01-31",
+			timezone: "UTC",
+			wantFrom: "2026-01-02",
+			wantTo:   "2026-01-31",
+		},
+		{
+			name:     "single day",
+			from:     "2026-03-01",
+			to:       "2026-03-01",
+			timezone: "UTC",
+			wantFrom: "2026-03-01",
+			wantTo:   "2026-03-01",
+		},
+		{
+			name:     "from after to",
+			from:     "2026-03-02",
+			to:       "2026-03-01",
+			timezone: "UTC",
+			wantErr:  ErrInvalidRange,
+		},
+		{
+			name:     "longer than a year",
+			from:     "2025-01-01",
+			to:       "2026-01-02",
+			timezone: "UTC",
+			wantErr:  ErrRangeTooLong,
+		},
+		{
+			name:     "date with a time",
+			from:     "2026-03-01T00:00:00Z",
+			timezone: "UTC",
+			wantErr:  ErrInvalidDate,
+		},
+		{
+			name:     "unknown time zone",
+			timezone: "Mars/Olympus_Mons",
+			wantErr:  ErrInvalidTimezone,
+		},
+		{
+			name:     "host time zone",
+			timezone: "Local",
+			wantErr:  ErrInvalidTimezone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRange(tt.from, tt.to, tt.timezone, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("NewRange() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRange() error = %v", err)
+			}
+
+			if got := FormatDate(r.From); got != tt.wantFrom {
+				t.Errorf("From = %s, want %s", got, tt.wantFrom)
+			}
+			if got := FormatDate(r.To); got != tt.wantTo {
+				t.Errorf("To = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestRangeBoundaries(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		timezone  string
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:      "utc",
+			from:      "2026-03-01",
+			to:        "2026-03-31",
+			timezone:  "UTC",
+			wantStart: "2026-03-01T00:00:00Z",
+			wantEnd:   "2026-04-01T00:00:00Z",
+		},
+		{
+			name:      "ahead of utc",
+			from:      "2026-03-01",
+			to:        "2026-03-01",
+			timezone:  "Asia/Jakarta",
+			wantStart: "2026-02-28T17:00:00Z",
+			wantEnd:   "2026-03-01T17:00:00Z",
+		},
+		{
+			name:      "day that loses an hour to daylight saving",
+			from:      "2026-03-29",
+			to:        "2026-03-29",
+			timezone:  "Europe/Amsterdam",
+			wantStart: "2026-03-28T23:00:00Z",
+			wantEnd:   "2026-03-29T22:00:00Z",
+		},
+		{
+			name:      "day that skips midnight",
+			from:      "2026-09-06",
+			to:        "2026-09-06",
+			timezone:  "America/Santiago",
+			wantStart: "2026-09-06T04:00:00Z",
+			wantEnd:   "2026-09-07T03:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRange(tt.from, tt.to, tt.timezone, time.Now())
+			if err != nil {
+				t.Fatalf("NewRange() error = %v", err)
+			}
+
+			if got := r.Start().UTC().Format(time.RFC3339); got != tt.wantStart {
+				t.Errorf("Start() = %s, want %s", got, tt.wantStart)
+			}
+			if got := r.End().UTC().Format(time.RFC3339); got != tt.wantEnd {
+				t.Errorf("End() = %s, want %s", got, tt.wantEnd)
+			}
+
+			f := r.Filter(nil)
+			if !f.Start.Equal(r.Start()) || !f.End.Equal(r.End()) || f.Timezone != tt.timezone {
+				t.Errorf("Filter() = %+v, want the range's boundaries in %s", f, tt.timezone)
+			}
+		})
+	}
+}
+
+func TestRangePeriods(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		groupBy string
+		want    []string
+	}{
+		{
+			name:    "days",
+			from:    "2026-02-27",
+			to:      "2026-03-02",
+			groupBy: GroupByDay,
+			want:    []string{"2026-02-27", "2026-02-28", "2026-03-01", "2026-03-02"},
+		},
+		{
+			name:    "weeks start on monday",
+			from:    "2026-03-01",
+			to:      "2026-03-16",
+			groupBy: GroupByWeek,
+			want:    []string{"2026-02-23", "2026-03-02", "2026-03-09", "2026-03-16"},
+		},
+		{
+			name:    "months",
+			from:    "2025-12-31",
+			to:      "2026-02-01",
+			groupBy: GroupByMonth,
+			want:    []string{"2025-12-01", "2026-01-01", "2026-02-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRange(tt.from, tt.to, "UTC", time.Now())
+			if err != nil {
+				t.Fatalf("NewRange() error = %v", err)
+			}
+
+			var got []string
+			for _, p := range r.Periods(tt.groupBy) {
+				got = append(got, FormatDate(p))
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Periods() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Periods() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error)
+	GetAll(ctx context.Context) ([]*entity.APIKey, error)
+	Touch(ctx context.Context, id int64, ip string, at time.Time) error
+	Revoke(ctx context.Context, id int64) (bool, error)
+}
+
+type apiKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	logger.Ctx(ctx).Info().Str("name", key.Name).Str("prefix", key.Prefix).Msg("Creating api key")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	err = tx.QueryRow(ctx, constant.QCreateAPIKey, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.AllowedIPs, key.ExpiresAt, key.CreatedBy, now, now).Scan(&key.ID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("name", key.Name).Msg("Failed to create api key")
+		return translateError(err)
+	}
+	key.CreatedAt = now
+	key.UpdatedAt = now
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "api_key", strconv.FormatInt(key.ID, 10), nil, key); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("api_key_id", key.ID).Msg("Api key created successfully")
+	return nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	key, err := scanAPIKey(tx.QueryRow(ctx, constant.QGetAPIKeyByPrefix, prefix))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("prefix", prefix).Msg("Failed to fetch api key")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) GetAll(ctx context.Context) ([]*entity.APIKey, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetAllAPIKeys)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch api keys")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*entity.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan api keys row")
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id int64, ip string, at time.Time) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, constant.QTouchAPIKey, at, ip, id); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("api_key_id", id).Msg("Failed to update api key last use")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64) (bool, error) {
+	logger.Ctx(ctx).Info().Int64("api_key_id", id).Msg("Revoking api key")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := scanAPIKey(tx.QueryRow(ctx, constant.QGetAPIKeyByIDForUpdate, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("api_key_id", id).Msg("Failed to lock api key for revoke")
+		return false, err
+	}
+
+	now := time.Now()
+	tag, err := tx.Exec(ctx, constant.QRevokeAPIKey, now, id)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("api_key_id", id).Msg("Failed to revoke api key")
+		return false, err
+	}
+
+	if tag.RowsAffected() == 1 {
+		after := *before
+		after.RevokedAt = &now
+		after.UpdatedAt = now
+		if err := writeAuditLog(ctx, tx, audit.ActionRevoke, "api_key", strconv.FormatInt(id, 10), before, &after); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func scanAPIKey(row pgx.Row) (*entity.APIKey, error) {
+	key := &entity.APIKey{}
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.AllowedIPs,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.CreatedBy,
+		&key.RevokedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
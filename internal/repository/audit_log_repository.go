@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditLogFilter struct {
+	ActorID    *int64
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditLogRepository interface {
+	GetAll(ctx context.Context, filter AuditLogFilter, q pagination.Query) (*pagination.Page[*entity.AuditLog], error)
+}
+
+type auditLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditLogRepository(db *pgxpool.Pool) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) GetAll(ctx context.Context, filter AuditLogFilter, q pagination.Query) (*pagination.Page[*entity.AuditLog], error) {
+	logger.Ctx(ctx).Info().Int("limit", q.Limit).Int("offset", q.Offset).Msg("Fetching paginated audit logs")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	where, args := auditLogWhere(filter)
+
+	total, err := countPage(ctx, tx, constant.QCountAuditLogQuery, where, args, q)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to get total audit logs count")
+		return nil, err
+	}
+
+	query, args := pageSQL(constant.QGetAllAuditLogs, where, args, q)
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch audit logs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*entity.AuditLog
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan audit logs row")
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	page := newPage(logs, total, q)
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Audit logs fetch successfully")
+	return page, nil
+}
+
+func scanAuditLog(row pgx.Row) (*entity.AuditLog, error) {
+	log := &entity.AuditLog{}
+	err := row.Scan(
+		&log.ID,
+		&log.ActorID,
+		&log.ActorName,
+		&log.ActorRole,
+		&log.Action,
+		&log.EntityType,
+		&log.EntityID,
+		&log.Before,
+		&log.After,
+		&log.IPAddress,
+		&log.RequestID,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+func auditLogWhere(filter AuditLogFilter) (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != nil {
+		add("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		add("entity_id = $%d", filter.EntityID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// writeAuditLog records a change inside the caller's transaction, so the
+// audit entry commits or rolls back together with the change it describes.
+func writeAuditLog(ctx context.Context, tx pgx.Tx, action, entityType, entityID string, before, after any) error {
+	beforeJSON, afterJSON, err := audit.Diff(before, after)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("entity_type", entityType).Msg("Failed to build audit diff")
+		return err
+	}
+
+	actor := audit.ActorFromContext(ctx)
+	var id int64
+	err = tx.QueryRow(ctx, constant.QCreateAuditLog,
+		actor.UserID,
+		actor.Username,
+		actor.Role,
+		action,
+		entityType,
+		entityID,
+		beforeJSON,
+		afterJSON,
+		actor.IPAddress,
+		actor.RequestID,
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("entity_type", entityType).Str("entity_id", entityID).Msg("Failed to write audit log")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BranchRepository interface {
+	Create(ctx context.Context, branch *entity.Branch) error
+	GetAll(ctx context.Context) ([]*entity.Branch, error)
+	Exists(ctx context.Context, id int64) (bool, error)
+}
+
+type branchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBranchRepository(db *pgxpool.Pool) BranchRepository {
+	return &branchRepository{db: db}
+}
+
+func (r *branchRepository) Create(ctx context.Context, branch *entity.Branch) error {
+	logger.Ctx(ctx).Info().Str("branch", branch.Name).Msg("Creating new branch")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	if err := tx.QueryRow(ctx, constant.QCreateBranch, branch.Name, now, now).Scan(&branch.ID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("branch", branch.Name).Msg("Failed to create branch")
+		return translateError(err)
+	}
+	branch.CreatedAt = now
+	branch.UpdatedAt = now
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "branch", strconv.FormatInt(branch.ID, 10), nil, branch); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("branch_id", branch.ID).Msg("Branch created successfully")
+	return nil
+}
+
+func (r *branchRepository) GetAll(ctx context.Context) ([]*entity.Branch, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetAllBranches)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch branches")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var branches []*entity.Branch
+	for rows.Next() {
+		branch := &entity.Branch{}
+		if err := rows.Scan(&branch.ID, &branch.Name, &branch.CreatedAt, &branch.UpdatedAt); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan branches row")
+			return nil, err
+		}
+		branches = append(branches, branch)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to iterate branches")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return branches, nil
+}
+
+func (r *branchRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, constant.QBranchExists, id).Scan(&exists); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("branch_id", id).Msg("Failed to check branch exists")
+		return false, err
+	}
+	return exists, nil
+}
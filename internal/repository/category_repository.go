@@ -2,47 +2,57 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"pharmly-backend/internal/audit"
 	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type CategoryRepository interface {
-	GetAll(ctx context.Context, page, pageSize int) ([]*entity.Category, int64, error)
+	GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Category], error)
+	Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.Category, error)
+	Exists(ctx context.Context, id int64) (bool, error)
+	IDsByName(ctx context.Context) (map[string][]int64, error)
 }
 
 type categoryRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewCategoryRepository(db *pgx.Conn) CategoryRepository {
+func NewCategoryRepository(db *pgxpool.Pool) CategoryRepository {
 	return &categoryRepository{db: db}
 }
 
-func (r *categoryRepository) GetAll(ctx context.Context, page, pageSize int) ([]*entity.Category, int64, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated categories")
+func (r *categoryRepository) GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Category], error) {
+	logger.Ctx(ctx).Info().Int("limit", q.Limit).Int("offset", q.Offset).Msg("Fetching paginated categories")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	var total int64
-	err = tx.QueryRow(ctx, constant.QCountCategoryQuery).Scan(&total)
+	total, err := countPage(ctx, tx, constant.QCountCategoryQuery, "", nil, q)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to get total categories count")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to get total categories count")
+		return nil, err
 	}
 
-	offset := (page - 1) * pageSize
-	rows, err := tx.Query(ctx, constant.QGetAllCategories, pageSize, offset)
+	query, args := pageSQL(constant.QGetAllCategories, "", nil, q)
+	rows, err := tx.Query(ctx, query, args...)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch categories")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch categories")
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -57,19 +67,143 @@ func (r *categoryRepository) GetAll(ctx context.Context, page, pageSize int) ([]
 			&category.CreatedAt,
 			&category.UpdatedAt,
 			&category.DeletedAt,
+			&category.Version,
 		)
 		if err != nil {
-			logger.Error().Err(err).Msg("Failed to scan categories row")
-			return nil, 0, err
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan categories row")
+			return nil, err
 		}
 		categories = append(categories, category)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
 	}
 
-	logger.Info().Int("count", len(categories)).Int64("total", total).Msg("categories fetch successfully")
-	return categories, total, nil
+	page := newPage(categories, total, q)
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("categories fetch successfully")
+	return page, nil
+}
+
+var categoryPatchColumns = map[string]bool{
+	"name":               true,
+	"description":        true,
+	"parent_category_id": true,
+}
+
+func (r *categoryRepository) Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.Category, error) {
+	logger.Ctx(ctx).Info().Int64("category_id", id).Int("fields", len(fields)).Msg("Patching category")
+
+	sets, args, err := patchAssignments(fields, categoryPatchColumns)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("category_id", id).Msg("Invalid category patch")
+		return nil, err
+	}
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := scanCategory(tx.QueryRow(ctx, constant.QGetCategoryByIDForUpdate, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("category_id", id).Msg("Failed to lock category for patch")
+		return nil, notFoundError(err, ErrCategoryNotFound)
+	}
+
+	if !VersionMatches(before.Version, version) {
+		logger.Ctx(ctx).Error().Int64("category_id", id).Int64("version", version).Msg("Category version conflict")
+		return nil, ErrVersionConflict
+	}
+
+	query := fmt.Sprintf(constant.QPatchCategory, sets, len(args)+1, len(args)+2, len(args)+3)
+	category, err := scanCategory(tx.QueryRow(ctx, query, append(args, time.Now(), id, before.Version)...))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("category_id", id).Msg("Failed to patch category")
+		return nil, translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUpdate, "category", strconv.FormatInt(id, 10), before, category); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Int64("category_id", id).Msg("Category patched successfully")
+	return category, nil
+}
+
+// Exists reports whether a category that has not been deleted has the given ID.
+func (r *categoryRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, constant.QCategoryExists, id).Scan(&exists); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("category_id", id).Msg("Failed to check category exists")
+		return false, err
+	}
+	return exists, nil
+}
+
+// IDsByName maps the lower-cased name of every category that has not been
+// deleted to its IDs. Names are not unique, so a name may map to several.
+func (r *categoryRepository) IDsByName(ctx context.Context) (map[string][]int64, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetCategoryNames)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch category names")
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string][]int64)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan categories row")
+			return nil, err
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		ids[key] = append(ids[key], id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch category names")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func scanCategory(row pgx.Row) (*entity.Category, error) {
+	category := &entity.Category{}
+	err := row.Scan(
+		&category.ID,
+		&category.Name,
+		&category.Description,
+		&category.ParentCategoryID,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+		&category.DeletedAt,
+		&category.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
 }
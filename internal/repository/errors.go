@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"pharmly-backend/internal/apperror"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrVersionConflict is returned when an update or delete guarded by a
+	// version number matched no row because the record changed in between.
+	ErrVersionConflict = apperror.Conflict("version_conflict", "resource was modified by another request")
+
+	ErrDuplicate          = apperror.Conflict("duplicate", "resource already exists")
+	ErrReferenceViolation = apperror.Conflict("reference_violation", "referenced resource does not exist or is still in use")
+	ErrEmailTaken         = apperror.Conflict("email_taken", "email already exists")
+	ErrUsernameTaken      = apperror.Conflict("username_taken", "username already exists")
+	ErrUnknownCategory    = apperror.Conflict("unknown_category", "category does not exist")
+	ErrUnknownSupplier    = apperror.Conflict("unknown_supplier", "supplier does not exist")
+	ErrUnknownParent      = apperror.Conflict("unknown_parent_category", "parent category does not exist")
+	ErrUnknownBranch      = apperror.Conflict("unknown_branch", "branch does not exist")
+	ErrBranchNameTaken    = apperror.Conflict("branch_name_taken", "branch name already exists")
+	ErrProductHasSales    = apperror.Conflict("product_has_sales", "product has recorded sales and cannot be deleted")
+	ErrInsufficientStock  = apperror.Conflict("insufficient_stock", "not enough stock")
+
+	ErrNoPendingEnrollment = apperror.Conflict("no_pending_enrollment", "no pending totp enrollment")
+	ErrAmbiguousBarcode    = apperror.Conflict("ambiguous_barcode", "barcode matches more than one product")
+
+	ErrProductNotFound   = apperror.NotFound("product_not_found", "product not found")
+	ErrCategoryNotFound  = apperror.NotFound("category_not_found", "category not found")
+	ErrSupplierNotFound  = apperror.NotFound("supplier_not_found", "supplier not found")
+	ErrUserNotFound      = apperror.NotFound("user_not_found", "user not found")
+	ErrImportJobNotFound = apperror.NotFound("import_job_not_found", "import job not found")
+	ErrSaleNotFound      = apperror.NotFound("sale_not_found", "sale not found")
+)
+
+// constraintErrors gives violations of known constraints a specific code.
+var constraintErrors = map[string]*apperror.Error{
+	"users_email_key":                    ErrEmailTaken,
+	"users_username_key":                 ErrUsernameTaken,
+	"products_category_id_fkey":          ErrUnknownCategory,
+	"products_supplier_id_fkey":          ErrUnknownSupplier,
+	"categories_parent_category_id_fkey": ErrUnknownParent,
+	"branches_name_key":                  ErrBranchNameTaken,
+	"product_batches_branch_id_fkey":     ErrUnknownBranch,
+	"sales_branch_id_fkey":               ErrUnknownBranch,
+	"sale_items_product_id_fkey":         ErrProductHasSales,
+	"sale_items_batch_id_fkey":           ErrProductHasSales,
+}
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// notFoundError maps pgx.ErrNoRows to notFound and returns any other error
+// unchanged.
+func notFoundError(err error, notFound *apperror.Error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notFound.Wrap(err)
+	}
+	return err
+}
+
+// translateError turns unique and foreign key violations into conflict
+// errors and returns any other error unchanged.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation, pgForeignKeyViolation:
+	default:
+		return err
+	}
+
+	if known, ok := constraintErrors[pgErr.ConstraintName]; ok {
+		return known.Wrap(err)
+	}
+	if pgErr.Code == pgUniqueViolation {
+		return ErrDuplicate.Wrap(err)
+	}
+	return ErrReferenceViolation.Wrap(err)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"pharmly-backend/internal/entity"
+	"sync"
+	"time"
+)
+
+// inMemoryIdempotencyRepository keeps idempotency keys in process memory.
+// It is meant for tests and single-instance development setups.
+type inMemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*entity.IdempotencyKey
+}
+
+func NewInMemoryIdempotencyRepository() IdempotencyRepository {
+	return &inMemoryIdempotencyRepository{records: make(map[string]*entity.IdempotencyKey)}
+}
+
+func (r *inMemoryIdempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (*entity.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := record.Scope + "\x00" + record.Key
+	if existing, ok := r.records[id]; ok {
+		expired := !existing.ExpiresAt.After(record.CreatedAt)
+		abandoned := existing.StatusCode == nil && !existing.CreatedAt.After(staleBefore)
+		if !expired && !abandoned {
+			copied := *existing
+			return &copied, nil
+		}
+	}
+
+	stored := *record
+	stored.StatusCode = nil
+	stored.ResponseBody = nil
+	stored.CompletedAt = nil
+	r.records[id] = &stored
+	return nil, nil
+}
+
+func (r *inMemoryIdempotencyRepository) Complete(ctx context.Context, record *entity.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.records[record.Scope+"\x00"+record.Key]
+	if !ok {
+		return nil
+	}
+
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.ResponseBody = append([]byte(nil), record.ResponseBody...)
+	existing.CompletedAt = record.CompletedAt
+	return nil
+}
+
+func (r *inMemoryIdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := scope + "\x00" + key
+	if existing, ok := r.records[id]; ok && existing.StatusCode == nil {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+func (r *inMemoryIdempotencyRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			delete(r.records, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims record.Key for a new request. It returns nil when the
+	// key was claimed, or the record currently holding the key. Unfinished
+	// records created before staleBefore are taken over.
+	Reserve(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (*entity.IdempotencyKey, error)
+	Complete(ctx context.Context, record *entity.IdempotencyKey) error
+	Release(ctx context.Context, scope, key string) error
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *entity.IdempotencyKey, staleBefore time.Time) (*entity.IdempotencyKey, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var key string
+	err = tx.QueryRow(ctx, constant.QReserveIdempotencyKey,
+		record.Scope,
+		record.Key,
+		record.RequestHash,
+		record.CreatedAt,
+		record.ExpiresAt,
+		staleBefore,
+	).Scan(&key)
+
+	if err == nil {
+		if err := tx.Commit(ctx); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		logger.Ctx(ctx).Error().Err(err).Str("scope", record.Scope).Msg("Failed to reserve idempotency key")
+		return nil, err
+	}
+
+	existing := &entity.IdempotencyKey{}
+	err = tx.QueryRow(ctx, constant.QGetIdempotencyKey, record.Scope, record.Key).Scan(
+		&existing.Scope,
+		&existing.Key,
+		&existing.RequestHash,
+		&existing.StatusCode,
+		&existing.ContentType,
+		&existing.ResponseBody,
+		&existing.CreatedAt,
+		&existing.CompletedAt,
+		&existing.ExpiresAt,
+	)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("scope", record.Scope).Msg("Failed to fetch idempotency key")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, record *entity.IdempotencyKey) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, constant.QCompleteIdempotencyKey,
+		record.StatusCode,
+		record.ContentType,
+		record.ResponseBody,
+		record.CompletedAt,
+		record.Scope,
+		record.Key,
+	)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("scope", record.Scope).Msg("Failed to store idempotent response")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, constant.QReleaseIdempotencyKey, scope, key); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("scope", scope).Msg("Failed to release idempotency key")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, constant.QPurgeIdempotencyKeys, now)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to purge idempotency keys")
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ImportJobRepository interface {
+	Create(ctx context.Context, job *entity.ImportJob) error
+	GetByID(ctx context.Context, id int64) (*entity.ImportJob, error)
+	// Update saves the status, counters and row errors of a running job.
+	Update(ctx context.Context, job *entity.ImportJob) error
+}
+
+type importJobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewImportJobRepository(db *pgxpool.Pool) ImportJobRepository {
+	return &importJobRepository{db: db}
+}
+
+func (r *importJobRepository) Create(ctx context.Context, job *entity.ImportJob) error {
+	logger.Ctx(ctx).Info().Str("entity_type", job.EntityType).Str("file", job.FileName).Msg("Creating import job")
+
+	now := time.Now()
+	err := r.db.QueryRow(ctx, constant.QCreateImportJob,
+		job.EntityType,
+		job.FileName,
+		job.Status,
+		job.DryRun,
+		job.TotalRows,
+		job.CreatedBy,
+		now,
+		now,
+	).Scan(&job.ID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to create import job")
+		return err
+	}
+
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	return nil
+}
+
+func (r *importJobRepository) GetByID(ctx context.Context, id int64) (*entity.ImportJob, error) {
+	job, err := scanImportJob(r.db.QueryRow(ctx, constant.QGetImportJobByID, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("import_job_id", id).Msg("Failed to fetch import job")
+		return nil, notFoundError(err, ErrImportJobNotFound)
+	}
+	return job, nil
+}
+
+func (r *importJobRepository) Update(ctx context.Context, job *entity.ImportJob) error {
+	rowErrors, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	job.UpdatedAt = time.Now()
+	_, err = r.db.Exec(ctx, constant.QUpdateImportJob,
+		job.Status,
+		job.ProcessedRows,
+		job.CreatedRows,
+		job.UpdatedRows,
+		job.FailedRows,
+		rowErrors,
+		job.Failure,
+		job.UpdatedAt,
+		job.FinishedAt,
+		job.ID,
+	)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("import_job_id", job.ID).Msg("Failed to update import job")
+		return err
+	}
+	return nil
+}
+
+func scanImportJob(row pgx.Row) (*entity.ImportJob, error) {
+	job := &entity.ImportJob{}
+	var rowErrors []byte
+	err := row.Scan(
+		&job.ID,
+		&job.EntityType,
+		&job.FileName,
+		&job.Status,
+		&job.DryRun,
+		&job.TotalRows,
+		&job.ProcessedRows,
+		&job.CreatedRows,
+		&job.UpdatedRows,
+		&job.FailedRows,
+		&rowErrors,
+		&job.Failure,
+		&job.CreatedBy,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rowErrors, &job.Errors); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
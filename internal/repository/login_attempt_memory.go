@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"pharmly-backend/internal/entity"
+	"sync"
+	"time"
+)
+
+// inMemoryLoginAttemptRepository keeps attempts and lockouts in process
+// memory. It is meant for tests and single-instance development setups.
+type inMemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	nextID   int64
+	attempts []*entity.LoginAttempt
+	lockouts map[string]*entity.LoginLockout
+}
+
+func NewInMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &inMemoryLoginAttemptRepository{lockouts: make(map[string]*entity.LoginLockout)}
+}
+
+func (r *inMemoryLoginAttemptRepository) CreateAttempt(ctx context.Context, attempt *entity.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	attempt.ID = r.nextID
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+
+	stored := *attempt
+	r.attempts = append(r.attempts, &stored)
+	return nil
+}
+
+func (r *inMemoryLoginAttemptRepository) GetLockout(ctx context.Context, key string) (*entity.LoginLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lockout, ok := r.lockouts[key]
+	if !ok {
+		return nil, nil
+	}
+
+	copied := *lockout
+	return &copied, nil
+}
+
+func (r *inMemoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lockout, ok := r.lockouts[key]
+	if !ok {
+		lockout = &entity.LoginLockout{Key: key}
+		r.lockouts[key] = lockout
+	}
+
+	if lockout.LastFailedAt.Before(windowStart) {
+		lockout.FailedCount = 1
+	} else {
+		lockout.FailedCount++
+	}
+	lockout.LastFailedAt = now
+	lockout.UpdatedAt = now
+
+	return lockout.FailedCount, nil
+}
+
+func (r *inMemoryLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lockout, ok := r.lockouts[key]; ok {
+		lockout.LockedUntil = &until
+		lockout.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *inMemoryLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.lockouts, key)
+	return nil
+}
+
+func (r *inMemoryLoginAttemptRepository) Unlock(ctx context.Context, key string, userID int64) error {
+	return r.Reset(ctx, key)
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttemptRepository interface {
+	CreateAttempt(ctx context.Context, attempt *entity.LoginAttempt) error
+	GetLockout(ctx context.Context, key string) (*entity.LoginLockout, error)
+	RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	Unlock(ctx context.Context, key string, userID int64) error
+}
+
+type loginAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(db *pgxpool.Pool) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) CreateAttempt(ctx context.Context, attempt *entity.LoginAttempt) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+
+	err = tx.QueryRow(ctx, constant.QCreateLoginAttempt, attempt.UserID, attempt.Email, attempt.IPAddress, attempt.UserAgent, attempt.Success, attempt.Reason, attempt.CreatedAt).Scan(&attempt.ID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("email", attempt.Email).Msg("Failed to record login attempt")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) GetLockout(ctx context.Context, key string) (*entity.LoginLockout, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	lockout := &entity.LoginLockout{}
+	err = tx.QueryRow(ctx, constant.QGetLoginLockout, key).Scan(
+		&lockout.Key,
+		&lockout.FailedCount,
+		&lockout.LockedUntil,
+		&lockout.LastFailedAt,
+		&lockout.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("key", key).Msg("Failed to fetch login lockout")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return lockout, nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var count int
+	err = tx.QueryRow(ctx, constant.QRecordLoginFailure, key, now, windowStart).Scan(&count)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("key", key).Msg("Failed to record login failure")
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	logger.Ctx(ctx).Info().Str("key", key).Time("locked_until", until).Msg("Locking login")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, constant.QLockLogin, until, time.Now(), key)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("key", key).Msg("Failed to lock login")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, constant.QResetLoginLockout, key)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("key", key).Msg("Failed to reset login lockout")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+// Unlock clears the lockout on key like Reset, and records in the audit log
+// that the user's account was unlocked.
+func (r *loginAttemptRepository) Unlock(ctx context.Context, key string, userID int64) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	before := &entity.LoginLockout{}
+	err = tx.QueryRow(ctx, constant.QUnlockLogin, key).Scan(
+		&before.Key,
+		&before.FailedCount,
+		&before.LockedUntil,
+		&before.LastFailedAt,
+		&before.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		before = nil
+	} else if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("key", key).Msg("Failed to unlock login")
+		return err
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUnlock, "user", strconv.FormatInt(userID, 10), before, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pharmly-backend/internal/pagination"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pageSQL appends the keyset condition, ordering and limit for q to a list
+// query filtered by where, which is empty or starts with " WHERE ". One row
+// more than the limit is fetched to tell whether another page follows.
+func pageSQL(query, where string, args []any, q pagination.Query) (string, []any) {
+	order := "DESC"
+	switch {
+	case q.AfterID > 0:
+		args = append(args, q.AfterID)
+		where = andWhere(where, fmt.Sprintf("id < $%d", len(args)))
+	case q.BeforeID > 0:
+		args = append(args, q.BeforeID)
+		where = andWhere(where, fmt.Sprintf("id > $%d", len(args)))
+		order = "ASC"
+	}
+
+	args = append(args, q.Limit+1)
+	query = fmt.Sprintf("%s%s ORDER BY id %s LIMIT $%d", query, where, order, len(args))
+	if q.Offset > 0 {
+		args = append(args, q.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+func andWhere(where, condition string) string {
+	if where == "" {
+		return " WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+// countPage counts the rows matching where, skipping the scan when q does
+// not ask for a total.
+func countPage(ctx context.Context, tx pgx.Tx, query, where string, args []any, q pagination.Query) (*int64, error) {
+	if !q.WithTotal {
+		return nil, nil
+	}
+
+	var total int64
+	if err := tx.QueryRow(ctx, query+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// newPage drops the extra row fetched by pageSQL and restores newest-first
+// order for pages read backwards.
+func newPage[T any](items []T, total *int64, q pagination.Query) *pagination.Page[T] {
+	hasMore := len(items) > q.Limit
+	if hasMore {
+		items = items[:q.Limit]
+	}
+	if q.BeforeID > 0 {
+		slices.Reverse(items)
+	}
+	return &pagination.Page[T]{Items: items, Total: total, HasMore: hasMore}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"fmt"
+	"pharmly-backend/internal/apperror"
+	"strings"
+)
+
+// ErrEmptyPatch is returned when a partial update carries no fields.
+var ErrEmptyPatch = apperror.Validation("empty_patch", "no fields to update")
+
+// FieldUpdate is a single column assignment of a partial update. A nil Value
+// sets the column to NULL.
+type FieldUpdate struct {
+	Column string
+	Value  any
+}
+
+// patchAssignments renders the SET list of a partial update as numbered
+// placeholders. Columns are checked against the table's allowlist since they
+// are interpolated into the statement.
+func patchAssignments(fields []FieldUpdate, allowed map[string]bool) (string, []any, error) {
+	if len(fields) == 0 {
+		return "", nil, ErrEmptyPatch
+	}
+
+	sets := make([]string, 0, len(fields))
+	args := make([]any, 0, len(fields)+3)
+	for _, field := range fields {
+		if !allowed[field.Column] {
+			return "", nil, fmt.Errorf("column %q cannot be patched", field.Column)
+		}
+		args = append(args, field.Value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", field.Column, len(args)))
+	}
+	return strings.Join(sets, ", "), args, nil
+}
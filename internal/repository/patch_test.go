@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPatchAssignments(t *testing.T) {
+	allowed := map[string]bool{"name": true, "description": true, "stock": true}
+	var null *string
+
+	tests := []struct {
+		name     string
+		fields   []FieldUpdate
+		wantSets string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "numbered placeholders in order",
+			fields:   []FieldUpdate{{Column: "name", Value: "Paracetamol"}, {Column: "stock", Value: 0}},
+			wantSets: "name = $1, stock = $2",
+			wantArgs: []any{"Paracetamol", 0},
+		},
+		{
+			name:     "nil value writes null",
+			fields:   []FieldUpdate{{Column: "description", Value: null}},
+			wantSets: "description = $1",
+			wantArgs: []any{null},
+		},
+		{
+			name:    "column outside the allowlist",
+			fields:  []FieldUpdate{{Column: "version", Value: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets, args, err := patchAssignments(tt.fields, allowed)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("patchAssignments() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patchAssignments() error = %v", err)
+			}
+			if sets != tt.wantSets || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("patchAssignments() = %q, %v, want %q, %v", sets, args, tt.wantSets, tt.wantArgs)
+			}
+		})
+	}
+
+	if _, _, err := patchAssignments(nil, allowed); !errors.Is(err, ErrEmptyPatch) {
+		t.Errorf("patchAssignments(nil) error = %v, want ErrEmptyPatch", err)
+	}
+}
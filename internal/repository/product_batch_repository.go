@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ProductBatchRepository interface {
+	Create(ctx context.Context, batch *entity.ProductBatch) error
+	GetSellable(ctx context.Context, productID, branchID int64, asOf time.Time) ([]*entity.ProductBatch, error)
+	BatchedStock(ctx context.Context, productID int64) (int, error)
+	Consume(ctx context.Context, id int64, quantity int) error
+}
+
+type productBatchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProductBatchRepository(db *pgxpool.Pool) ProductBatchRepository {
+	return &productBatchRepository{db: db}
+}
+
+func (r *productBatchRepository) Create(ctx context.Context, batch *entity.ProductBatch) error {
+	logger.Ctx(ctx).Info().Int64("product_id", batch.ProductID).Str("batch_number", batch.BatchNumber).Msg("Creating product batch")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch.ReceivedAt = time.Now()
+	err = tx.QueryRow(ctx, constant.QCreateProductBatch, batch.ProductID, batch.BranchID, batch.BatchNumber, batch.CostPrice, batch.QuantityReceived, batch.QuantityRemaining, batch.ExpirationDate, batch.ReceivedBy, batch.ReceivedAt).Scan(&batch.ID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", batch.ProductID).Msg("Failed to create product batch")
+		return translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "product_batch", strconv.FormatInt(batch.ID, 10), nil, batch); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("batch_id", batch.ID).Msg("Product batch created successfully")
+	return nil
+}
+
+// GetSellable locks the batches of a product at a branch that have stock
+// left and do not expire before asOf, soonest to expire first.
+func (r *productBatchRepository) GetSellable(ctx context.Context, productID, branchID int64, asOf time.Time) ([]*entity.ProductBatch, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetSellableBatches, productID, branchID, asOf)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", productID).Int64("branch_id", branchID).Msg("Failed to fetch sellable batches")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []*entity.ProductBatch
+	for rows.Next() {
+		batch, err := scanProductBatch(rows)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan product batches row")
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to iterate product batches")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return batches, nil
+}
+
+// BatchedStock is how many units of a product the batches of every branch
+// still hold, expired or not.
+func (r *productBatchRepository) BatchedStock(ctx context.Context, productID int64) (int, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var stock int
+	if err := tx.QueryRow(ctx, constant.QGetBatchedStock, productID).Scan(&stock); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", productID).Msg("Failed to count batched stock")
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return 0, err
+	}
+
+	return stock, nil
+}
+
+func (r *productBatchRepository) Consume(ctx context.Context, id int64, quantity int) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, constant.QConsumeProductBatch, quantity, id)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("batch_id", id).Msg("Failed to consume product batch")
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		logger.Ctx(ctx).Error().Int64("batch_id", id).Int("quantity", quantity).Msg("Insufficient batch stock")
+		return ErrInsufficientStock
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func scanProductBatch(row pgx.Row) (*entity.ProductBatch, error) {
+	batch := &entity.ProductBatch{}
+	err := row.Scan(
+		&batch.ID,
+		&batch.ProductID,
+		&batch.BranchID,
+		&batch.BatchNumber,
+		&batch.CostPrice,
+		&batch.QuantityReceived,
+		&batch.QuantityRemaining,
+		&batch.ExpirationDate,
+		&batch.ReceivedBy,
+		&batch.ReceivedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
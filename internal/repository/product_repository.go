@@ -2,61 +2,76 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"pharmly-backend/internal/audit"
 	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ProductRepository interface {
 	Create(ctx context.Context, product *entity.Product) error
 	GetByID(ctx context.Context, id int64) (*entity.Product, error)
-	GetAll(ctx context.Context, page, pageSize int) ([]*entity.Product, int64, error)
+	GetByBarcode(ctx context.Context, barcode string) (*entity.Product, error)
+	GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Product], error)
 	Update(ctx context.Context, product *entity.Product) error
-	Delete(ctx context.Context, id int64) error
+	Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.Product, error)
+	Delete(ctx context.Context, id int64, version int64) error
+	AdjustStock(ctx context.Context, id int64, delta int) (*entity.Product, error)
+	CountInventoryAlerts(ctx context.Context, expiringBefore time.Time) (lowStock int64, expiring int64, err error)
 }
 
 type productRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewProductRepository(db *pgx.Conn) ProductRepository {
+func NewProductRepository(db *pgxpool.Pool) ProductRepository {
 	return &productRepository{db: db}
 }
 
 func (r *productRepository) Create(ctx context.Context, product *entity.Product) error {
-	logger.Info().Str("product", product.Name).Msg("Creating new product")
+	logger.Ctx(ctx).Info().Str("product", product.Name).Msg("Creating new product")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	err = tx.QueryRow(ctx, constant.QCreateProduct, product.Name, product.CategoryID, product.GenericName, product.Description, product.Price, product.Stock, product.Unit, product.ExpirationDate, product.Barcode, product.SupplierID, product.MinStock, product.IsActive, time.Now(), time.Now()).Scan(&product.ID)
+	err = tx.QueryRow(ctx, constant.QCreateProduct, product.Name, product.CategoryID, product.GenericName, product.Description, product.Price, product.Stock, product.Unit, product.ExpirationDate, product.Barcode, product.SupplierID, product.MinStock, product.IsActive, time.Now(), time.Now()).Scan(&product.ID, &product.Version)
 	if err != nil {
-		logger.Error().Err(err).Int64("product_id", product.ID).Msg("Failed to create product")
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", product.ID).Msg("Failed to create product")
+		return translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "product", strconv.FormatInt(product.ID, 10), nil, product); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return err
 	}
 
-	logger.Info().Str("product", product.Name).Msg("Product created successfully")
+	logger.Ctx(ctx).Info().Str("product", product.Name).Msg("Product created successfully")
 	return nil
 }
 
 func (r *productRepository) GetByID(ctx context.Context, id int64) (*entity.Product, error) {
-	logger.Info().Int64("product_id", id).Msg("Fetching product by ID")
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Fetching product by ID")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
@@ -79,43 +94,43 @@ func (r *productRepository) GetByID(ctx context.Context, id int64) (*entity.Prod
 		&product.CreatedAt,
 		&product.UpdatedAt,
 		&product.DeletedAt,
+		&product.Version,
 	)
 
 	if err != nil {
-		logger.Error().Err(err).Int64("product_id", product.ID).Msg("Failed to fecth product")
-		return nil, err
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
+		return nil, notFoundError(err, ErrProductNotFound)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return nil, err
 	}
 
 	return product, nil
 }
 
-func (r *productRepository) GetAll(ctx context.Context, page, pageSize int) ([]*entity.Product, int64, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated products")
+func (r *productRepository) GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Product], error) {
+	logger.Ctx(ctx).Info().Int("limit", q.Limit).Int("offset", q.Offset).Msg("Fetching paginated products")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	var total int64
-	err = tx.QueryRow(ctx, constant.QCountProductQuery).Scan(&total)
+	total, err := countPage(ctx, tx, constant.QCountProductQuery, "", nil, q)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to get total products count")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to get total products count")
+		return nil, err
 	}
 
-	offset := (page - 1) * pageSize
-	rows, err := tx.Query(ctx, constant.QGetAllProducts, pageSize, offset)
+	query, args := pageSQL(constant.QGetAllProducts, "", nil, q)
+	rows, err := tx.Query(ctx, query, args...)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch products")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch products")
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -139,35 +154,43 @@ func (r *productRepository) GetAll(ctx context.Context, page, pageSize int) ([]*
 			&product.CreatedAt,
 			&product.UpdatedAt,
 			&product.DeletedAt,
+			&product.Version,
 		)
 		if err != nil {
-			logger.Error().Err(err).Msg("Failed to scan products row")
-			return nil, 0, err
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan products row")
+			return nil, err
 		}
 		products = append(products, product)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
 	}
 
-	logger.Info().Int("count", len(products)).Int64("total", total).Msg("products fetch successfully")
-	return products, total, nil
+	page := newPage(products, total, q)
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("products fetch successfully")
+	return page, nil
 
 }
 
 func (r *productRepository) Update(ctx context.Context, product *entity.Product) error {
-	logger.Info().Int64("product_id", product.ID).Msg("Updating Product")
+	logger.Ctx(ctx).Info().Int64("product_id", product.ID).Msg("Updating Product")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, constant.QUpdateProduct,
+	before, err := scanProduct(tx.QueryRow(ctx, constant.QGetProductByIDForUpdate, product.ID))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", product.ID).Msg("Failed to lock product for update")
+		return notFoundError(err, ErrProductNotFound)
+	}
+
+	err = tx.QueryRow(ctx, constant.QUpdateProduct,
 		product.Name,
 		product.CategoryID,
 		product.GenericName,
@@ -182,43 +205,264 @@ func (r *productRepository) Update(ctx context.Context, product *entity.Product)
 		product.IsActive,
 		time.Now(),
 		product.ID,
-	)
+		product.Version,
+	).Scan(&product.Version)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Ctx(ctx).Error().Int64("product_id", product.ID).Int64("version", product.Version).Msg("Product version conflict")
+		return ErrVersionConflict
+	}
 
 	if err != nil {
-		logger.Error().Err(err).Int64("product_id", product.ID).Msg("Failed to update product")
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", product.ID).Msg("Failed to update product")
+		return translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUpdate, "product", strconv.FormatInt(product.ID, 10), before, product); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return err
 	}
-	logger.Info().Int64("product_id", product.ID).Msg("Product updated successfully")
+	logger.Ctx(ctx).Info().Int64("product_id", product.ID).Msg("Product updated successfully")
 	return nil
 }
 
-func (r *productRepository) Delete(ctx context.Context, id int64) error {
-	logger.Info().Int64("product_id", id).Msg("Deleting product")
+var productPatchColumns = map[string]bool{
+	"name":            true,
+	"category_id":     true,
+	"generic_name":    true,
+	"description":     true,
+	"price":           true,
+	"stock":           true,
+	"unit":            true,
+	"expiration_date": true,
+	"barcode":         true,
+	"supplier_id":     true,
+	"min_stock":       true,
+	"is_active":       true,
+}
+
+func (r *productRepository) Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.Product, error) {
+	logger.Ctx(ctx).Info().Int64("product_id", id).Int("fields", len(fields)).Msg("Patching product")
+
+	sets, args, err := patchAssignments(fields, productPatchColumns)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Invalid product patch")
+		return nil, err
+	}
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := scanProduct(tx.QueryRow(ctx, constant.QGetProductByIDForUpdate, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to lock product for patch")
+		return nil, notFoundError(err, ErrProductNotFound)
+	}
+
+	if !VersionMatches(before.Version, version) {
+		logger.Ctx(ctx).Error().Int64("product_id", id).Int64("version", version).Msg("Product version conflict")
+		return nil, ErrVersionConflict
+	}
+
+	query := fmt.Sprintf(constant.QPatchProduct, sets, len(args)+1, len(args)+2, len(args)+3)
+	product, err := scanProduct(tx.QueryRow(ctx, query, append(args, time.Now(), id, before.Version)...))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to patch product")
+		return nil, translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUpdate, "product", strconv.FormatInt(id, 10), before, product); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Product patched successfully")
+	return product, nil
+}
+
+func (r *productRepository) Delete(ctx context.Context, id int64, version int64) error {
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Deleting product")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, constant.QDeleteProduct, id)
+	before, err := scanProduct(tx.QueryRow(ctx, constant.QGetProductByIDForUpdate, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to lock product for delete")
+		return notFoundError(err, ErrProductNotFound)
+	}
+
+	if !VersionMatches(before.Version, version) {
+		logger.Ctx(ctx).Error().Int64("product_id", id).Int64("version", version).Msg("Product version conflict")
+		return ErrVersionConflict
+	}
+
+	tag, err := tx.Exec(ctx, constant.QDeleteProduct, id, before.Version)
 	if err != nil {
-		logger.Error().Err(err).Int64("product_id", id).Msg("Failed to delete product")
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to delete product")
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		logger.Ctx(ctx).Error().Int64("product_id", id).Int64("version", version).Msg("Product version conflict")
+		return ErrVersionConflict
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionDelete, "product", strconv.FormatInt(id, 10), before, nil); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return err
 	}
 
-	logger.Info().Int64("product_id", id).Msg("Product deleted successfully")
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Product deleted successfully")
 
 	return nil
 }
+
+func scanProduct(row pgx.Row) (*entity.Product, error) {
+	product := &entity.Product{}
+	err := row.Scan(
+		&product.ID,
+		&product.Name,
+		&product.CategoryID,
+		&product.GenericName,
+		&product.Description,
+		&product.Price,
+		&product.Stock,
+		&product.Unit,
+		&product.ExpirationDate,
+		&product.Barcode,
+		&product.SupplierID,
+		&product.MinStock,
+		&product.IsActive,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+		&product.DeletedAt,
+		&product.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// AdjustStock adds delta, which may be negative, to the stock of a product.
+// It returns ErrInsufficientStock rather than let stock drop below zero.
+func (r *productRepository) AdjustStock(ctx context.Context, id int64, delta int) (*entity.Product, error) {
+	logger.Ctx(ctx).Info().Int64("product_id", id).Int("delta", delta).Msg("Adjusting product stock")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := scanProduct(tx.QueryRow(ctx, constant.QGetProductByIDForUpdate, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to lock product for stock adjustment")
+		return nil, notFoundError(err, ErrProductNotFound)
+	}
+
+	after := *before
+	after.UpdatedAt = time.Now()
+	err = tx.QueryRow(ctx, constant.QAdjustProductStock, delta, after.UpdatedAt, id).Scan(&after.Stock, &after.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Ctx(ctx).Error().Int64("product_id", id).Int("stock", before.Stock).Int("delta", delta).Msg("Insufficient product stock")
+		return nil, ErrInsufficientStock
+	}
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to adjust product stock")
+		return nil, err
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUpdate, "product", strconv.FormatInt(id, 10), before, &after); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Int("stock", after.Stock).Msg("Product stock adjusted successfully")
+	return &after, nil
+}
+
+// GetByBarcode returns the product that has not been deleted with the given
+// barcode. Barcodes are not unique in the table, so more than one match is
+// reported as ErrAmbiguousBarcode rather than picking one.
+func (r *productRepository) GetByBarcode(ctx context.Context, barcode string) (*entity.Product, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetProductsByBarcode, barcode)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("barcode", barcode).Msg("Failed to fetch product by barcode")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*entity.Product
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan products row")
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("barcode", barcode).Msg("Failed to fetch product by barcode")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	switch len(products) {
+	case 0:
+		return nil, ErrProductNotFound
+	case 1:
+		return products[0], nil
+	}
+	return nil, ErrAmbiguousBarcode
+}
+
+// CountInventoryAlerts counts active products at or below their minimum stock
+// and products still in stock that expire on or before expiringBefore.
+func (r *productRepository) CountInventoryAlerts(ctx context.Context, expiringBefore time.Time) (int64, int64, error) {
+	var lowStock, expiring int64
+	err := r.db.QueryRow(ctx, constant.QCountInventoryAlerts, expiringBefore).Scan(&lowStock, &expiring)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to count inventory alerts")
+		return 0, 0, err
+	}
+
+	return lowStock, expiring, nil
+}
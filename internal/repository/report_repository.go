@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/report"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReportRepository aggregates recorded sales. Revenue is what items sold
+// for and cost what their batches cost, so gross profit is the difference.
+type ReportRepository interface {
+	SalesByPeriod(ctx context.Context, f report.Filter, groupBy string) ([]*entity.PeriodSales, error)
+	TopProducts(ctx context.Context, f report.Filter, limit int) ([]*entity.ProductSales, error)
+	SlowProducts(ctx context.Context, f report.Filter, limit int) ([]*entity.ProductSales, error)
+	SalesByCategory(ctx context.Context, f report.Filter) ([]*entity.CategorySales, error)
+	SalesByCashier(ctx context.Context, f report.Filter) ([]*entity.CashierSales, error)
+}
+
+type reportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReportRepository(db *pgxpool.Pool) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+func (r *reportRepository) SalesByPeriod(ctx context.Context, f report.Filter, groupBy string) ([]*entity.PeriodSales, error) {
+	return queryReport(ctx, r.db, "sales by period", constant.QReportSalesByPeriod, []any{groupBy, f.Timezone, f.Start, f.End, f.BranchID}, func(row pgx.Row) (*entity.PeriodSales, error) {
+		sales := &entity.PeriodSales{}
+		err := row.Scan(&sales.Period, &sales.Sales, &sales.Units, &sales.Revenue, &sales.Cost)
+		sales.GrossProfit = sales.Revenue.Sub(sales.Cost)
+		return sales, err
+	})
+}
+
+func (r *reportRepository) TopProducts(ctx context.Context, f report.Filter, limit int) ([]*entity.ProductSales, error) {
+	return queryReport(ctx, r.db, "top products", constant.QReportTopProducts, []any{f.Start, f.End, f.BranchID, limit}, scanProductSales)
+}
+
+func (r *reportRepository) SlowProducts(ctx context.Context, f report.Filter, limit int) ([]*entity.ProductSales, error) {
+	return queryReport(ctx, r.db, "slow products", constant.QReportSlowProducts, []any{f.Start, f.End, f.BranchID, limit}, scanProductSales)
+}
+
+func (r *reportRepository) SalesByCategory(ctx context.Context, f report.Filter) ([]*entity.CategorySales, error) {
+	return queryReport(ctx, r.db, "sales by category", constant.QReportSalesByCategory, []any{f.Start, f.End, f.BranchID}, func(row pgx.Row) (*entity.CategorySales, error) {
+		sales := &entity.CategorySales{}
+		err := row.Scan(&sales.CategoryID, &sales.Name, &sales.Units, &sales.Revenue, &sales.Cost)
+		sales.GrossProfit = sales.Revenue.Sub(sales.Cost)
+		return sales, err
+	})
+}
+
+func (r *reportRepository) SalesByCashier(ctx context.Context, f report.Filter) ([]*entity.CashierSales, error) {
+	return queryReport(ctx, r.db, "sales by cashier", constant.QReportSalesByCashier, []any{f.Start, f.End, f.BranchID}, func(row pgx.Row) (*entity.CashierSales, error) {
+		sales := &entity.CashierSales{}
+		err := row.Scan(&sales.CashierID, &sales.Username, &sales.FullName, &sales.Sales, &sales.Units, &sales.Revenue, &sales.Cost)
+		sales.GrossProfit = sales.Revenue.Sub(sales.Cost)
+		return sales, err
+	})
+}
+
+func scanProductSales(row pgx.Row) (*entity.ProductSales, error) {
+	sales := &entity.ProductSales{}
+	err := row.Scan(&sales.ProductID, &sales.Name, &sales.Units, &sales.Revenue, &sales.Cost)
+	sales.GrossProfit = sales.Revenue.Sub(sales.Cost)
+	return sales, err
+}
+
+// queryReport runs a report query and scans every row it returns. name
+// describes the report in log messages.
+func queryReport[T any](ctx context.Context, db *pgxpool.Pool, name, query string, args []any, scan func(pgx.Row) (T, error)) ([]T, error) {
+	logger.Ctx(ctx).Info().Str("report", name).Msg("Running sales report")
+
+	tx, err := database.BeginTx(ctx, db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("report", name).Msg("Failed to run sales report")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []T
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("report", name).Msg("Failed to scan sales report row")
+			return nil, err
+		}
+		result = append(result, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("report", name).Msg("Failed to iterate sales report")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return result, nil
+}
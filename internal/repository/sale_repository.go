@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SaleRepository interface {
+	Create(ctx context.Context, sale *entity.Sale) error
+	GetByID(ctx context.Context, id int64) (*entity.Sale, error)
+}
+
+type saleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSaleRepository(db *pgxpool.Pool) SaleRepository {
+	return &saleRepository{db: db}
+}
+
+// Create records a sale and its items. Stock is taken out of the products
+// and batches sold from by the caller.
+func (r *saleRepository) Create(ctx context.Context, sale *entity.Sale) error {
+	logger.Ctx(ctx).Info().Int64("branch_id", sale.BranchID).Int64("cashier_id", sale.CashierID).Msg("Creating new sale")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sale.SoldAt = time.Now()
+	err = tx.QueryRow(ctx, constant.QCreateSale, sale.BranchID, sale.CashierID, sale.TotalAmount, sale.TotalCost, sale.SoldAt).Scan(&sale.ID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to create sale")
+		return translateError(err)
+	}
+
+	for i := range sale.Items {
+		item := &sale.Items[i]
+		item.SaleID = sale.ID
+		err := tx.QueryRow(ctx, constant.QCreateSaleItem, item.SaleID, item.ProductID, item.BatchID, item.Quantity, item.UnitPrice, item.UnitCost).Scan(&item.ID)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Int64("sale_id", sale.ID).Int64("product_id", item.ProductID).Msg("Failed to create sale item")
+			return translateError(err)
+		}
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "sale", strconv.FormatInt(sale.ID, 10), nil, sale); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("sale_id", sale.ID).Msg("Sale created successfully")
+	return nil
+}
+
+func (r *saleRepository) GetByID(ctx context.Context, id int64) (*entity.Sale, error) {
+	logger.Ctx(ctx).Info().Int64("sale_id", id).Msg("Fetching sale by ID")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	sale := &entity.Sale{}
+	err = tx.QueryRow(ctx, constant.QGetSaleByID, id).Scan(&sale.ID, &sale.BranchID, &sale.CashierID, &sale.TotalAmount, &sale.TotalCost, &sale.SoldAt)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("sale_id", id).Msg("Failed to fetch sale")
+		return nil, notFoundError(err, ErrSaleNotFound)
+	}
+
+	rows, err := tx.Query(ctx, constant.QGetSaleItems, id)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("sale_id", id).Msg("Failed to fetch sale items")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item entity.SaleItem
+		if err := rows.Scan(&item.ID, &item.SaleID, &item.ProductID, &item.BatchID, &item.Quantity, &item.UnitPrice, &item.UnitCost); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan sale items row")
+			return nil, err
+		}
+		sale.Items = append(sale.Items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to iterate sale items")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return sale, nil
+}
@@ -2,47 +2,57 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"pharmly-backend/internal/audit"
 	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type SupplierRepository interface {
-	GetAll(ctx context.Context, page, pageSize int) ([]*entity.Supplier, int64, error)
+	GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Supplier], error)
+	Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.Supplier, error)
+	Exists(ctx context.Context, id int64) (bool, error)
+	IDsByName(ctx context.Context) (map[string][]int64, error)
 }
 
 type supplierRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewSupplierRepository(db *pgx.Conn) SupplierRepository {
+func NewSupplierRepository(db *pgxpool.Pool) SupplierRepository {
 	return &supplierRepository{db: db}
 }
 
-func (r *supplierRepository) GetAll(ctx context.Context, page, pageSize int) ([]*entity.Supplier, int64, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated products")
+func (r *supplierRepository) GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Supplier], error) {
+	logger.Ctx(ctx).Info().Int("limit", q.Limit).Int("offset", q.Offset).Msg("Fetching paginated products")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	var total int64
-	err = tx.QueryRow(ctx, constant.QCountSupplierQuery).Scan(&total)
+	total, err := countPage(ctx, tx, constant.QCountSupplierQuery, "", nil, q)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to get total suppliers count")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to get total suppliers count")
+		return nil, err
 	}
 
-	offset := (page - 1) * pageSize
-	rows, err := tx.Query(ctx, constant.QGetAllSuppliers, pageSize, offset)
+	query, args := pageSQL(constant.QGetAllSuppliers, "", nil, q)
+	rows, err := tx.Query(ctx, query, args...)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch suppliers")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch suppliers")
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -59,19 +69,147 @@ func (r *supplierRepository) GetAll(ctx context.Context, page, pageSize int) ([]
 			&supplier.CreatedAt,
 			&supplier.UpdatedAt,
 			&supplier.DeletedAt,
+			&supplier.Version,
 		)
 		if err != nil {
-			logger.Error().Err(err).Msg("Failed to scan suppliers row")
-			return nil, 0, err
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan suppliers row")
+			return nil, err
 		}
 		suppliers = append(suppliers, supplier)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
 	}
 
-	logger.Info().Int("count", len(suppliers)).Int64("total", total).Msg("Suppliers fetch successfully")
-	return suppliers, total, nil
+	page := newPage(suppliers, total, q)
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Suppliers fetch successfully")
+	return page, nil
+}
+
+var supplierPatchColumns = map[string]bool{
+	"name":           true,
+	"contact_person": true,
+	"phone":          true,
+	"address":        true,
+	"email":          true,
+}
+
+func (r *supplierRepository) Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.Supplier, error) {
+	logger.Ctx(ctx).Info().Int64("supplier_id", id).Int("fields", len(fields)).Msg("Patching supplier")
+
+	sets, args, err := patchAssignments(fields, supplierPatchColumns)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("supplier_id", id).Msg("Invalid supplier patch")
+		return nil, err
+	}
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := scanSupplier(tx.QueryRow(ctx, constant.QGetSupplierByIDForUpdate, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("supplier_id", id).Msg("Failed to lock supplier for patch")
+		return nil, notFoundError(err, ErrSupplierNotFound)
+	}
+
+	if !VersionMatches(before.Version, version) {
+		logger.Ctx(ctx).Error().Int64("supplier_id", id).Int64("version", version).Msg("Supplier version conflict")
+		return nil, ErrVersionConflict
+	}
+
+	query := fmt.Sprintf(constant.QPatchSupplier, sets, len(args)+1, len(args)+2, len(args)+3)
+	supplier, err := scanSupplier(tx.QueryRow(ctx, query, append(args, time.Now(), id, before.Version)...))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("supplier_id", id).Msg("Failed to patch supplier")
+		return nil, translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUpdate, "supplier", strconv.FormatInt(id, 10), before, supplier); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Int64("supplier_id", id).Msg("Supplier patched successfully")
+	return supplier, nil
+}
+
+// Exists reports whether a supplier that has not been deleted has the given ID.
+func (r *supplierRepository) Exists(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, constant.QSupplierExists, id).Scan(&exists); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("supplier_id", id).Msg("Failed to check supplier exists")
+		return false, err
+	}
+	return exists, nil
+}
+
+// IDsByName maps the lower-cased name of every supplier that has not been
+// deleted to its IDs. Names are not unique, so a name may map to several.
+func (r *supplierRepository) IDsByName(ctx context.Context) (map[string][]int64, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetSupplierNames)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch supplier names")
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string][]int64)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan suppliers row")
+			return nil, err
+		}
+		key := strings.ToLower(strings.TrimSpace(name))
+		ids[key] = append(ids[key], id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch supplier names")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func scanSupplier(row pgx.Row) (*entity.Supplier, error) {
+	supplier := &entity.Supplier{}
+	err := row.Scan(
+		&supplier.ID,
+		&supplier.Name,
+		&supplier.ContactPerson,
+		&supplier.Phone,
+		&supplier.Address,
+		&supplier.Email,
+		&supplier.CreatedAt,
+		&supplier.UpdatedAt,
+		&supplier.DeletedAt,
+		&supplier.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return supplier, nil
 }
@@ -0,0 +1,370 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/audit"
+	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userID int64) (*entity.UserTOTP, error)
+	SaveTOTP(ctx context.Context, totp *entity.UserTOTP) error
+	ConfirmTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error
+	MarkTOTPStepUsed(ctx context.Context, userID int64, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	IsRequiredForRole(ctx context.Context, role string) (bool, error)
+	GetRolePolicies(ctx context.Context) ([]*entity.RoleTwoFactorPolicy, error)
+	SaveRolePolicy(ctx context.Context, policy *entity.RoleTwoFactorPolicy) error
+}
+
+type twoFactorRepository struct {
+	db      *pgxpool.Pool
+	secrets *utils.SecretBox
+}
+
+// NewTwoFactorRepository stores TOTP secrets sealed by secrets, so a copy of
+// the database alone cannot generate codes.
+func NewTwoFactorRepository(db *pgxpool.Pool, secrets *utils.SecretBox) TwoFactorRepository {
+	return &twoFactorRepository{db: db, secrets: secrets}
+}
+
+// totpSecretContext binds a sealed secret to its user, so it cannot be copied
+// onto another account.
+func totpSecretContext(userID int64) string {
+	return "user_totp:" + strconv.FormatInt(userID, 10)
+}
+
+func (r *twoFactorRepository) GetTOTP(ctx context.Context, userID int64) (*entity.UserTOTP, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	totp := &entity.UserTOTP{}
+	var sealed string
+	err = tx.QueryRow(ctx, constant.QGetUserTOTP, userID).Scan(
+		&totp.UserID,
+		&sealed,
+		&totp.ConfirmedAt,
+		&totp.LastUsedStep,
+		&totp.CreatedAt,
+		&totp.UpdatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to fetch totp")
+		return nil, err
+	}
+
+	totp.Secret, err = r.secrets.Open(sealed, totpSecretContext(userID))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to decrypt totp secret")
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return totp, nil
+}
+
+func (r *twoFactorRepository) SaveTOTP(ctx context.Context, totp *entity.UserTOTP) error {
+	logger.Ctx(ctx).Info().Int64("user_id", totp.UserID).Msg("Saving pending totp enrollment")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	sealed, err := r.secrets.Seal(totp.Secret, totpSecretContext(totp.UserID))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", totp.UserID).Msg("Failed to encrypt totp secret")
+		return err
+	}
+
+	_, err = tx.Exec(ctx, constant.QSaveUserTOTP, totp.UserID, sealed, time.Now())
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", totp.UserID).Msg("Failed to save totp")
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) ConfirmTOTP(ctx context.Context, userID int64, step int64, recoveryCodeHashes []string) error {
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Confirming totp enrollment")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	tag, err := tx.Exec(ctx, constant.QConfirmUserTOTP, now, step, userID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to confirm totp")
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoPendingEnrollment
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes, now); err != nil {
+		return err
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "two_factor", strconv.FormatInt(userID, 10), nil, map[string]any{"enabled": true}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Totp enrollment confirmed successfully")
+	return nil
+}
+
+func (r *twoFactorRepository) MarkTOTPStepUsed(ctx context.Context, userID int64, step int64) (bool, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, constant.QMarkTOTPStepUsed, step, time.Now(), userID)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to mark totp step used")
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *twoFactorRepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Disabling totp")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, constant.QDeleteUserTOTP, userID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to delete totp")
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, constant.QDeleteRecoveryCodes, userID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to delete recovery codes")
+		return err
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionDelete, "two_factor", strconv.FormatInt(userID, 10), map[string]any{"enabled": true}, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes, time.Now()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string, now time.Time) error {
+	if _, err := tx.Exec(ctx, constant.QDeleteRecoveryCodes, userID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to delete recovery codes")
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, constant.QCreateRecoveryCode, userID, hash, now); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to create recovery code")
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, constant.QUseRecoveryCode, time.Now(), userID, codeHash)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to use recovery code")
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *twoFactorRepository) IsRequiredForRole(ctx context.Context, role string) (bool, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	policy := &entity.RoleTwoFactorPolicy{}
+	err = tx.QueryRow(ctx, constant.QGetRoleTwoFactorPolicy, role).Scan(&policy.Role, &policy.Required, &policy.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("role", role).Msg("Failed to fetch role two-factor policy")
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return false, err
+	}
+
+	return policy.Required, nil
+}
+
+func (r *twoFactorRepository) GetRolePolicies(ctx context.Context) ([]*entity.RoleTwoFactorPolicy, error) {
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, constant.QGetAllRoleTwoFactorPolicies)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch role two-factor policies")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []*entity.RoleTwoFactorPolicy
+	for rows.Next() {
+		policy := &entity.RoleTwoFactorPolicy{}
+		if err := rows.Scan(&policy.Role, &policy.Required, &policy.UpdatedAt); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan role two-factor policy row")
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (r *twoFactorRepository) SaveRolePolicy(ctx context.Context, policy *entity.RoleTwoFactorPolicy) error {
+	logger.Ctx(ctx).Info().Str("role", policy.Role).Bool("required", policy.Required).Msg("Saving role two-factor policy")
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var before *entity.RoleTwoFactorPolicy
+	existing := &entity.RoleTwoFactorPolicy{}
+	err = tx.QueryRow(ctx, constant.QGetRoleTwoFactorPolicyForUpdate, policy.Role).Scan(&existing.Role, &existing.Required, &existing.UpdatedAt)
+	if err == nil {
+		before = existing
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		logger.Ctx(ctx).Error().Err(err).Str("role", policy.Role).Msg("Failed to lock role two-factor policy")
+		return err
+	}
+
+	policy.UpdatedAt = time.Now()
+	if _, err := tx.Exec(ctx, constant.QSaveRoleTwoFactorPolicy, policy.Role, policy.Required, policy.UpdatedAt); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("role", policy.Role).Msg("Failed to save role two-factor policy")
+		return err
+	}
+
+	action := audit.ActionUpdate
+	if before == nil {
+		action = audit.ActionCreate
+	}
+	if err := writeAuditLog(ctx, tx, action, "role_two_factor_policy", policy.Role, before, policy); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+
+	return nil
+}
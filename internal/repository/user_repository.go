@@ -2,82 +2,85 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"pharmly-backend/internal/audit"
 	"pharmly-backend/internal/constant"
+	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
-	GetAll(ctx context.Context, page, pageSize int) ([]*entity.User, int64, error)
+	GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.User], error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	GetByID(ctx context.Context, id int64) (*entity.User, error)
+	Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.User, error)
 }
 
 type userRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewUserRepository(db *pgx.Conn) UserRepository {
+func NewUserRepository(db *pgxpool.Pool) UserRepository {
 	return &userRepository{db: db}
 }
 
 func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
-	logger.Info().Str("email", user.Email).Msg("Creating new user")
+	logger.Ctx(ctx).Info().Str("email", user.Email).Msg("Creating new user")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	err = tx.QueryRow(ctx, constant.QCreateUser, user.Username, user.FullName, user.Email, user.Password, user.Role, time.Now(), time.Now()).Scan(&user.ID)
+	err = tx.QueryRow(ctx, constant.QCreateUser, user.Username, user.FullName, user.Email, user.Password, user.Role, time.Now(), time.Now()).Scan(&user.ID, &user.Version)
 	if err != nil {
-		if err.Error() == "ERROR: duplicate key value violates unique constraint \"users_email_key\" (SQLSTATE 23505)" {
-			return errors.New("email already exists")
-		}
-		if err.Error() == "ERROR: duplicate key value violates unique constraint \"users_username_key\" (SQLSTATE 23505)" {
-			return errors.New("username already exists")
-		}
+		return translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionCreate, "user", strconv.FormatInt(user.ID, 10), nil, user); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return err
 	}
 
-	logger.Info().Str("email", user.Email).Int64("user_id", user.ID).Msg("User created successfully")
+	logger.Ctx(ctx).Info().Str("email", user.Email).Int64("user_id", user.ID).Msg("User created successfully")
 	return nil
 }
 
-func (r *userRepository) GetAll(ctx context.Context, page, pageSize int) ([]*entity.User, int64, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated users")
+func (r *userRepository) GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.User], error) {
+	logger.Ctx(ctx).Info().Int("limit", q.Limit).Int("offset", q.Offset).Msg("Fetching paginated users")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	var total int64
-	err = tx.QueryRow(ctx, constant.QCountUserQuery).Scan(&total)
+	total, err := countPage(ctx, tx, constant.QCountUserQuery, "", nil, q)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to get total users count")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to get total users count")
+		return nil, err
 	}
 
-	offset := (page - 1) * pageSize
-	rows, err := tx.Query(ctx, constant.QGetAllUsers, pageSize, offset)
+	query, args := pageSQL(constant.QGetAllUsers, "", nil, q)
+	rows, err := tx.Query(ctx, query, args...)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch users")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch users")
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -95,29 +98,31 @@ func (r *userRepository) GetAll(ctx context.Context, page, pageSize int) ([]*ent
 			&user.CreatedAt,
 			&user.UpdatedAt,
 			&user.DeletedAt,
+			&user.Version,
 		)
 		if err != nil {
-			logger.Error().Err(err).Msg("Failed to scan users row")
-			return nil, 0, err
+			logger.Ctx(ctx).Error().Err(err).Msg("Failed to scan users row")
+			return nil, err
 		}
 		users = append(users, user)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
-		return nil, 0, err
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
 	}
 
-	logger.Info().Int("count", len(users)).Int64("total", total).Msg("Users fetch successfully")
-	return users, total, nil
+	page := newPage(users, total, q)
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Users fetch successfully")
+	return page, nil
 }
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
-	logger.Info().Str("email", email).Msg("Fetching user by email")
+	logger.Ctx(ctx).Info().Str("email", email).Msg("Fetching user by email")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
@@ -134,33 +139,34 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&user.Version,
 	)
 
 	if err == pgx.ErrNoRows {
-		logger.Error().Str("email", email).Msg("User not found")
+		logger.Ctx(ctx).Error().Str("email", email).Msg("User not found")
 		return nil, nil
 	}
 
 	if err != nil {
-		logger.Error().Err(err).Str("email", email).Msg("Failed to fetch user")
+		logger.Ctx(ctx).Error().Err(err).Str("email", email).Msg("Failed to fetch user")
 		return nil, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return nil, err
 	}
 
-	logger.Info().Str("email", email).Int64("user_id", user.ID).Msg("User fetched successfully")
+	logger.Ctx(ctx).Info().Str("email", email).Int64("user_id", user.ID).Msg("User fetched successfully")
 	return user, nil
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id int64) (*entity.User, error) {
-	logger.Info().Int64("user_id", id).Msg("Fetching user by ID")
+	logger.Ctx(ctx).Info().Int64("user_id", id).Msg("Fetching user by ID")
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	tx, err := database.BeginTx(ctx, r.db)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to begin transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
@@ -172,29 +178,106 @@ func (r *userRepository) GetByID(ctx context.Context, id int64) (*entity.User, e
 		&user.FullName,
 		&user.Email,
 		&user.Password,
-		&user.Password,
 		&user.Role,
 		&user.Status,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
+		&user.Version,
 	)
 
 	if err == pgx.ErrNoRows {
-		logger.Error().Int64("user_id", id).Msg("User not found")
+		logger.Ctx(ctx).Error().Int64("user_id", id).Msg("User not found")
 		return nil, nil
 	}
 
 	if err != nil {
-		logger.Error().Err(err).Int64("user_id", id).Msg("Failed to fetch user")
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", id).Msg("Failed to fetch user")
 		return nil, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		logger.Error().Err(err).Msg("Failed to commit transaction")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
 		return nil, err
 	}
 
-	logger.Info().Int64("user_id", id).Msg("User fetched successfully")
+	logger.Ctx(ctx).Info().Int64("user_id", id).Msg("User fetched successfully")
+	return user, nil
+}
+
+var userPatchColumns = map[string]bool{
+	"username":  true,
+	"full_name": true,
+	"email":     true,
+	"password":  true,
+	"role":      true,
+	"status":    true,
+}
+
+func (r *userRepository) Patch(ctx context.Context, id int64, version int64, fields []FieldUpdate) (*entity.User, error) {
+	logger.Ctx(ctx).Info().Int64("user_id", id).Int("fields", len(fields)).Msg("Patching user")
+
+	sets, args, err := patchAssignments(fields, userPatchColumns)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", id).Msg("Invalid user patch")
+		return nil, err
+	}
+
+	tx, err := database.BeginTx(ctx, r.db)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	before, err := scanUser(tx.QueryRow(ctx, constant.QGetUserByIDForUpdate, id))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", id).Msg("Failed to lock user for patch")
+		return nil, notFoundError(err, ErrUserNotFound)
+	}
+
+	if !VersionMatches(before.Version, version) {
+		logger.Ctx(ctx).Error().Int64("user_id", id).Int64("version", version).Msg("User version conflict")
+		return nil, ErrVersionConflict
+	}
+
+	query := fmt.Sprintf(constant.QPatchUser, sets, len(args)+1, len(args)+2, len(args)+3)
+	user, err := scanUser(tx.QueryRow(ctx, query, append(args, time.Now(), id, before.Version)...))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", id).Msg("Failed to patch user")
+		return nil, translateError(err)
+	}
+
+	if err := writeAuditLog(ctx, tx, audit.ActionUpdate, "user", strconv.FormatInt(id, 10), before, user); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Int64("user_id", id).Msg("User patched successfully")
+	return user, nil
+}
+
+func scanUser(row pgx.Row) (*entity.User, error) {
+	user := &entity.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.FullName,
+		&user.Email,
+		&user.Password,
+		&user.Role,
+		&user.Status,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
+		&user.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
 	return user, nil
 }
@@ -0,0 +1,11 @@
+package repository
+
+// AnyVersion is the expected version of a client that sent "If-Match: *",
+// which matches whatever version the record currently has.
+const AnyVersion int64 = 0
+
+// VersionMatches reports whether a record at version current may be changed
+// by a client expecting version expected.
+func VersionMatches(current, expected int64) bool {
+	return expected == AnyVersion || current == expected
+}
@@ -0,0 +1,194 @@
+// Package spreadsheet reads and writes tabular files as CSV or XLSX.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"pharmly-backend/internal/apperror"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var (
+	ErrUnsupportedFormat = apperror.Validation("unsupported_file_format", "File must be a .csv or .xlsx file")
+	ErrUnreadableFile    = apperror.Validation("unreadable_file", "File could not be read")
+	ErrNoHeader          = apperror.Validation("missing_header_row", "File has no header row")
+
+	errInvalidDate = errors.New("invalid date")
+)
+
+// Table is the content of a file. Header holds the column names from the
+// first non-blank row.
+type Table struct {
+	Header []string
+	Rows   []Row
+}
+
+// Row is a data row. Number is its line in a CSV file or its row in the
+// sheet, so errors can point users at the right place.
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+// Cell returns the trimmed value in column i, or "" when the row is shorter.
+func (r Row) Cell(i int) string {
+	if i < 0 || i >= len(r.Cells) {
+		return ""
+	}
+	return strings.TrimSpace(r.Cells[i])
+}
+
+// FormatOf returns the format of a file from its extension.
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Read reads a CSV file or the first sheet of an XLSX workbook. Blank rows
+// are skipped.
+func Read(r io.Reader, format string) (*Table, error) {
+	var table *Table
+	var err error
+	switch format {
+	case FormatCSV:
+		table, err = readCSV(r)
+	case FormatXLSX:
+		table, err = readXLSX(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, ErrUnreadableFile.Wrap(err)
+	}
+
+	if len(table.Header) == 0 {
+		return nil, ErrNoHeader
+	}
+	return table, nil
+}
+
+func readCSV(r io.Reader) (*Table, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = csvDelimiter(data)
+	reader.FieldsPerRecord = -1
+
+	table := &Table{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		table.add(line, record)
+	}
+}
+
+// csvDelimiter picks a semicolon for files whose header uses it, as
+// spreadsheet programs do in locales with a decimal comma.
+func csvDelimiter(data []byte) rune {
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func readXLSX(r io.Reader) (*Table, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return &Table{}, nil
+	}
+
+	rows, err := file.Rows(sheets[0])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	table := &Table{}
+	for number := 1; rows.Next(); number++ {
+		// Raw values keep dates as serial numbers instead of applying the
+		// cell's display format, which varies between workbooks.
+		cells, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
+		table.add(number, cells)
+	}
+	return table, rows.Error()
+}
+
+func (t *Table) add(number int, cells []string) {
+	blank := true
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			blank = false
+			break
+		}
+	}
+	if blank {
+		return
+	}
+
+	if t.Header == nil {
+		t.Header = cells
+		return
+	}
+	t.Rows = append(t.Rows, Row{Number: number, Cells: cells})
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"02/01/2006",
+	"2/1/2006",
+	"02-01-2006",
+}
+
+// ParseDate reads a date written as YYYY-MM-DD, DD/MM/YYYY, RFC 3339 or an
+// Excel serial date number.
+func ParseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		return excelize.ExcelDateToTime(serial, false)
+	}
+	return time.Time{}, errInvalidDate
+}
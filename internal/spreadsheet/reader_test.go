@@ -0,0 +1,135 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"pharmly-backend/internal/apperror"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2027-12-31", want: time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "2027-12-31T08:30:00Z", want: time.Date(2027, 12, 31, 8, 30, 0, 0, time.UTC)},
+		{value: "31/12/2027", want: time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "5/3/2027", want: time.Date(2027, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{value: "31-12-2027", want: time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "45292", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "12/31/2027", wantErr: true},
+		{value: "next year", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "-3", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDate(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDate(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDate(%q) error = %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseDate(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want rune
+	}{
+		{"comma header", "name,price,unit\nParacetamol;Extra,12,box\n", ','},
+		{"semicolon header", "name;price;unit\nParacetamol;12,50;box\n", ';'},
+		{"decimal commas in rows do not count", "name;price\n\"a\";1,5\n\"b\";2,5\n", ';'},
+		{"single column", "name\nParacetamol\n", ','},
+		{"empty file", "", ','},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := csvDelimiter([]byte(tt.data)); got != tt.want {
+				t.Errorf("csvDelimiter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	data := "\ufeffname;price\n\n  Paracetamol ;12,50\n;\nIbuprofen\n"
+
+	table, err := Read(strings.NewReader(data), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"name", "price"}; !reflect.DeepEqual(table.Header, want) {
+		t.Errorf("Header = %q, want %q", table.Header, want)
+	}
+	want := []Row{
+		{Number: 3, Cells: []string{"  Paracetamol ", "12,50"}},
+		{Number: 5, Cells: []string{"Ibuprofen"}},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("Rows = %+v, want %+v", table.Rows, want)
+	}
+	if got := table.Rows[0].Cell(0); got != "Paracetamol" {
+		t.Errorf("Cell(0) = %q, want trimmed value", got)
+	}
+	if got := table.Rows[1].Cell(1); got != "" {
+		t.Errorf("Cell past the end = %q, want empty", got)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		format   string
+		wantCode string
+	}{
+		{"blank file", "\n\n", FormatCSV, ErrNoHeader.Code},
+		{"broken quotes", "name\n\"Paracetamol\n", FormatCSV, ErrUnreadableFile.Code},
+		{"not a workbook", "name\n", FormatXLSX, ErrUnreadableFile.Code},
+		{"unknown format", "name\n", "ods", ErrUnsupportedFormat.Code},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewBufferString(tt.data), tt.format)
+			if !hasCode(err, tt.wantCode) {
+				t.Errorf("Read() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]string{"stock.csv": FormatCSV, "Stock.XLSX": FormatXLSX} {
+		if got, err := FormatOf(name); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := FormatOf("stock.xls"); !hasCode(err, ErrUnsupportedFormat.Code) {
+		t.Errorf("FormatOf(stock.xls) error = %v, want %s", err, ErrUnsupportedFormat.Code)
+	}
+}
+
+func hasCode(err error, code string) bool {
+	appErr, ok := apperror.As(err)
+	return ok && appErr.Code == code
+}
@@ -0,0 +1,200 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	MIMECSV  = "text/csv"
+	MIMEXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+const xlsxSheet = "Sheet1"
+
+var ErrUnsupportedCell = errors.New("unsupported cell type")
+
+var contentTypes = map[string]string{
+	FormatCSV:  MIMECSV + "; charset=utf-8",
+	FormatXLSX: MIMEXLSX,
+}
+
+// ContentType returns the MIME type of files in format.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Date is a cell holding a calendar date without a time of day.
+type Date time.Time
+
+// Writer writes a table one row at a time. Cells may be strings, integers,
+// booleans, decimals, times, dates or pointers to them; nil is written as an
+// empty cell and any other type fails with ErrUnsupportedCell. The file is
+// complete only after Close.
+type Writer interface {
+	Write(cells ...any) error
+	Close() error
+}
+
+// NewWriter returns a Writer producing format on w. CSV rows are written as
+// they come; XLSX rows are buffered by the workbook in a temporary file once
+// they outgrow memory and copied to w on Close.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{out: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		file := excelize.NewFile()
+		stream, err := file.NewStreamWriter(xlsxSheet)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &xlsxWriter{out: w, file: file, stream: stream}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvWriter struct {
+	out *csv.Writer
+}
+
+func (w *csvWriter) Write(cells ...any) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		value, err := cellValue(cell)
+		if err != nil {
+			return err
+		}
+		record[i] = csvCell(value)
+	}
+	return w.out.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.out.Flush()
+	return w.out.Error()
+}
+
+// csvCell formats a value returned by cellValue for CSV. Text starting with
+// a formula character is prefixed with a quote so spreadsheet programs do
+// not evaluate it.
+func csvCell(value any) string {
+	switch value := value.(type) {
+	case string:
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			return "'" + value
+		}
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case bool:
+		return strconv.FormatBool(value)
+	case decimal.Decimal:
+		return value.String()
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	case Date:
+		return time.Time(value).Format(time.DateOnly)
+	}
+	return ""
+}
+
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	rows   int
+	// dateStyle is created on the first date written.
+	dateStyle int
+}
+
+func (w *xlsxWriter) Write(cells ...any) error {
+	values := make([]any, len(cells))
+	for i, cell := range cells {
+		value, err := cellValue(cell)
+		if err != nil {
+			return err
+		}
+		switch typed := value.(type) {
+		case decimal.Decimal:
+			value = typed.InexactFloat64()
+		case Date:
+			style, err := w.dateStyleID()
+			if err != nil {
+				return err
+			}
+			value = excelize.Cell{StyleID: style, Value: time.Time(typed)}
+		}
+		values[i] = value
+	}
+
+	w.rows++
+	cell, err := excelize.CoordinatesToCellName(1, w.rows)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) dateStyleID() (int, error) {
+	if w.dateStyle == 0 {
+		style, err := w.file.NewStyle(&excelize.Style{NumFmt: 14})
+		if err != nil {
+			return 0, err
+		}
+		w.dateStyle = style
+	}
+	return w.dateStyle, nil
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := w.file.WriteTo(w.out)
+	return err
+}
+
+// cellValue dereferences pointers, returning nil for nil pointers, and
+// rejects types a cell cannot hold.
+func cellValue(cell any) (any, error) {
+	switch cell := cell.(type) {
+	case nil, string, int, int64, bool, decimal.Decimal, time.Time, Date:
+		return cell, nil
+	case *string:
+		return deref(cell), nil
+	case *int:
+		return deref(cell), nil
+	case *int64:
+		return deref(cell), nil
+	case *bool:
+		return deref(cell), nil
+	case *decimal.Decimal:
+		return deref(cell), nil
+	case *time.Time:
+		return deref(cell), nil
+	case *Date:
+		return deref(cell), nil
+	}
+	return nil, fmt.Errorf("%w %T", ErrUnsupportedCell, cell)
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
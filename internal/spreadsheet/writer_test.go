@@ -0,0 +1,107 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCSVWriter(t *testing.T) {
+	name := "Paracetamol"
+	stock, active := 40, false
+	var missing *string
+	var noStock *int
+	var noFlag *bool
+	when := time.Date(2027, 12, 31, 8, 30, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{"id", "name", "price", "active", "expires", "updated_at", "note"},
+		{int64(7), &name, decimal.RequireFromString("12.50"), true, Date(time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC)), when, missing},
+		{1, "=HYPERLINK(\"x\")", "+1", "-2", "@SUM(A1)", "plain, with comma", nil},
+		{&stock, &active, noStock, noFlag},
+	}
+	for _, row := range rows {
+		if err := w.Write(row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,name,price,active,expires,updated_at,note\n" +
+		"7,Paracetamol,12.5,true,2027-12-31,2027-12-31T01:30:00Z,\n" +
+		"1,\"'=HYPERLINK(\"\"x\"\")\",'+1,'-2,'@SUM(A1),\"plain, with comma\",\n" +
+		"40,false,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXLSXWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, FormatXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write("id", "name", "price", "expires"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(int64(7), "Paracetamol", decimal.RequireFromString("12.5"), Date(time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := Read(&buf, FormatXLSX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id", "name", "price", "expires"}; !reflect.DeepEqual(table.Header, want) {
+		t.Errorf("Header = %q, want %q", table.Header, want)
+	}
+	if len(table.Rows) != 1 {
+		t.Fatalf("read %d rows, want 1", len(table.Rows))
+	}
+
+	row := table.Rows[0]
+	if row.Cell(0) != "7" || row.Cell(1) != "Paracetamol" || row.Cell(2) != "12.5" {
+		t.Errorf("row = %q, want 7, Paracetamol, 12.5", row.Cells)
+	}
+	expires, err := ParseDate(row.Cell(3))
+	if err != nil || !expires.Equal(time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expires = %q (%v, %v), want 2027-12-31", row.Cell(3), expires, err)
+	}
+}
+
+func TestNewWriterUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, "ods"); !hasCode(err, ErrUnsupportedFormat.Code) {
+		t.Errorf("NewWriter() error = %v, want %s", err, ErrUnsupportedFormat.Code)
+	}
+}
+
+func TestWriterRejectsUnsupportedCells(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatXLSX} {
+		for _, cell := range []any{3.5, []string{"a"}, struct{}{}} {
+			w, err := NewWriter(&bytes.Buffer{}, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.Write("ok", cell); !errors.Is(err, ErrUnsupportedCell) {
+				t.Errorf("%s Write(%T) error = %v, want %v", format, cell, err, ErrUnsupportedCell)
+			}
+			w.Close()
+		}
+	}
+}
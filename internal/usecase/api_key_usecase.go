@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/netip"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
+	"pharmly-backend/internal/utils"
+	"time"
+)
+
+// apiKeyTouchInterval throttles last-used writes so a busy integration does
+// not turn every request into an UPDATE.
+const apiKeyTouchInterval = time.Minute
+
+var (
+	ErrInvalidAPIKey        = apperror.Unauthenticated("invalid_api_key", "invalid api key")
+	ErrAPIKeyIPNotAllowed   = apperror.Forbidden("api_key_ip_not_allowed", "api key is not allowed from this address")
+	ErrAPIKeyNotFound       = apperror.NotFound("api_key_not_found", "api key not found")
+	ErrAPIKeyExpiryInPast   = apperror.Validation("api_key_expiry_in_past", "api key expiry must be in the future")
+	ErrInvalidAPIKeyAddress = apperror.Validation("invalid_api_key_address", "invalid ip address or cidr in allowlist")
+)
+
+type APIKeyUsecase interface {
+	CreateAPIKey(ctx context.Context, createdBy int64, req *dto.APIKeyRequest) (*dto.APIKeyCreatedResponse, error)
+	GetAllAPIKeys(ctx context.Context) ([]*dto.APIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	Authenticate(ctx context.Context, rawKey, ip string) (*entity.APIKey, error)
+}
+
+type apiKeyUsecase struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyUsecase(repo repository.APIKeyRepository) APIKeyUsecase {
+	return &apiKeyUsecase{repo: repo}
+}
+
+func (u *apiKeyUsecase) CreateAPIKey(ctx context.Context, createdBy int64, req *dto.APIKeyRequest) (*dto.APIKeyCreatedResponse, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyUsecase.CreateAPIKey")
+	defer span.End()
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpiryInPast
+	}
+
+	for _, entry := range req.AllowedIPs {
+		if _, err := parseAllowedIP(entry); err != nil {
+			return nil, ErrInvalidAPIKeyAddress
+		}
+	}
+
+	raw, prefix, hash, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	allowedIPs := req.AllowedIPs
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+
+	key := &entity.APIKey{
+		Name:       req.Name,
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Scopes:     req.Scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedBy:  createdBy,
+	}
+
+	if err := u.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &dto.APIKeyCreatedResponse{
+		APIKeyResponse: *toAPIKeyResponse(key),
+		Key:            raw,
+	}, nil
+}
+
+func (u *apiKeyUsecase) GetAllAPIKeys(ctx context.Context) ([]*dto.APIKeyResponse, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyUsecase.GetAllAPIKeys")
+	defer span.End()
+
+	keys, err := u.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]*dto.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, toAPIKeyResponse(key))
+	}
+	return response, nil
+}
+
+func (u *apiKeyUsecase) RevokeAPIKey(ctx context.Context, id int64) error {
+	ctx, span := tracing.Start(ctx, "APIKeyUsecase.RevokeAPIKey")
+	defer span.End()
+
+	revoked, err := u.repo.Revoke(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	logger.Ctx(ctx).Info().Int64("api_key_id", id).Msg("Api key revoked successfully")
+	return nil
+}
+
+func (u *apiKeyUsecase) Authenticate(ctx context.Context, rawKey, ip string) (*entity.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyUsecase.Authenticate")
+	defer span.End()
+
+	prefix, err := utils.ParseAPIKeyPrefix(rawKey)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := u.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil || subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(utils.HashAPIKey(rawKey))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if !ipAllowed(key.AllowedIPs, ip) {
+		return nil, ErrAPIKeyIPNotAllowed
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := u.repo.Touch(ctx, key.ID, ip, now); err != nil {
+			logger.Ctx(ctx).Error().Err(err).Int64("api_key_id", key.ID).Msg("Failed to record api key usage")
+		}
+	}
+
+	return key, nil
+}
+
+func ipAllowed(allowlist []string, ip string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range allowlist {
+		prefix, err := parseAllowedIP(entry)
+		if err == nil && prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAllowedIP(entry string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+func toAPIKeyResponse(key *entity.APIKey) *dto.APIKeyResponse {
+	return &dto.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		AllowedIPs: key.AllowedIPs,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedBy:  key.CreatedBy,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
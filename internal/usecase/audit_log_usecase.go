@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/tracing"
+	"time"
+)
+
+type AuditLogUsecase interface {
+	GetAllAuditLogs(ctx context.Context, filter *dto.AuditLogFilterRequest, req *dto.PaginationRequest) ([]*dto.AuditLogResponse, *dto.PaginationResponse, error)
+	ExportAuditLogs(ctx context.Context, filter *dto.AuditLogFilterRequest, w spreadsheet.Writer) error
+}
+
+type auditLogUsecase struct {
+	repo repository.AuditLogRepository
+}
+
+func NewAuditLogUsecase(repo repository.AuditLogRepository) AuditLogUsecase {
+	return &auditLogUsecase{repo: repo}
+}
+
+func (u *auditLogUsecase) GetAllAuditLogs(ctx context.Context, filter *dto.AuditLogFilterRequest, req *dto.PaginationRequest) ([]*dto.AuditLogResponse, *dto.PaginationResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuditLogUsecase.GetAllAuditLogs")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int("page", req.Page).Int("page_size", req.PageSize).Msg("Fetching paginated audit logs")
+
+	repoFilter, err := toAuditLogFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query, err := pagination.NewQuery(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := u.repo.GetAll(ctx, repoFilter, query)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch audit logs")
+		return nil, nil, err
+	}
+
+	response := make([]*dto.AuditLogResponse, 0, len(page.Items))
+	for _, log := range page.Items {
+		response = append(response, &dto.AuditLogResponse{
+			ID:         log.ID,
+			ActorID:    log.ActorID,
+			ActorName:  log.ActorName,
+			ActorRole:  log.ActorRole,
+			Action:     log.Action,
+			EntityType: log.EntityType,
+			EntityID:   log.EntityID,
+			Before:     log.Before,
+			After:      log.After,
+			IPAddress:  log.IPAddress,
+			RequestID:  log.RequestID,
+			CreatedAt:  log.CreatedAt,
+		})
+	}
+
+	paging := pagination.Response(req, page, func(log *entity.AuditLog) int64 { return log.ID })
+
+	logger.Ctx(ctx).Info().Int("count", len(response)).Msg("Audit logs fetched successfully")
+	return response, paging, nil
+}
+
+func (u *auditLogUsecase) ExportAuditLogs(ctx context.Context, filter *dto.AuditLogFilterRequest, w spreadsheet.Writer) error {
+	ctx, span := tracing.Start(ctx, "AuditLogUsecase.ExportAuditLogs")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Msg("Exporting audit logs")
+
+	repoFilter, err := toAuditLogFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Write("id", "created_at", "actor_id", "actor_name", "actor_role", "action", "entity_type", "entity_id", "ip_address", "request_id", "before", "after"); err != nil {
+		return err
+	}
+
+	fetch := func(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.AuditLog], error) {
+		return u.repo.GetAll(ctx, repoFilter, q)
+	}
+	count, err := exportRows(ctx, fetch, func(log *entity.AuditLog) int64 { return log.ID }, func(log *entity.AuditLog) error {
+		return w.Write(
+			log.ID,
+			log.CreatedAt,
+			log.ActorID,
+			log.ActorName,
+			log.ActorRole,
+			log.Action,
+			log.EntityType,
+			log.EntityID,
+			log.IPAddress,
+			log.RequestID,
+			string(log.Before),
+			string(log.After),
+		)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int("count", count).Msg("Failed to export audit logs")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int("count", count).Msg("Audit logs exported successfully")
+	return nil
+}
+
+func toAuditLogFilter(filter *dto.AuditLogFilterRequest) (repository.AuditLogFilter, error) {
+	repoFilter := repository.AuditLogFilter{
+		ActorID:    filter.ActorID,
+		Action:     filter.Action,
+		EntityType: filter.EntityType,
+		EntityID:   filter.EntityID,
+	}
+
+	if filter.From != "" {
+		from, err := time.Parse(time.RFC3339, filter.From)
+		if err != nil {
+			return repoFilter, err
+		}
+		repoFilter.From = &from
+	}
+
+	if filter.To != "" {
+		to, err := time.Parse(time.RFC3339, filter.To)
+		if err != nil {
+			return repoFilter, err
+		}
+		repoFilter.To = &to
+	}
+
+	return repoFilter, nil
+}
@@ -2,37 +2,101 @@ package usecase
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
 	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
 	"pharmly-backend/internal/utils"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials = apperror.Unauthenticated("invalid_credentials", "invalid credentials")
+	ErrUserNotFound       = repository.ErrUserNotFound
+	ErrInvalidChallenge   = apperror.Unauthenticated("invalid_challenge", "invalid or expired two-factor challenge")
+	ErrUserExists         = apperror.Conflict("user_exists", "user already exists")
+)
+
+// AccountLockedError is returned by Login while an account or client IP is
+// temporarily locked after too many failed attempts.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again after %s", e.Until.Format(time.RFC3339))
+}
+
+// RetryAfter reports how long the client should wait before retrying.
+func (e *AccountLockedError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+// LoginPolicy controls failed-attempt tracking. Once a key reaches its
+// threshold within Window, it is locked for BaseLockout, doubling with every
+// further failure up to MaxLockout.
+type LoginPolicy struct {
+	AccountMaxAttempts int
+	IPMaxAttempts      int
+	Window             time.Duration
+	BaseLockout        time.Duration
+	MaxLockout         time.Duration
+}
+
+func (p LoginPolicy) lockoutFor(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	lockout := p.BaseLockout
+	for i := threshold; i < failures; i++ {
+		lockout *= 2
+		if lockout >= p.MaxLockout {
+			return p.MaxLockout
+		}
+	}
+	return lockout
+}
+
 type AuthUsecase interface {
 	Register(ctx context.Context, req *dto.UserRequest) (*dto.AuthResponse, error)
 	Login(ctx context.Context, req *dto.LoginRequest) (*dto.AuthResponse, error)
+	VerifyTwoFactor(ctx context.Context, req *dto.TwoFactorLoginRequest) (*dto.AuthResponse, error)
+	UnlockAccount(ctx context.Context, userID int64) error
 }
 
 type authUsecase struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	attempts  repository.LoginAttemptRepository
+	twoFactor repository.TwoFactorRepository
+	tx        database.Transactor
+	policy    LoginPolicy
 }
 
-func NewAuthUsecase(repo repository.UserRepository) AuthUsecase {
-	return &authUsecase{repo: repo}
+func NewAuthUsecase(repo repository.UserRepository, attempts repository.LoginAttemptRepository, twoFactor repository.TwoFactorRepository, tx database.Transactor, policy LoginPolicy) AuthUsecase {
+	return &authUsecase{repo: repo, attempts: attempts, twoFactor: twoFactor, tx: tx, policy: policy}
 }
 
-func (u *authUsecase) Register(ctx context.Context, req *dto.UserRequest) (*dto.AuthResponse, error) {
-	existingUser, err := u.repo.GetByEmail(ctx, req.Email)
-	if err != nil {
-		return nil, err
-	}
+// accountLockKey normalizes the email so differently cased or padded
+// spellings of one address share a failure counter.
+func accountLockKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
 
-	if existingUser != nil {
-		return nil, errors.New("user already exists")
-	}
+func ipLockKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (u *authUsecase) Register(ctx context.Context, req *dto.UserRequest) (*dto.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.Register")
+	defer span.End()
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -47,7 +111,19 @@ func (u *authUsecase) Register(ctx context.Context, req *dto.UserRequest) (*dto.
 		Role:     req.Role,
 	}
 
-	if err := u.repo.Create(ctx, user); err != nil {
+	err = u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		existingUser, err := u.repo.GetByEmail(ctx, req.Email)
+		if err != nil {
+			return err
+		}
+
+		if existingUser != nil {
+			return ErrUserExists
+		}
+
+		return u.repo.Create(ctx, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -63,17 +139,129 @@ func (u *authUsecase) Register(ctx context.Context, req *dto.UserRequest) (*dto.
 }
 
 func (u *authUsecase) Login(ctx context.Context, req *dto.LoginRequest) (*dto.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.Login")
+	defer span.End()
+
+	now := time.Now()
+
+	if err := u.checkLockout(ctx, req, now); err != nil {
+		return nil, err
+	}
+
 	user, err := u.repo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
-		return nil, errors.New("invalid credentials")
+		return nil, u.loginFailed(ctx, req, nil, "unknown_email", now)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, u.loginFailed(ctx, req, &user.ID, "invalid_password", now)
+	}
+
+	totp, err := u.twoFactor.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp != nil && totp.ConfirmedAt != nil {
+		challenge, err := utils.GenerateChallengeToken(user, utils.TokenPurposeTwoFactor)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.AuthResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	required, err := u.twoFactor.IsRequiredForRole(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	if required {
+		challenge, err := utils.GenerateChallengeToken(user, utils.TokenPurposeEnrollment)
+		if err != nil {
+			return nil, err
+		}
+		u.recordAttempt(ctx, req, &user.ID, false, "two_factor_enrollment_required")
+		return &dto.AuthResponse{TwoFactorEnrollmentRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	return u.completeLogin(ctx, req, user)
+}
+
+func (u *authUsecase) VerifyTwoFactor(ctx context.Context, req *dto.TwoFactorLoginRequest) (*dto.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.VerifyTwoFactor")
+	defer span.End()
+
+	now := time.Now()
+
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken, utils.TokenPurposeTwoFactor)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := u.repo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	loginReq := &dto.LoginRequest{Email: user.Email, IPAddress: req.IPAddress, UserAgent: req.UserAgent}
+	if err := u.checkLockout(ctx, loginReq, now); err != nil {
+		return nil, err
+	}
+
+	totp, err := u.twoFactor.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp == nil || totp.ConfirmedAt == nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	var verified bool
+	if req.Code != "" {
+		verified, err = verifyTOTPCode(ctx, u.twoFactor, totp, req.Code, now)
+	} else {
+		verified, err = u.twoFactor.UseRecoveryCode(ctx, user.ID, utils.HashRecoveryCode(req.RecoveryCode))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !verified {
+		return nil, u.loginFailed(ctx, loginReq, &user.ID, "invalid_two_factor_code", now)
+	}
+
+	return u.completeLogin(ctx, loginReq, user)
+}
+
+func (u *authUsecase) checkLockout(ctx context.Context, req *dto.LoginRequest, now time.Time) error {
+	for _, key := range []string{accountLockKey(req.Email), ipLockKey(req.IPAddress)} {
+		lockout, err := u.attempts.GetLockout(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if lockout != nil && lockout.LockedUntil != nil && lockout.LockedUntil.After(now) {
+			u.recordAttempt(ctx, req, nil, false, "locked")
+			return &AccountLockedError{Until: *lockout.LockedUntil}
+		}
+	}
+	return nil
+}
+
+// completeLogin clears the account's failure counter only once every factor
+// has been verified, so re-entering a known password cannot reset it.
+func (u *authUsecase) completeLogin(ctx context.Context, req *dto.LoginRequest, user *entity.User) (*dto.AuthResponse, error) {
+	if err := u.attempts.Reset(ctx, accountLockKey(req.Email)); err != nil {
+		return nil, err
 	}
 
 	token, err := utils.GenerateToken(user)
@@ -81,8 +269,87 @@ func (u *authUsecase) Login(ctx context.Context, req *dto.LoginRequest) (*dto.Au
 		return nil, err
 	}
 
+	u.recordAttempt(ctx, req, &user.ID, true, "")
+
 	return &dto.AuthResponse{
 		User:  (*dto.UserResponse)(user),
 		Token: token,
 	}, nil
 }
+
+func (u *authUsecase) UnlockAccount(ctx context.Context, userID int64) error {
+	ctx, span := tracing.Start(ctx, "AuthUsecase.UnlockAccount")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Unlocking user account")
+
+	user, err := u.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := u.attempts.Unlock(ctx, accountLockKey(user.Email), user.ID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to unlock user account")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("User account unlocked successfully")
+	return nil
+}
+
+type lockCheck struct {
+	key       string
+	threshold int
+}
+
+// loginFailed counts the failure against the client IP and the account,
+// locks whichever crossed its threshold and returns the error for the caller.
+// Unknown emails are counted against their account key too, so they lock and
+// respond exactly like real accounts and the lockout does not reveal which
+// emails are registered.
+func (u *authUsecase) loginFailed(ctx context.Context, req *dto.LoginRequest, userID *int64, reason string, now time.Time) error {
+	u.recordAttempt(ctx, req, userID, false, reason)
+
+	checks := []lockCheck{
+		{accountLockKey(req.Email), u.policy.AccountMaxAttempts},
+		{ipLockKey(req.IPAddress), u.policy.IPMaxAttempts},
+	}
+
+	windowStart := now.Add(-u.policy.Window)
+	for _, check := range checks {
+		failures, err := u.attempts.RecordFailure(ctx, check.key, now, windowStart)
+		if err != nil {
+			return err
+		}
+
+		if lockout := u.policy.lockoutFor(failures, check.threshold); lockout > 0 {
+			if err := u.attempts.Lock(ctx, check.key, now.Add(lockout)); err != nil {
+				return err
+			}
+			logger.Ctx(ctx).Info().Str("key", check.key).Int("failures", failures).Dur("lockout", lockout).Msg("Login locked after repeated failures")
+		}
+	}
+
+	return ErrInvalidCredentials
+}
+
+// recordAttempt writes the login audit entry. A failure to audit is logged
+// but never blocks the login itself.
+func (u *authUsecase) recordAttempt(ctx context.Context, req *dto.LoginRequest, userID *int64, success bool, reason string) {
+	attempt := &entity.LoginAttempt{
+		UserID:    userID,
+		Email:     req.Email,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+		Success:   success,
+		Reason:    reason,
+	}
+
+	if err := u.attempts.CreateAttempt(ctx, attempt); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("email", req.Email).Msg("Failed to record login attempt")
+	}
+}
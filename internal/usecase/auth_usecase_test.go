@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/repository"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakeUsers struct {
+	repository.UserRepository
+	users []*entity.User
+}
+
+func (f *fakeUsers) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+	for _, user := range f.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+var testLoginPolicy = LoginPolicy{
+	AccountMaxAttempts: 2,
+	IPMaxAttempts:      10,
+	Window:             time.Minute,
+	BaseLockout:        time.Minute,
+	MaxLockout:         time.Hour,
+}
+
+func newTestAuthUsecase(t *testing.T) (*authUsecase, repository.LoginAttemptRepository) {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := repository.NewInMemoryLoginAttemptRepository()
+	users := &fakeUsers{users: []*entity.User{{ID: 7, Email: "alice@example.com", Password: string(hash)}}}
+	return &authUsecase{repo: users, attempts: attempts, policy: testLoginPolicy}, attempts
+}
+
+func TestAccountLockKey(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"alice@example.com", "account:alice@example.com"},
+		{"Alice@Example.com", "account:alice@example.com"},
+		{"  alice@example.com ", "account:alice@example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := accountLockKey(tt.email); got != tt.want {
+			t.Errorf("accountLockKey(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	userID := int64(7)
+	tests := []struct {
+		name   string
+		email  string
+		userID *int64
+	}{
+		{"unknown email", "nobody@example.com", nil},
+		{"known user", "Alice@Example.com", &userID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, attempts := newTestAuthUsecase(t)
+			req := &dto.LoginRequest{Email: tt.email, IPAddress: "10.0.0.1"}
+			now := time.Now()
+
+			for range 2 {
+				err := u.loginFailed(context.Background(), req, tt.userID, "invalid_password", now)
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("loginFailed() error = %v, want ErrInvalidCredentials", err)
+				}
+			}
+
+			for _, key := range []string{accountLockKey(tt.email), "ip:10.0.0.1"} {
+				lockout, err := attempts.GetLockout(context.Background(), key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if lockout == nil || lockout.FailedCount != 2 {
+					t.Fatalf("%s lockout = %+v, want 2 failures", key, lockout)
+				}
+
+				wantLocked := key == accountLockKey(tt.email)
+				if locked := lockout.LockedUntil != nil; locked != wantLocked {
+					t.Errorf("%s locked = %v, want %v", key, locked, wantLocked)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginLocksUnknownEmailsLikeAccounts(t *testing.T) {
+	for _, email := range []string{"alice@example.com", "nobody@example.com"} {
+		t.Run(email, func(t *testing.T) {
+			u, _ := newTestAuthUsecase(t)
+
+			for i := range 2 {
+				req := &dto.LoginRequest{Email: email, Password: "wrong", IPAddress: fmt.Sprintf("10.0.0.%d", i)}
+				if _, err := u.Login(context.Background(), req); !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Login() error = %v, want ErrInvalidCredentials", err)
+				}
+			}
+
+			_, err := u.Login(context.Background(), &dto.LoginRequest{Email: email, Password: "wrong", IPAddress: "10.0.0.9"})
+			var locked *AccountLockedError
+			if !errors.As(err, &locked) {
+				t.Fatalf("Login() error = %v, want AccountLockedError", err)
+			}
+			if wait := locked.RetryAfter(); wait <= 0 || wait > testLoginPolicy.BaseLockout {
+				t.Errorf("RetryAfter() = %v, want up to %v", wait, testLoginPolicy.BaseLockout)
+			}
+		})
+	}
+}
+
+func TestUnlockAccount(t *testing.T) {
+	u, attempts := newTestAuthUsecase(t)
+	ctx := context.Background()
+
+	key := accountLockKey("alice@example.com")
+	if _, err := attempts.RecordFailure(ctx, key, time.Now(), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := attempts.Lock(ctx, key, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := u.UnlockAccount(ctx, 7); err != nil {
+		t.Fatalf("UnlockAccount() error = %v", err)
+	}
+	if lockout, _ := attempts.GetLockout(ctx, key); lockout != nil {
+		t.Errorf("lockout = %+v after unlock, want none", lockout)
+	}
+
+	if err := u.UnlockAccount(ctx, 8); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UnlockAccount() of unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestLoginPolicyLockoutFor(t *testing.T) {
+	policy := LoginPolicy{BaseLockout: time.Minute, MaxLockout: 10 * time.Minute}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{9, 10 * time.Minute},
+		{20, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.lockoutFor(tt.failures, 5); got != tt.want {
+			t.Errorf("lockoutFor(%d, 5) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
+)
+
+type BranchUsecase interface {
+	CreateBranch(ctx context.Context, req *dto.BranchRequest) (*dto.BranchResponse, error)
+	GetAllBranches(ctx context.Context) ([]*dto.BranchResponse, error)
+}
+
+type branchUsecase struct {
+	repo repository.BranchRepository
+}
+
+func NewBranchUsecase(repo repository.BranchRepository) BranchUsecase {
+	return &branchUsecase{repo: repo}
+}
+
+func (u *branchUsecase) CreateBranch(ctx context.Context, req *dto.BranchRequest) (*dto.BranchResponse, error) {
+	ctx, span := tracing.Start(ctx, "BranchUsecase.CreateBranch")
+	defer span.End()
+
+	branch := &entity.Branch{Name: req.Name}
+	if err := u.repo.Create(ctx, branch); err != nil {
+		return nil, err
+	}
+
+	return branchResponse(branch), nil
+}
+
+func (u *branchUsecase) GetAllBranches(ctx context.Context) ([]*dto.BranchResponse, error) {
+	ctx, span := tracing.Start(ctx, "BranchUsecase.GetAllBranches")
+	defer span.End()
+
+	branches, err := u.repo.GetAll(ctx)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch branches")
+		return nil, err
+	}
+
+	response := make([]*dto.BranchResponse, 0, len(branches))
+	for _, branch := range branches {
+		response = append(response, branchResponse(branch))
+	}
+	return response, nil
+}
+
+func branchResponse(branch *entity.Branch) *dto.BranchResponse {
+	return &dto.BranchResponse{
+		ID:        branch.ID,
+		Name:      branch.Name,
+		CreatedAt: branch.CreatedAt,
+		UpdatedAt: branch.UpdatedAt,
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
 	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/tracing"
 )
 
 type CategoryUsecase interface {
-	GetAllCategories(ctx context.Context, page, pageSize int) ([]*entity.Category, *dto.PaginationResponse, error)
+	GetAllCategories(ctx context.Context, req *dto.PaginationRequest) ([]*entity.Category, *dto.PaginationResponse, error)
+	ExportCategories(ctx context.Context, w spreadsheet.Writer) error
+	PatchCategory(ctx context.Context, id int64, version int64, req *dto.CategoryPatchRequest) (*entity.Category, error)
 }
 
 type categoryUsecase struct {
@@ -20,33 +25,75 @@ func NewCategoryUsecase(repo repository.CategoryRepository) CategoryUsecase {
 	return &categoryUsecase{repo: repo}
 }
 
-func (u *categoryUsecase) GetAllCategories(ctx context.Context, page, pageSize int) ([]*entity.Category, *dto.PaginationResponse, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated categories")
+func (u *categoryUsecase) GetAllCategories(ctx context.Context, req *dto.PaginationRequest) ([]*entity.Category, *dto.PaginationResponse, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.GetAllCategories")
+	defer span.End()
 
-	categories, total, err := u.repo.GetAll(ctx, page, pageSize)
+	logger.Ctx(ctx).Info().Int("page", req.Page).Int("page_size", req.PageSize).Msg("Fetching paginated categories")
+
+	query, err := pagination.NewQuery(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := u.repo.GetAll(ctx, query)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch categories")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch categories")
 		return nil, nil, err
 	}
 
-	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
-	hasNextPage := page < int(totalPages)
-	hasPrevPage := page > 1
-
-	nextPage := page + 1
-	prevPage := page - 1
-
-	pagination := &dto.PaginationResponse{
-		TotalItems:   total,
-		TotalPages:   int(totalPages),
-		CurrentPage:  page,
-		PageSize:     pageSize,
-		HasNextPage:  hasNextPage,
-		HasPrevPage:  hasPrevPage,
-		NextPage:     &nextPage,
-		PreviousPage: &prevPage,
+	paging := pagination.Response(req, page, func(category *entity.Category) int64 { return category.ID })
+
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Categories fetched successfully")
+	return page.Items, paging, nil
+}
+
+func (u *categoryUsecase) ExportCategories(ctx context.Context, w spreadsheet.Writer) error {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.ExportCategories")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Msg("Exporting categories")
+
+	if err := w.Write("id", "name", "description", "parent_category_id", "created_at", "updated_at"); err != nil {
+		return err
+	}
+
+	count, err := exportRows(ctx, u.repo.GetAll, func(category *entity.Category) int64 { return category.ID }, func(category *entity.Category) error {
+		return w.Write(
+			category.ID,
+			category.Name,
+			category.Description,
+			category.ParentCategoryID,
+			category.CreatedAt,
+			category.UpdatedAt,
+		)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int("count", count).Msg("Failed to export categories")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int("count", count).Msg("Categories exported successfully")
+	return nil
+}
+
+func (u *categoryUsecase) PatchCategory(ctx context.Context, id int64, version int64, req *dto.CategoryPatchRequest) (*entity.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryUsecase.PatchCategory")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("category_id", id).Msg("Starting category patch process")
+
+	var fields []repository.FieldUpdate
+	fields = patchOptional(fields, "name", req.Name)
+	fields = patchOptional(fields, "description", req.Description)
+	fields = patchNullable(fields, "parent_category_id", req.ParentCategoryID)
+
+	category, err := u.repo.Patch(ctx, id, version, fields)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("category_id", id).Msg("Failed to patch category")
+		return nil, err
 	}
 
-	logger.Info().Int("count", len(categories)).Int64("total", total).Msg("Categories fetched successfully")
-	return categories, pagination, nil
+	logger.Ctx(ctx).Info().Int64("category_id", id).Msg("Category patched successfully")
+	return category, nil
 }
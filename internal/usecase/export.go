@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/pagination"
+)
+
+// exportBatchSize is how many rows an export reads per query. Each batch is
+// a short keyset query, so a slow download holds no connection open.
+const exportBatchSize = 500
+
+// exportRows passes every row of a list to fn, newest first as in the list,
+// reading exportBatchSize rows at a time after the last row seen. It returns
+// the number of rows written.
+func exportRows[T any](ctx context.Context, fetch func(context.Context, pagination.Query) (*pagination.Page[T], error), id func(T) int64, fn func(T) error) (int, error) {
+	q := pagination.Query{Limit: exportBatchSize}
+	count := 0
+	for {
+		page, err := fetch(ctx, q)
+		if err != nil {
+			return count, err
+		}
+
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if !page.HasMore || len(page.Items) == 0 {
+			return count, nil
+		}
+		q.AfterID = id(page.Items[len(page.Items)-1])
+	}
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/pagination"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"strconv"
+	"testing"
+)
+
+// fakeProductList pages through products with IDs total down to 1 the way
+// the repository does, newest first.
+type fakeProductList struct {
+	repository.ProductRepository
+	total   int64
+	queries []pagination.Query
+}
+
+func (f *fakeProductList) GetAll(ctx context.Context, q pagination.Query) (*pagination.Page[*entity.Product], error) {
+	f.queries = append(f.queries, q)
+
+	next := f.total
+	if q.AfterID > 0 {
+		next = q.AfterID - 1
+	}
+
+	page := &pagination.Page[*entity.Product]{}
+	for id := next; id >= 1 && len(page.Items) < q.Limit; id-- {
+		page.Items = append(page.Items, &entity.Product{ID: id, Name: "Product " + strconv.FormatInt(id, 10)})
+	}
+	page.HasMore = len(page.Items) > 0 && page.Items[len(page.Items)-1].ID > 1
+	return page, nil
+}
+
+func TestExportProductsReadsEveryBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		total       int64
+		wantQueries int
+	}{
+		{"empty list", 0, 1},
+		{"less than one batch", 3, 1},
+		{"exactly one batch", exportBatchSize, 1},
+		{"several batches", 2*exportBatchSize + 7, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeProductList{total: tt.total}
+			u := NewProductusecase(repo, nil)
+
+			var buf bytes.Buffer
+			w, err := spreadsheet.NewWriter(&buf, spreadsheet.FormatCSV)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := u.ExportProducts(context.Background(), w); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != int(tt.total)+1 {
+				t.Fatalf("exported %d rows, want %d", len(records)-1, tt.total)
+			}
+			for i, record := range records[1:] {
+				if want := strconv.FormatInt(tt.total-int64(i), 10); record[0] != want {
+					t.Fatalf("row %d has id %s, want %s", i+1, record[0], want)
+				}
+			}
+
+			if len(repo.queries) != tt.wantQueries {
+				t.Errorf("made %d queries, want %d", len(repo.queries), tt.wantQueries)
+			}
+			for _, q := range repo.queries {
+				if q.Limit != exportBatchSize || q.WithTotal || q.Offset != 0 {
+					t.Errorf("query %+v, want keyset batches without totals", q)
+				}
+			}
+		})
+	}
+}
+
+func TestExportRowsStopsOnWriteError(t *testing.T) {
+	repo := &fakeProductList{total: 2 * exportBatchSize}
+	errFull := errors.New("disk full")
+
+	count, err := exportRows(context.Background(), repo.GetAll, func(p *entity.Product) int64 { return p.ID }, func(p *entity.Product) error {
+		if p.ID == 2*exportBatchSize-10 {
+			return errFull
+		}
+		return nil
+	})
+	if !errors.Is(err, errFull) || count != 10 {
+		t.Errorf("exportRows() = %d, %v, want 10, %v", count, err, errFull)
+	}
+	if len(repo.queries) != 1 {
+		t.Errorf("made %d queries, want 1", len(repo.queries))
+	}
+}
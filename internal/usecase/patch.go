@@ -0,0 +1,23 @@
+package usecase
+
+import (
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/repository"
+)
+
+// patchOptional appends a column assignment when the field was supplied.
+func patchOptional[T any](fields []repository.FieldUpdate, column string, value dto.Optional[T]) []repository.FieldUpdate {
+	if !value.Set {
+		return fields
+	}
+	return append(fields, repository.FieldUpdate{Column: column, Value: value.Value})
+}
+
+// patchNullable appends a column assignment when the field was supplied,
+// writing NULL for an explicit null.
+func patchNullable[T any](fields []repository.FieldUpdate, column string, value dto.Nullable[T]) []repository.FieldUpdate {
+	if !value.Set {
+		return fields
+	}
+	return append(fields, repository.FieldUpdate{Column: column, Value: value.Ptr()})
+}
@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/repository"
+	"reflect"
+	"testing"
+)
+
+func TestPatchFields(t *testing.T) {
+	var fields []repository.FieldUpdate
+	fields = patchOptional(fields, "name", dto.Optional[string]{})
+	fields = patchOptional(fields, "stock", dto.Optional[int]{Set: true, Value: 0})
+	fields = patchNullable(fields, "generic_name", dto.Nullable[string]{})
+	fields = patchNullable(fields, "description", dto.Nullable[string]{Set: true, Null: true})
+	fields = patchNullable(fields, "phone", dto.Nullable[string]{Set: true, Value: "0812"})
+
+	phone := "0812"
+	want := []repository.FieldUpdate{
+		{Column: "stock", Value: 0},
+		{Column: "description", Value: (*string)(nil)},
+		{Column: "phone", Value: &phone},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %+v, want %+v", fields, want)
+	}
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
+)
+
+type ProductBatchUsecase interface {
+	ReceiveBatch(ctx context.Context, receivedBy, productID int64, req *dto.ProductBatchRequest) (*dto.ProductBatchResponse, error)
+}
+
+type productBatchUsecase struct {
+	batches  repository.ProductBatchRepository
+	products repository.ProductRepository
+	tx       database.Transactor
+}
+
+func NewProductBatchUsecase(batches repository.ProductBatchRepository, products repository.ProductRepository, tx database.Transactor) ProductBatchUsecase {
+	return &productBatchUsecase{batches: batches, products: products, tx: tx}
+}
+
+// ReceiveBatch records a delivery of a product to a branch and adds it to
+// the product's stock.
+func (u *productBatchUsecase) ReceiveBatch(ctx context.Context, receivedBy, productID int64, req *dto.ProductBatchRequest) (*dto.ProductBatchResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductBatchUsecase.ReceiveBatch")
+	defer span.End()
+
+	batch := &entity.ProductBatch{
+		ProductID:         productID,
+		BranchID:          req.BranchID,
+		BatchNumber:       req.BatchNumber,
+		CostPrice:         req.CostPrice,
+		QuantityReceived:  req.Quantity,
+		QuantityRemaining: req.Quantity,
+		ExpirationDate:    req.ExpirationDate,
+		ReceivedBy:        receivedBy,
+	}
+
+	var product *entity.Product
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		product, err = u.products.AdjustStock(ctx, productID, req.Quantity)
+		if err != nil {
+			return err
+		}
+
+		if product.DeletedAt.Valid {
+			return repository.ErrProductNotFound
+		}
+
+		return u.batches.Create(ctx, batch)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", productID).Msg("Failed to receive product batch")
+		return nil, err
+	}
+
+	return &dto.ProductBatchResponse{
+		ID:                batch.ID,
+		ProductID:         batch.ProductID,
+		BranchID:          batch.BranchID,
+		BatchNumber:       batch.BatchNumber,
+		CostPrice:         batch.CostPrice,
+		QuantityReceived:  batch.QuantityReceived,
+		QuantityRemaining: batch.QuantityRemaining,
+		ExpirationDate:    batch.ExpirationDate,
+		ReceivedBy:        batch.ReceivedBy,
+		ReceivedAt:        batch.ReceivedAt,
+		ProductStock:      product.Stock,
+	}, nil
+}
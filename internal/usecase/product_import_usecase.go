@@ -0,0 +1,566 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/tracing"
+	"pharmly-backend/internal/validation"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// maxImportRows bounds a single upload so one file cannot occupy an
+	// import for hours. Larger catalogs can be split across files.
+	maxImportRows = 20000
+
+	// importProgressInterval is how many rows are processed between saves
+	// of a running job's progress.
+	importProgressInterval = 100
+
+	importInterrupted = "Import interrupted by server shutdown, rows after the last processed row were not imported"
+)
+
+var (
+	ErrImportTooLarge       = apperror.Validation("import_too_large", fmt.Sprintf("File must contain at most %d rows", maxImportRows))
+	ErrMissingImportColumns = apperror.Validation("missing_import_columns", "File is missing required columns")
+	ErrUnknownImportColumn  = apperror.Validation("unknown_import_column", "Mapping names a column that is not in the file")
+)
+
+// productImportFields lists the fields a product file can provide, in the
+// order they are reported. Category and supplier are given by name.
+var productImportFields = []string{
+	"name",
+	"category",
+	"generic_name",
+	"description",
+	"price",
+	"stock",
+	"unit",
+	"expiration_date",
+	"barcode",
+	"supplier",
+	"min_stock",
+	"is_active",
+}
+
+var requiredProductImportFields = []string{"name", "category", "price", "unit", "expiration_date", "supplier"}
+
+// importFieldOf maps product request fields to the import fields they are
+// resolved from, where the names differ.
+var importFieldOf = map[string]string{
+	"category_id": "category",
+	"supplier_id": "supplier",
+}
+
+type ProductImportUsecase interface {
+	// StartImport reads the file and records an import job. Rows are
+	// validated, and unless it is a dry run imported, in the background;
+	// the job reports their progress.
+	StartImport(ctx context.Context, createdBy int64, req *dto.ProductImportRequest, file io.Reader) (*dto.ImportJobResponse, error)
+	GetImportJob(ctx context.Context, id int64) (*dto.ImportJobResponse, error)
+	// Wait blocks until background imports finish or ctx is done. Imports
+	// stop early once the lifecycle context they were created with is
+	// cancelled.
+	Wait(ctx context.Context) error
+}
+
+type productImportUsecase struct {
+	jobs       repository.ImportJobRepository
+	products   repository.ProductRepository
+	categories repository.CategoryRepository
+	suppliers  repository.SupplierRepository
+	writer     ProductUsecase
+	lifecycle  context.Context
+	running    sync.WaitGroup
+}
+
+// NewProductImportUsecase creates and updates products through writer, so
+// imported rows are audited like changes made through the API. Cancelling
+// lifecycle, which lives as long as the application, interrupts running
+// imports and marks their jobs failed.
+func NewProductImportUsecase(lifecycle context.Context, jobs repository.ImportJobRepository, products repository.ProductRepository, categories repository.CategoryRepository, suppliers repository.SupplierRepository, writer ProductUsecase) ProductImportUsecase {
+	return &productImportUsecase{
+		lifecycle:  lifecycle,
+		jobs:       jobs,
+		products:   products,
+		categories: categories,
+		suppliers:  suppliers,
+		writer:     writer,
+	}
+}
+
+func (u *productImportUsecase) StartImport(ctx context.Context, createdBy int64, req *dto.ProductImportRequest, file io.Reader) (*dto.ImportJobResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductImportUsecase.StartImport")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Str("file", req.FileName).Bool("dry_run", req.DryRun).Msg("Starting product import")
+
+	format, err := spreadsheet.FormatOf(req.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := spreadsheet.Read(file, format)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("file", req.FileName).Msg("Failed to read import file")
+		return nil, err
+	}
+
+	if len(table.Rows) > maxImportRows {
+		return nil, ErrImportTooLarge
+	}
+
+	columns, err := productImportColumns(table.Header, req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &entity.ImportJob{
+		EntityType: "product",
+		FileName:   req.FileName,
+		Status:     entity.ImportStatusPending,
+		DryRun:     req.DryRun,
+		TotalRows:  len(table.Rows),
+		Errors:     []entity.ImportRowError{},
+		CreatedBy:  createdBy,
+	}
+	if err := u.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Dry runs check every row against the database too, so they run in the
+	// background like imports rather than within the request's deadline.
+	run := &productImport{job: job, header: table.Header, columns: columns}
+	response := toImportJobResponse(job)
+	u.running.Add(1)
+	go func() {
+		defer u.running.Done()
+
+		// The import outlives the request but not the application: it keeps
+		// the request's logger and trace, and is cancelled with lifecycle.
+		ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		stop := context.AfterFunc(u.lifecycle, cancel)
+		defer stop()
+
+		u.run(ctx, run, table.Rows)
+	}()
+
+	return response, nil
+}
+
+func (u *productImportUsecase) GetImportJob(ctx context.Context, id int64) (*dto.ImportJobResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductImportUsecase.GetImportJob")
+	defer span.End()
+
+	job, err := u.jobs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toImportJobResponse(job), nil
+}
+
+func (u *productImportUsecase) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		u.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// productImport is the state of one import while its rows are processed.
+type productImport struct {
+	job        *entity.ImportJob
+	header     []string
+	columns    map[string]int
+	categories map[string][]int64
+	suppliers  map[string][]int64
+}
+
+func (u *productImportUsecase) run(ctx context.Context, run *productImport, rows []spreadsheet.Row) {
+	ctx, span := tracing.Start(ctx, "ProductImportUsecase.run")
+	defer span.End()
+
+	job := run.job
+	job.Status = entity.ImportStatusRunning
+	u.saveJob(ctx, job)
+
+	var err error
+	run.categories, err = u.categories.IDsByName(ctx)
+	if err == nil {
+		run.suppliers, err = u.suppliers.IDsByName(ctx)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		u.finish(ctx, job, "Failed to load categories and suppliers")
+		return
+	}
+
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			u.finish(ctx, job, importInterrupted)
+			return
+		}
+
+		u.importRow(ctx, run, row)
+
+		job.ProcessedRows++
+		if job.ProcessedRows%importProgressInterval == 0 {
+			u.saveJob(ctx, job)
+		}
+	}
+
+	u.finish(ctx, job, "")
+}
+
+func (u *productImportUsecase) finish(ctx context.Context, job *entity.ImportJob, failure string) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = entity.ImportStatusCompleted
+	if failure != "" {
+		job.Status = entity.ImportStatusFailed
+		job.Failure = &failure
+	}
+	// An interrupted import still records how far it got.
+	u.saveJob(context.WithoutCancel(ctx), job)
+
+	logger.Ctx(ctx).Info().
+		Int64("import_job_id", job.ID).
+		Str("status", job.Status).
+		Int("created", job.CreatedRows).
+		Int("updated", job.UpdatedRows).
+		Int("failed", job.FailedRows).
+		Msg("Product import finished")
+}
+
+// saveJob stores the job's progress. A failure is logged rather than
+// stopping the import, as the rows already written stay written.
+func (u *productImportUsecase) saveJob(ctx context.Context, job *entity.ImportJob) {
+	if err := u.jobs.Update(ctx, job); err != nil {
+		logger.Ctx(ctx).Error().
+			Err(err).
+			Int64("import_job_id", job.ID).
+			Str("status", job.Status).
+			Int("processed", job.ProcessedRows).
+			Msg("Failed to save import job")
+	}
+}
+
+// importRow creates the product in row, or updates the product with the
+// same barcode from the cells the row fills in. Problems are recorded on the
+// job instead of stopping it.
+func (u *productImportUsecase) importRow(ctx context.Context, run *productImport, row spreadsheet.Row) {
+	job := run.job
+
+	req, rowErrors := run.parseRow(row)
+
+	var existing *entity.Product
+	if len(rowErrors) == 0 && req.Barcode != "" {
+		product, err := u.products.GetByBarcode(ctx, req.Barcode)
+		switch {
+		case err == nil:
+			existing = product
+		case !errors.Is(err, repository.ErrProductNotFound):
+			rowErrors = append(rowErrors, run.rowError(row, "barcode", importErrorMessage(err)))
+		}
+	}
+
+	var patch *dto.ProductPatchRequest
+	if len(rowErrors) == 0 {
+		if existing == nil {
+			rowErrors = run.validate(ctx, row, req, true)
+		} else {
+			patch = run.patch(row, req)
+			rowErrors = run.validate(ctx, row, patch, false)
+		}
+	}
+
+	if len(rowErrors) == 0 && !job.DryRun {
+		var err error
+		if existing == nil {
+			_, err = u.writer.CreateProduct(ctx, req)
+		} else {
+			_, err = u.writer.PatchProduct(ctx, existing.ID, existing.Version, patch)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, run.rowError(row, "", importErrorMessage(err)))
+		}
+	}
+
+	switch {
+	case len(rowErrors) > 0:
+		job.FailedRows++
+		job.Errors = append(job.Errors, rowErrors...)
+	case existing == nil:
+		job.CreatedRows++
+	default:
+		job.UpdatedRows++
+	}
+}
+
+// parseRow converts the cells of row into a product request. Cells that
+// cannot be converted are reported; the rest is left to validation.
+func (run *productImport) parseRow(row spreadsheet.Row) (*dto.ProductRequest, []entity.ImportRowError) {
+	req := &dto.ProductRequest{
+		Name:        run.cell(row, "name"),
+		GenericName: run.cell(row, "generic_name"),
+		Description: run.cell(row, "description"),
+		Unit:        run.cell(row, "unit"),
+		Barcode:     run.cell(row, "barcode"),
+		IsActive:    true,
+	}
+
+	var rowErrors []entity.ImportRowError
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, run.rowError(row, field, message))
+	}
+
+	var message string
+	if req.CategoryID, message = resolveName(run.categories, run.cell(row, "category"), "category"); message != "" {
+		fail("category", message)
+	}
+	if req.SupplierID, message = resolveName(run.suppliers, run.cell(row, "supplier"), "supplier"); message != "" {
+		fail("supplier", message)
+	}
+
+	if value := run.cell(row, "price"); value != "" {
+		price, err := decimal.NewFromString(value)
+		if err != nil {
+			fail("price", "Must be a number")
+		}
+		req.Price = price
+	}
+
+	parseInt := func(field string, target *int) {
+		if value := run.cell(row, field); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				fail(field, "Must be a whole number")
+			}
+			*target = number
+		}
+	}
+	parseInt("stock", &req.Stock)
+	parseInt("min_stock", &req.MinStock)
+
+	if value := run.cell(row, "expiration_date"); value != "" {
+		date, err := spreadsheet.ParseDate(value)
+		if err != nil {
+			fail("expiration_date", "Must be a date such as 2027-12-31")
+		}
+		req.ExpirationDate = date
+	}
+
+	if value := run.cell(row, "is_active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			fail("is_active", "Must be true or false")
+		}
+		req.IsActive = active
+	}
+
+	return req, rowErrors
+}
+
+// patch builds the update of an existing product from the mapped cells that
+// are not empty, so columns left out of the file or left blank keep their
+// current values. req holds the same cells already parsed by parseRow.
+func (run *productImport) patch(row spreadsheet.Row, req *dto.ProductRequest) *dto.ProductPatchRequest {
+	given := func(field string) bool {
+		return run.cell(row, field) != ""
+	}
+
+	return &dto.ProductPatchRequest{
+		Name:           dto.Optional[string]{Set: given("name"), Value: req.Name},
+		CategoryID:     dto.Optional[int64]{Set: given("category"), Value: req.CategoryID},
+		GenericName:    dto.Optional[string]{Set: given("generic_name"), Value: req.GenericName},
+		Description:    dto.Nullable[string]{Set: given("description"), Value: req.Description},
+		Price:          dto.Optional[decimal.Decimal]{Set: given("price"), Value: req.Price},
+		Stock:          dto.Optional[int]{Set: given("stock"), Value: req.Stock},
+		Unit:           dto.Optional[string]{Set: given("unit"), Value: req.Unit},
+		ExpirationDate: dto.Optional[time.Time]{Set: given("expiration_date"), Value: req.ExpirationDate},
+		Barcode:        dto.Optional[string]{Set: given("barcode"), Value: req.Barcode},
+		SupplierID:     dto.Optional[int64]{Set: given("supplier"), Value: req.SupplierID},
+		MinStock:       dto.Optional[int]{Set: given("min_stock"), Value: req.MinStock},
+		IsActive:       dto.Optional[bool]{Set: given("is_active"), Value: req.IsActive},
+	}
+}
+
+// validate applies the same rules as the product API to a create request or
+// an update patch. Rules for new records, such as a future expiration date,
+// only apply when the row creates one.
+func (run *productImport) validate(ctx context.Context, row spreadsheet.Row, req any, creating bool) []entity.ImportRowError {
+	validate := validation.Validate.StructCtx
+	if creating {
+		validate = validation.ValidateCreate
+	}
+
+	err := validate(ctx, req)
+	if err == nil {
+		return nil
+	}
+
+	messages := validation.GetValidationErrors(err, validation.LangEnglish)
+	if len(messages) == 0 {
+		return []entity.ImportRowError{run.rowError(row, "", "Invalid product")}
+	}
+
+	fields := make([]string, 0, len(messages))
+	for field := range messages {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	rowErrors := make([]entity.ImportRowError, 0, len(fields))
+	for _, field := range fields {
+		importField, ok := importFieldOf[field]
+		if !ok {
+			importField = field
+		}
+		rowErrors = append(rowErrors, run.rowError(row, importField, messages[field]))
+	}
+	return rowErrors
+}
+
+func (run *productImport) cell(row spreadsheet.Row, field string) string {
+	i, ok := run.columns[field]
+	if !ok {
+		return ""
+	}
+	return row.Cell(i)
+}
+
+// rowError reports a problem in row under the file's own name for the
+// column holding field.
+func (run *productImport) rowError(row spreadsheet.Row, field, message string) entity.ImportRowError {
+	column := field
+	if i, ok := run.columns[field]; ok {
+		column = strings.TrimSpace(run.header[i])
+	}
+	return entity.ImportRowError{Row: row.Number, Column: column, Message: message}
+}
+
+// productImportColumns finds the column index of each import field, using
+// mapping where given and the field's own name otherwise. Header names are
+// matched ignoring case, spaces and hyphens.
+func productImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		key := normalizeColumn(name)
+		if _, seen := index[key]; !seen {
+			index[key] = i
+		}
+	}
+
+	columns := make(map[string]int, len(productImportFields))
+	for _, field := range productImportFields {
+		source, mapped := mapping[field]
+		if !mapped {
+			source = field
+		}
+
+		i, ok := index[normalizeColumn(source)]
+		if !ok {
+			if mapped {
+				return nil, apperror.Validation(ErrUnknownImportColumn.Code, fmt.Sprintf("Mapping for %s names column %q, which is not in the file", field, source))
+			}
+			continue
+		}
+		columns[field] = i
+	}
+
+	var missing []string
+	for _, field := range requiredProductImportFields {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, apperror.Validation(ErrMissingImportColumns.Code, "File is missing required columns: "+strings.Join(missing, ", "))
+	}
+
+	return columns, nil
+}
+
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// resolveName returns the ID of the record called name, or a message when no
+// record or more than one has that name. An empty name resolves to 0 so the
+// required rule reports it.
+func resolveName(ids map[string][]int64, name, kind string) (int64, string) {
+	if name == "" {
+		return 0, ""
+	}
+
+	matches := ids[strings.ToLower(name)]
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Sprintf("No %s named %q", kind, name)
+	case 1:
+		return matches[0], ""
+	}
+	return 0, fmt.Sprintf("More than one %s is named %q", kind, name)
+}
+
+// importErrorMessage returns a message for err that is safe to show to the
+// user who uploaded the file.
+func importErrorMessage(err error) string {
+	if appErr, ok := apperror.As(err); ok && appErr.Kind != apperror.KindInternal {
+		return appErr.Message
+	}
+	return "Failed to save product"
+}
+
+func toImportJobResponse(job *entity.ImportJob) *dto.ImportJobResponse {
+	rowErrors := make([]dto.ImportRowError, 0, len(job.Errors))
+	for _, rowError := range job.Errors {
+		rowErrors = append(rowErrors, dto.ImportRowError{
+			Row:     rowError.Row,
+			Column:  rowError.Column,
+			Message: rowError.Message,
+		})
+	}
+
+	return &dto.ImportJobResponse{
+		ID:            job.ID,
+		EntityType:    job.EntityType,
+		FileName:      job.FileName,
+		Status:        job.Status,
+		DryRun:        job.DryRun,
+		TotalRows:     job.TotalRows,
+		ProcessedRows: job.ProcessedRows,
+		CreatedRows:   job.CreatedRows,
+		UpdatedRows:   job.UpdatedRows,
+		FailedRows:    job.FailedRows,
+		Errors:        rowErrors,
+		Failure:       job.Failure,
+		CreatedBy:     job.CreatedBy,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+		FinishedAt:    job.FinishedAt,
+	}
+}
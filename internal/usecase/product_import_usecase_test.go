@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/validation"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	exists := func(ctx context.Context, id int64) (bool, error) { return true, nil }
+	validation.RegisterExists("category_exists", exists)
+	validation.RegisterExists("supplier_exists", exists)
+}
+
+const importTestBarcode = "4006381333931"
+
+var importTestHeader = []string{"Name", "Category", "Generic Name", "Description", "Price", "Stock", "Unit", "Expiration Date", "Barcode", "Supplier", "Min Stock", "Is Active"}
+
+type fakeImportProducts struct {
+	repository.ProductRepository
+	byBarcode map[string]*entity.Product
+}
+
+func (f *fakeImportProducts) GetByBarcode(ctx context.Context, barcode string) (*entity.Product, error) {
+	if product, ok := f.byBarcode[barcode]; ok {
+		return product, nil
+	}
+	return nil, repository.ErrProductNotFound
+}
+
+type fakeProductWriter struct {
+	ProductUsecase
+	created []*dto.ProductRequest
+	patched []*dto.ProductPatchRequest
+}
+
+func (f *fakeProductWriter) CreateProduct(ctx context.Context, req *dto.ProductRequest) (*dto.ProductResponse, error) {
+	f.created = append(f.created, req)
+	return &dto.ProductResponse{}, nil
+}
+
+func (f *fakeProductWriter) PatchProduct(ctx context.Context, id int64, version int64, req *dto.ProductPatchRequest) (*dto.ProductResponse, error) {
+	f.patched = append(f.patched, req)
+	return &dto.ProductResponse{}, nil
+}
+
+func newTestImport(t *testing.T, dryRun bool) *productImport {
+	t.Helper()
+
+	columns, err := productImportColumns(importTestHeader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &productImport{
+		job:        &entity.ImportJob{DryRun: dryRun},
+		header:     importTestHeader,
+		columns:    columns,
+		categories: map[string][]int64{"analgesics": {1}, "vitamins": {2, 3}},
+		suppliers:  map[string][]int64{"kimia farma": {7}},
+	}
+}
+
+func TestProductImportColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []string
+		mapping  map[string]string
+		want     map[string]int
+		wantCode string
+	}{
+		{
+			name:   "header names ignore case, spaces and hyphens",
+			header: []string{"NAME", "category", "Price", "Unit", "expiration-date", "Supplier", "notes"},
+			want:   map[string]int{"name": 0, "category": 1, "price": 2, "unit": 3, "expiration_date": 4, "supplier": 5},
+		},
+		{
+			name:    "mapping renames columns",
+			header:  []string{"Nama Produk", "Kategori", "Harga", "Satuan", "Kedaluwarsa", "Pemasok", "Kode"},
+			mapping: map[string]string{"name": "Nama Produk", "category": "kategori", "price": "Harga", "unit": "Satuan", "expiration_date": "Kedaluwarsa", "supplier": "Pemasok", "barcode": "Kode"},
+			want:    map[string]int{"name": 0, "category": 1, "price": 2, "unit": 3, "expiration_date": 4, "supplier": 5, "barcode": 6},
+		},
+		{
+			name:     "mapping names a missing column",
+			header:   importTestHeader,
+			mapping:  map[string]string{"barcode": "EAN"},
+			wantCode: ErrUnknownImportColumn.Code,
+		},
+		{
+			name:     "required column missing",
+			header:   []string{"name", "category", "price", "unit", "supplier"},
+			wantCode: ErrMissingImportColumns.Code,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := productImportColumns(tt.header, tt.mapping)
+			if tt.wantCode != "" {
+				appErr, ok := apperror.As(err)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("productImportColumns() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("productImportColumns() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("productImportColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRow(t *testing.T) {
+	tests := []struct {
+		name        string
+		cells       []string
+		want        *dto.ProductRequest
+		wantColumns []string
+	}{
+		{
+			name:  "complete row",
+			cells: []string{"Paracetamol", "Analgesics", "Acetaminophen", "Tablets", "12.50", "40", "box", "31/12/2027", importTestBarcode, "kimia farma", "5", "false"},
+			want: &dto.ProductRequest{
+				Name:           "Paracetamol",
+				CategoryID:     1,
+				GenericName:    "Acetaminophen",
+				Description:    "Tablets",
+				Price:          decimal.RequireFromString("12.50"),
+				Stock:          40,
+				Unit:           "box",
+				ExpirationDate: time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC),
+				Barcode:        importTestBarcode,
+				SupplierID:     7,
+				MinStock:       5,
+				IsActive:       false,
+			},
+		},
+		{
+			name:  "blank optional cells keep defaults",
+			cells: []string{"Paracetamol", "analgesics", "", "", "12.50", "", "box", "2027-12-31", "", "Kimia Farma", "", ""},
+			want: &dto.ProductRequest{
+				Name:           "Paracetamol",
+				CategoryID:     1,
+				Price:          decimal.RequireFromString("12.50"),
+				Unit:           "box",
+				ExpirationDate: time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC),
+				SupplierID:     7,
+				IsActive:       true,
+			},
+		},
+		{
+			name:        "unconvertible cells",
+			cells:       []string{"Paracetamol", "Analgesics", "", "", "twelve", "4.5", "box", "next year", "", "Kimia Farma", "x", "maybe"},
+			wantColumns: []string{"Price", "Stock", "Min Stock", "Expiration Date", "Is Active"},
+		},
+		{
+			name:        "unknown and ambiguous names",
+			cells:       []string{"Paracetamol", "Vitamins", "", "", "1", "", "box", "2027-12-31", "", "Unknown", "", ""},
+			wantColumns: []string{"Category", "Supplier"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := newTestImport(t, false)
+			got, rowErrors := run.parseRow(spreadsheet.Row{Number: 2, Cells: tt.cells})
+
+			var columns []string
+			for _, rowError := range rowErrors {
+				if rowError.Row != 2 {
+					t.Errorf("row error %+v has row %d, want 2", rowError, rowError.Row)
+				}
+				columns = append(columns, rowError.Column)
+			}
+			if !reflect.DeepEqual(columns, tt.wantColumns) {
+				t.Fatalf("parseRow() error columns = %v, want %v", columns, tt.wantColumns)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportRowPatchesOnlyGivenCells(t *testing.T) {
+	run := newTestImport(t, false)
+	writer := &fakeProductWriter{}
+	u := &productImportUsecase{
+		products: &fakeImportProducts{byBarcode: map[string]*entity.Product{
+			importTestBarcode: {ID: 9, Version: 4},
+		}},
+		writer: writer,
+	}
+
+	row := spreadsheet.Row{Number: 2, Cells: []string{"Paracetamol 500", "", "", "", "13", "", "", "", importTestBarcode, "", "", ""}}
+	u.importRow(context.Background(), run, row)
+
+	if run.job.UpdatedRows != 1 || run.job.FailedRows != 0 || len(run.job.Errors) != 0 {
+		t.Fatalf("job = %+v, want one updated row", run.job)
+	}
+	if len(writer.created) != 0 || len(writer.patched) != 1 {
+		t.Fatalf("created %d and patched %d products, want one patch", len(writer.created), len(writer.patched))
+	}
+
+	want := &dto.ProductPatchRequest{
+		Name:    dto.Optional[string]{Set: true, Value: "Paracetamol 500"},
+		Price:   dto.Optional[decimal.Decimal]{Set: true, Value: decimal.RequireFromString("13")},
+		Barcode: dto.Optional[string]{Set: true, Value: importTestBarcode},
+	}
+	got := writer.patched[0]
+	// Unset fields still carry parseRow's defaults, which the patch ignores.
+	got.IsActive.Value = false
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("patch = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportRowCreatesNewProduct(t *testing.T) {
+	row := spreadsheet.Row{Number: 3, Cells: []string{"Paracetamol", "Analgesics", "", "", "12.50", "10", "box", "2999-12-31", importTestBarcode, "Kimia Farma", "", ""}}
+
+	for _, dryRun := range []bool{false, true} {
+		run := newTestImport(t, dryRun)
+		writer := &fakeProductWriter{}
+		u := &productImportUsecase{products: &fakeImportProducts{}, writer: writer}
+
+		u.importRow(context.Background(), run, row)
+
+		if run.job.CreatedRows != 1 || run.job.FailedRows != 0 {
+			t.Fatalf("dry run %v: job = %+v, want one created row", dryRun, run.job)
+		}
+		wantCreated := 1
+		if dryRun {
+			wantCreated = 0
+		}
+		if len(writer.created) != wantCreated || len(writer.patched) != 0 {
+			t.Errorf("dry run %v: created %d and patched %d products, want %d created", dryRun, len(writer.created), len(writer.patched), wantCreated)
+		}
+	}
+}
+
+func TestImportRowReportsValidationErrors(t *testing.T) {
+	run := newTestImport(t, false)
+	writer := &fakeProductWriter{}
+	u := &productImportUsecase{products: &fakeImportProducts{}, writer: writer}
+
+	row := spreadsheet.Row{Number: 4, Cells: []string{"", "Analgesics", "", "", "0", "", "box", "2000-01-01", "", "Kimia Farma", "", ""}}
+	u.importRow(context.Background(), run, row)
+
+	if run.job.FailedRows != 1 || len(writer.created) != 0 {
+		t.Fatalf("job = %+v, created %d, want one failed row", run.job, len(writer.created))
+	}
+
+	var columns []string
+	for _, rowError := range run.job.Errors {
+		columns = append(columns, rowError.Column)
+	}
+	want := []string{"Expiration Date", "Name", "Price"}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("error columns = %v, want %v", columns, want)
+	}
+}
+
+type fakeImportJobs struct {
+	repository.ImportJobRepository
+	mu    sync.Mutex
+	saved []entity.ImportJob
+}
+
+func (f *fakeImportJobs) Create(ctx context.Context, job *entity.ImportJob) error {
+	job.ID = 1
+	return nil
+}
+
+func (f *fakeImportJobs) Update(ctx context.Context, job *entity.ImportJob) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved = append(f.saved, *job)
+	return nil
+}
+
+type fakeImportCategories struct {
+	repository.CategoryRepository
+	ids map[string][]int64
+}
+
+func (f *fakeImportCategories) IDsByName(ctx context.Context) (map[string][]int64, error) {
+	return f.ids, nil
+}
+
+type fakeImportSuppliers struct {
+	repository.SupplierRepository
+	ids map[string][]int64
+}
+
+func (f *fakeImportSuppliers) IDsByName(ctx context.Context) (map[string][]int64, error) {
+	return f.ids, nil
+}
+
+// cancellingProductWriter shuts the application down while the first
+// product is being saved and waits until the import has been told.
+type cancellingProductWriter struct {
+	fakeProductWriter
+	cancel context.CancelFunc
+}
+
+func (f *cancellingProductWriter) CreateProduct(ctx context.Context, req *dto.ProductRequest) (*dto.ProductResponse, error) {
+	f.cancel()
+	<-ctx.Done()
+	return f.fakeProductWriter.CreateProduct(ctx, req)
+}
+
+func TestImportStopsOnShutdown(t *testing.T) {
+	lifecycle, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := &fakeImportJobs{}
+	categories := &fakeImportCategories{ids: map[string][]int64{"analgesics": {1}}}
+	suppliers := &fakeImportSuppliers{ids: map[string][]int64{"kimia farma": {7}}}
+	writer := &cancellingProductWriter{cancel: cancel}
+	u := NewProductImportUsecase(lifecycle, jobs, &fakeImportProducts{}, categories, suppliers, writer)
+
+	row := "Paracetamol,Analgesics,,,12.50,10,box,2999-12-31,,Kimia Farma,,\n"
+	file := strings.Join(importTestHeader, ",") + "\n" + strings.Repeat(row, 3)
+
+	// The request's context ending must not stop the import.
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	if _, err := u.StartImport(reqCtx, 9, &dto.ProductImportRequest{FileName: "products.csv"}, strings.NewReader(file)); err != nil {
+		t.Fatalf("StartImport() error = %v", err)
+	}
+	reqCancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	if err := u.Wait(waitCtx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if len(writer.created) != 1 {
+		t.Errorf("created %d products, want 1 before the shutdown", len(writer.created))
+	}
+
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	last := jobs.saved[len(jobs.saved)-1]
+	if last.Status != entity.ImportStatusFailed || last.Failure == nil || last.FinishedAt == nil {
+		t.Fatalf("last saved job = %+v, want it finished as failed", last)
+	}
+	if last.ProcessedRows != 1 || last.CreatedRows != 1 {
+		t.Errorf("job processed %d and created %d rows, want 1 and 1", last.ProcessedRows, last.CreatedRows)
+	}
+}
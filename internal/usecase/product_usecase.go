@@ -2,30 +2,42 @@ package usecase
 
 import (
 	"context"
+	"pharmly-backend/internal/database"
 	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/metrics"
+	"pharmly-backend/internal/pagination"
 	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/tracing"
 	"time"
 )
 
 type ProductUsecase interface {
 	CreateProduct(ctx context.Context, req *dto.ProductRequest) (*dto.ProductResponse, error)
 	GetProductByID(ctx context.Context, id int64) (*entity.Product, error)
-	GetAllProducts(ctx context.Context, page, pageSize int) ([]*entity.Product, *dto.PaginationResponse, error)
-	UpdateProduct(ctx context.Context, id int64, req *dto.ProductRequest) (*dto.ProductResponse, error)
-	DeleteProduct(ctx context.Context, id int64) error
+	GetAllProducts(ctx context.Context, req *dto.PaginationRequest) ([]*entity.Product, *dto.PaginationResponse, error)
+	ExportProducts(ctx context.Context, w spreadsheet.Writer) error
+	UpdateProduct(ctx context.Context, id int64, version int64, req *dto.ProductRequest) (*dto.ProductResponse, error)
+	PatchProduct(ctx context.Context, id int64, version int64, req *dto.ProductPatchRequest) (*dto.ProductResponse, error)
+	DeleteProduct(ctx context.Context, id int64, version int64) error
+	RefreshInventoryMetrics(ctx context.Context, expiryWindow time.Duration) error
 }
 
 type productsUsecase struct {
 	repo repository.ProductRepository
+	tx   database.Transactor
 }
 
-func NewProductusecase(repo repository.ProductRepository) ProductUsecase {
-	return &productsUsecase{repo: repo}
+func NewProductusecase(repo repository.ProductRepository, tx database.Transactor) ProductUsecase {
+	return &productsUsecase{repo: repo, tx: tx}
 }
 
 func (u *productsUsecase) CreateProduct(ctx context.Context, req *dto.ProductRequest) (*dto.ProductResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.CreateProduct")
+	defer span.End()
+
 	product := &entity.Product{
 		Name:           req.Name,
 		CategoryID:     req.CategoryID,
@@ -59,81 +71,175 @@ func (u *productsUsecase) CreateProduct(ctx context.Context, req *dto.ProductReq
 		SupplierID:     product.SupplierID,
 		MinStock:       product.MinStock,
 		IsActive:       product.IsActive,
+		Version:        product.Version,
 	}, nil
 }
 
 func (u *productsUsecase) GetProductByID(ctx context.Context, id int64) (*entity.Product, error) {
-	logger.Info().Int64("product_id", id).Msg("Fetching product by ID")
+	ctx, span := tracing.Start(ctx, "ProductUsecase.GetProductByID")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Fetching product by ID")
 
 	product, err := u.repo.GetByID(ctx, id)
 	if err != nil {
-		logger.Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
 		return nil, err
 	}
 
-	logger.Info().Int64("product_id", id).Msg("Product fetched successfully")
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Product fetched successfully")
 	return product, nil
 }
 
-func (u *productsUsecase) GetAllProducts(ctx context.Context, page, pageSize int) ([]*entity.Product, *dto.PaginationResponse, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated products")
+func (u *productsUsecase) GetAllProducts(ctx context.Context, req *dto.PaginationRequest) ([]*entity.Product, *dto.PaginationResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.GetAllProducts")
+	defer span.End()
 
-	products, total, err := u.repo.GetAll(ctx, page, pageSize)
+	logger.Ctx(ctx).Info().Int("page", req.Page).Int("page_size", req.PageSize).Msg("Fetching paginated products")
+
+	query, err := pagination.NewQuery(req)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch products")
 		return nil, nil, err
 	}
 
-	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
-	hasNextPage := page < int(totalPages)
-	hasPrevPage := page > 1
-
-	nextPage := page + 1
-	prevPage := page - 1
-
-	pagination := &dto.PaginationResponse{
-		TotalItems:   total,
-		TotalPages:   int(totalPages),
-		CurrentPage:  page,
-		PageSize:     pageSize,
-		HasNextPage:  hasNextPage,
-		HasPrevPage:  hasPrevPage,
-		NextPage:     &nextPage,
-		PreviousPage: &prevPage,
+	page, err := u.repo.GetAll(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch products")
+		return nil, nil, err
 	}
 
-	logger.Info().Int("count", len(products)).Int64("total", total).Msg("Products fetched successfully")
-	return products, pagination, nil
+	paging := pagination.Response(req, page, func(product *entity.Product) int64 { return product.ID })
+
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Products fetched successfully")
+	return page.Items, paging, nil
 }
 
-func (u *productsUsecase) UpdateProduct(ctx context.Context, id int64, req *dto.ProductRequest) (*dto.ProductResponse, error) {
-	logger.Info().Int64("product_id", id).Msg("Starting product update process")
+func (u *productsUsecase) ExportProducts(ctx context.Context, w spreadsheet.Writer) error {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.ExportProducts")
+	defer span.End()
 
-	product, err := u.repo.GetByID(ctx, id)
+	logger.Ctx(ctx).Info().Msg("Exporting products")
+
+	if err := w.Write("id", "name", "generic_name", "category_id", "supplier_id", "barcode", "unit", "price", "stock", "min_stock", "expiration_date", "is_active", "created_at", "updated_at"); err != nil {
+		return err
+	}
+
+	count, err := exportRows(ctx, u.repo.GetAll, func(product *entity.Product) int64 { return product.ID }, func(product *entity.Product) error {
+		return w.Write(
+			product.ID,
+			product.Name,
+			product.GenericName,
+			product.CategoryID,
+			product.SupplierID,
+			product.Barcode,
+			product.Unit,
+			product.Price,
+			product.Stock,
+			product.MinStock,
+			spreadsheet.Date(product.ExpirationDate),
+			product.IsActive,
+			product.CreatedAt,
+			product.UpdatedAt,
+		)
+	})
 	if err != nil {
-		logger.Error().Err(err).Int64("transaction_id", id).Msg("Failed to fetch transaction")
+		logger.Ctx(ctx).Error().Err(err).Int("count", count).Msg("Failed to export products")
+		return err
 	}
 
-	product.Name = req.Name
-	product.CategoryID = req.CategoryID
-	product.GenericName = req.GenericName
-	product.Description = &req.Description
-	product.Price = req.Price
-	product.Stock = req.Stock
-	product.Unit = req.Unit
-	product.ExpirationDate = req.ExpirationDate
-	product.Barcode = req.Barcode
-	product.SupplierID = req.SupplierID
-	product.MinStock = req.MinStock
-	product.IsActive = req.IsActive
-	product.UpdatedAt = time.Now()
-
-	if err := u.repo.Update(ctx, product); err != nil {
-		logger.Error().Err(err).Int64("product_id", id).Msg("Failed to update product")
+	logger.Ctx(ctx).Info().Int("count", count).Msg("Products exported successfully")
+	return nil
+}
+
+func (u *productsUsecase) UpdateProduct(ctx context.Context, id int64, version int64, req *dto.ProductRequest) (*dto.ProductResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.UpdateProduct")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Starting product update process")
+
+	var product *entity.Product
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		product, err = u.repo.GetByID(ctx, id)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
+			return err
+		}
+
+		if !repository.VersionMatches(product.Version, version) {
+			return repository.ErrVersionConflict
+		}
+
+		product.Name = req.Name
+		product.CategoryID = req.CategoryID
+		product.GenericName = req.GenericName
+		product.Description = &req.Description
+		product.Price = req.Price
+		product.Stock = req.Stock
+		product.Unit = req.Unit
+		product.ExpirationDate = req.ExpirationDate
+		product.Barcode = req.Barcode
+		product.SupplierID = req.SupplierID
+		product.MinStock = req.MinStock
+		product.IsActive = req.IsActive
+		product.UpdatedAt = time.Now()
+
+		return u.repo.Update(ctx, product)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to update product")
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Product updated successfully")
+	return &dto.ProductResponse{
+		ID:             product.ID,
+		Name:           product.Name,
+		CategoryID:     product.CategoryID,
+		GenericName:    product.GenericName,
+		Description:    product.Description,
+		Price:          product.Price,
+		Stock:          product.Stock,
+		Unit:           product.Unit,
+		ExpirationDate: product.ExpirationDate,
+		Barcode:        product.Barcode,
+		SupplierID:     product.SupplierID,
+		MinStock:       product.MinStock,
+		IsActive:       product.IsActive,
+		CreatedAt:      product.CreatedAt,
+		UpdatedAt:      product.UpdatedAt,
+		DeletedAt:      product.DeletedAt,
+		Version:        product.Version,
+	}, nil
+}
+
+func (u *productsUsecase) PatchProduct(ctx context.Context, id int64, version int64, req *dto.ProductPatchRequest) (*dto.ProductResponse, error) {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.PatchProduct")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Starting product patch process")
+
+	var fields []repository.FieldUpdate
+	fields = patchOptional(fields, "name", req.Name)
+	fields = patchOptional(fields, "category_id", req.CategoryID)
+	fields = patchOptional(fields, "generic_name", req.GenericName)
+	fields = patchNullable(fields, "description", req.Description)
+	fields = patchOptional(fields, "price", req.Price)
+	fields = patchOptional(fields, "stock", req.Stock)
+	fields = patchOptional(fields, "unit", req.Unit)
+	fields = patchOptional(fields, "expiration_date", req.ExpirationDate)
+	fields = patchOptional(fields, "barcode", req.Barcode)
+	fields = patchOptional(fields, "supplier_id", req.SupplierID)
+	fields = patchOptional(fields, "min_stock", req.MinStock)
+	fields = patchOptional(fields, "is_active", req.IsActive)
+
+	product, err := u.repo.Patch(ctx, id, version, fields)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to patch product")
 		return nil, err
 	}
 
-	logger.Info().Int64("product_id", id).Msg("Product updated successfully")
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Product patched successfully")
 	return &dto.ProductResponse{
 		ID:             product.ID,
 		Name:           product.Name,
@@ -151,23 +257,51 @@ func (u *productsUsecase) UpdateProduct(ctx context.Context, id int64, req *dto.
 		CreatedAt:      product.CreatedAt,
 		UpdatedAt:      product.UpdatedAt,
 		DeletedAt:      product.DeletedAt,
+		Version:        product.Version,
 	}, nil
 }
 
-func (u *productsUsecase) DeleteProduct(ctx context.Context, id int64) error {
-	logger.Info().Int64("product_id", id).Msg("Starting product deletion proccess")
+func (u *productsUsecase) DeleteProduct(ctx context.Context, id int64, version int64) error {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.DeleteProduct")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Starting product deletion proccess")
+
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		product, err := u.repo.GetByID(ctx, id)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
+			return err
+		}
 
-	_, err := u.repo.GetByID(ctx, id)
+		if !repository.VersionMatches(product.Version, version) {
+			return repository.ErrVersionConflict
+		}
+
+		return u.repo.Delete(ctx, id, product.Version)
+	})
 	if err != nil {
-		logger.Error().Err(err).Int64("product_id", id).Msg("Failed to fetch product")
-		return nil
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", id).Msg("Failed to delete product")
+		return err
 	}
 
-	if err := u.repo.Delete(ctx, id); err != nil {
-		logger.Error().Err(err).Int64("product_id", id).Msg("Failed to delete product")
-		return nil
+	logger.Ctx(ctx).Info().Int64("product_id", id).Msg("Product deleted successfully")
+	return nil
+}
+
+// RefreshInventoryMetrics updates the low-stock and expiring product gauges.
+// Products expiring within expiryWindow from now count as expiring.
+func (u *productsUsecase) RefreshInventoryMetrics(ctx context.Context, expiryWindow time.Duration) error {
+	ctx, span := tracing.Start(ctx, "ProductUsecase.RefreshInventoryMetrics")
+	defer span.End()
+
+	lowStock, expiring, err := u.repo.CountInventoryAlerts(ctx, time.Now().Add(expiryWindow))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to refresh inventory metrics")
+		return err
 	}
 
-	logger.Info().Int64("product_id", id).Msg("Product deleted successfully")
+	metrics.LowStockProducts.Set(float64(lowStock))
+	metrics.ExpiringProducts.Set(float64(expiring))
 	return nil
 }
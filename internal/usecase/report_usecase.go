@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/report"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
+	"time"
+)
+
+const (
+	defaultReportSort  = "top"
+	defaultReportLimit = 20
+)
+
+type ReportUsecase interface {
+	SalesReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.PeriodSalesResponse], error)
+	ProductReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.ProductSalesResponse], error)
+	CategoryReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.CategorySalesResponse], error)
+	CashierReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.CashierSalesResponse], error)
+}
+
+type reportUsecase struct {
+	repo     repository.ReportRepository
+	timezone string
+	now      func() time.Time
+}
+
+// NewReportUsecase returns a ReportUsecase whose reports use timezone unless
+// a request names another.
+func NewReportUsecase(repo repository.ReportRepository, timezone string) ReportUsecase {
+	return &reportUsecase{repo: repo, timezone: timezone, now: time.Now}
+}
+
+// SalesReport totals sales by day, week or month. Periods without sales are
+// included with zero totals.
+func (u *reportUsecase) SalesReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.PeriodSalesResponse], error) {
+	ctx, span := tracing.Start(ctx, "ReportUsecase.SalesReport")
+	defer span.End()
+
+	rng, err := u.reportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	groupBy := req.GroupBy
+	if groupBy == "" {
+		groupBy = report.GroupByDay
+	}
+
+	sales, err := u.repo.SalesByPeriod(ctx, rng.Filter(req.BranchID), groupBy)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to build sales report")
+		return nil, err
+	}
+
+	byPeriod := make(map[string]*entity.PeriodSales, len(sales))
+	for _, period := range sales {
+		byPeriod[report.FormatDate(period.Period)] = period
+	}
+
+	response := newReportResponse[dto.PeriodSalesResponse](rng, req)
+	response.GroupBy = groupBy
+	for _, start := range rng.Periods(groupBy) {
+		row := dto.PeriodSalesResponse{Period: report.FormatDate(start)}
+		if period, ok := byPeriod[row.Period]; ok {
+			row.Sales = period.Sales
+			row.SalesTotals = salesTotals(period.SalesTotals)
+		}
+		response.Rows = append(response.Rows, row)
+	}
+
+	return response, nil
+}
+
+// ProductReport lists the best selling products by units sold, or with sort
+// slow the active products that sold least, including those that did not
+// sell at all.
+func (u *reportUsecase) ProductReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.ProductSalesResponse], error) {
+	ctx, span := tracing.Start(ctx, "ReportUsecase.ProductReport")
+	defer span.End()
+
+	rng, err := u.reportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, limit := req.Sort, req.Limit
+	if sort == "" {
+		sort = defaultReportSort
+	}
+	if limit == 0 {
+		limit = defaultReportLimit
+	}
+
+	fetch := u.repo.TopProducts
+	if sort == "slow" {
+		fetch = u.repo.SlowProducts
+	}
+
+	products, err := fetch(ctx, rng.Filter(req.BranchID), limit)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Str("sort", sort).Msg("Failed to build product report")
+		return nil, err
+	}
+
+	response := newReportResponse[dto.ProductSalesResponse](rng, req)
+	response.Sort = sort
+	for _, product := range products {
+		response.Rows = append(response.Rows, dto.ProductSalesResponse{
+			ProductID:   product.ProductID,
+			Name:        product.Name,
+			SalesTotals: salesTotals(product.SalesTotals),
+		})
+	}
+
+	return response, nil
+}
+
+func (u *reportUsecase) CategoryReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.CategorySalesResponse], error) {
+	ctx, span := tracing.Start(ctx, "ReportUsecase.CategoryReport")
+	defer span.End()
+
+	rng, err := u.reportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := u.repo.SalesByCategory(ctx, rng.Filter(req.BranchID))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to build category report")
+		return nil, err
+	}
+
+	response := newReportResponse[dto.CategorySalesResponse](rng, req)
+	for _, category := range categories {
+		response.Rows = append(response.Rows, dto.CategorySalesResponse{
+			CategoryID:  category.CategoryID,
+			Name:        category.Name,
+			SalesTotals: salesTotals(category.SalesTotals),
+		})
+	}
+
+	return response, nil
+}
+
+func (u *reportUsecase) CashierReport(ctx context.Context, req *dto.ReportRequest) (*dto.ReportResponse[dto.CashierSalesResponse], error) {
+	ctx, span := tracing.Start(ctx, "ReportUsecase.CashierReport")
+	defer span.End()
+
+	rng, err := u.reportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cashiers, err := u.repo.SalesByCashier(ctx, rng.Filter(req.BranchID))
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to build cashier report")
+		return nil, err
+	}
+
+	response := newReportResponse[dto.CashierSalesResponse](rng, req)
+	for _, cashier := range cashiers {
+		response.Rows = append(response.Rows, dto.CashierSalesResponse{
+			CashierID:   cashier.CashierID,
+			Username:    cashier.Username,
+			FullName:    cashier.FullName,
+			Sales:       cashier.Sales,
+			SalesTotals: salesTotals(cashier.SalesTotals),
+		})
+	}
+
+	return response, nil
+}
+
+func (u *reportUsecase) reportRange(req *dto.ReportRequest) (report.Range, error) {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = u.timezone
+	}
+	return report.NewRange(req.From, req.To, timezone, u.now())
+}
+
+func newReportResponse[T any](rng report.Range, req *dto.ReportRequest) *dto.ReportResponse[T] {
+	return &dto.ReportResponse[T]{
+		From:     report.FormatDate(rng.From),
+		To:       report.FormatDate(rng.To),
+		Timezone: rng.Location.String(),
+		BranchID: req.BranchID,
+		Rows:     []T{},
+	}
+}
+
+func salesTotals(totals entity.SalesTotals) dto.SalesTotals {
+	return dto.SalesTotals{
+		Units:       totals.Units,
+		Revenue:     totals.Revenue,
+		Cost:        totals.Cost,
+		GrossProfit: totals.GrossProfit,
+	}
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/report"
+	"pharmly-backend/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type fakeReports struct {
+	repository.ReportRepository
+	periods []*entity.PeriodSales
+	filter  report.Filter
+	groupBy string
+	called  string
+	limit   int
+}
+
+func (f *fakeReports) SalesByPeriod(ctx context.Context, filter report.Filter, groupBy string) ([]*entity.PeriodSales, error) {
+	f.filter, f.groupBy = filter, groupBy
+	return f.periods, nil
+}
+
+func (f *fakeReports) TopProducts(ctx context.Context, filter report.Filter, limit int) ([]*entity.ProductSales, error) {
+	f.filter, f.called, f.limit = filter, "top", limit
+	return nil, nil
+}
+
+func (f *fakeReports) SlowProducts(ctx context.Context, filter report.Filter, limit int) ([]*entity.ProductSales, error) {
+	f.filter, f.called, f.limit = filter, "slow", limit
+	return nil, nil
+}
+
+func newTestReportUsecase(repo repository.ReportRepository) *reportUsecase {
+	return &reportUsecase{
+		repo:     repo,
+		timezone: "Asia/Jakarta",
+		now:      func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestSalesReportIncludesEmptyPeriods(t *testing.T) {
+	repo := &fakeReports{periods: []*entity.PeriodSales{
+		{
+			Period: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			Sales:  3,
+			SalesTotals: entity.SalesTotals{
+				Units:       4,
+				Revenue:     decimal.NewFromInt(40),
+				Cost:        decimal.NewFromInt(25),
+				GrossProfit: decimal.NewFromInt(15),
+			},
+		},
+	}}
+	u := newTestReportUsecase(repo)
+
+	branchID := int64(2)
+	response, err := u.SalesReport(context.Background(), &dto.ReportRequest{From: "2026-03-01", To: "2026-03-03", BranchID: &branchID})
+	if err != nil {
+		t.Fatalf("SalesReport() error = %v", err)
+	}
+
+	if repo.groupBy != report.GroupByDay || repo.filter.Timezone != "Asia/Jakarta" || repo.filter.BranchID != &branchID {
+		t.Errorf("filter = %+v grouped by %q, want days in Asia/Jakarta at branch 2", repo.filter, repo.groupBy)
+	}
+	if want := time.Date(2026, 2, 28, 17, 0, 0, 0, time.UTC); !repo.filter.Start.Equal(want) {
+		t.Errorf("Start = %s, want %s", repo.filter.Start.UTC(), want)
+	}
+
+	if response.From != "2026-03-01" || response.To != "2026-03-03" || response.GroupBy != report.GroupByDay {
+		t.Errorf("response covers %s to %s by %s", response.From, response.To, response.GroupBy)
+	}
+
+	if len(response.Rows) != 3 {
+		t.Fatalf("rows = %+v, want one per day", response.Rows)
+	}
+	for i, want := range []struct {
+		period string
+		sales  int64
+		profit int64
+	}{
+		{"2026-03-01", 0, 0},
+		{"2026-03-02", 3, 15},
+		{"2026-03-03", 0, 0},
+	} {
+		row := response.Rows[i]
+		if row.Period != want.period || row.Sales != want.sales || !row.GrossProfit.Equal(decimal.NewFromInt(want.profit)) {
+			t.Errorf("row %d = %+v, want %s with %d sales and %d profit", i, row, want.period, want.sales, want.profit)
+		}
+	}
+}
+
+func TestSalesReportRejectsInvalidRange(t *testing.T) {
+	u := newTestReportUsecase(&fakeReports{})
+
+	_, err := u.SalesReport(context.Background(), &dto.ReportRequest{From: "2026-03-05", To: "2026-03-01"})
+	if !errors.Is(err, report.ErrInvalidRange) {
+		t.Fatalf("SalesReport() error = %v, want %v", err, report.ErrInvalidRange)
+	}
+}
+
+func TestProductReportSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       dto.ReportRequest
+		wantQuery string
+		wantLimit int
+	}{
+		{"defaults to top sellers", dto.ReportRequest{}, "top", defaultReportLimit},
+		{"slow movers", dto.ReportRequest{Sort: "slow", Limit: 5}, "slow", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeReports{}
+			u := newTestReportUsecase(repo)
+
+			response, err := u.ProductReport(context.Background(), &tt.req)
+			if err != nil {
+				t.Fatalf("ProductReport() error = %v", err)
+			}
+
+			if repo.called != tt.wantQuery || repo.limit != tt.wantLimit {
+				t.Errorf("queried %s with limit %d, want %s with limit %d", repo.called, repo.limit, tt.wantQuery, tt.wantLimit)
+			}
+			if response.Sort != tt.wantQuery || response.Rows == nil {
+				t.Errorf("response = %+v, want sort %s and empty rows", response, tt.wantQuery)
+			}
+			if response.From != "2026-02-09" || response.To != "2026-03-10" {
+				t.Errorf("response covers %s to %s, want the last 30 days", response.From, response.To)
+			}
+		})
+	}
+}
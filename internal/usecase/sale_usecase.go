@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/metrics"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrProductNotForSale = apperror.Conflict("product_not_for_sale", "product is inactive and cannot be sold")
+
+type SaleUsecase interface {
+	CreateSale(ctx context.Context, cashierID int64, req *dto.SaleRequest) (*dto.SaleResponse, error)
+	GetSaleByID(ctx context.Context, id int64) (*dto.SaleResponse, error)
+}
+
+type saleUsecase struct {
+	sales    repository.SaleRepository
+	batches  repository.ProductBatchRepository
+	products repository.ProductRepository
+	tx       database.Transactor
+	location *time.Location
+}
+
+// NewSaleUsecase returns a SaleUsecase that treats a batch as expired from
+// the day after its expiration date in location.
+func NewSaleUsecase(sales repository.SaleRepository, batches repository.ProductBatchRepository, products repository.ProductRepository, tx database.Transactor, location *time.Location) SaleUsecase {
+	return &saleUsecase{sales: sales, batches: batches, products: products, tx: tx, location: location}
+}
+
+// CreateSale sells the requested items at their current price, taking stock
+// from the batches at the branch that expire soonest.
+func (u *saleUsecase) CreateSale(ctx context.Context, cashierID int64, req *dto.SaleRequest) (*dto.SaleResponse, error) {
+	ctx, span := tracing.Start(ctx, "SaleUsecase.CreateSale")
+	defer span.End()
+
+	now := time.Now().In(u.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	sale := &entity.Sale{BranchID: req.BranchID, CashierID: cashierID}
+	err := u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		sale.Items = nil
+		for _, line := range req.Items {
+			items, err := u.sellProduct(ctx, req.BranchID, line, today)
+			if err != nil {
+				return err
+			}
+			sale.Items = append(sale.Items, items...)
+		}
+
+		sale.TotalAmount, sale.TotalCost = saleTotals(sale.Items)
+		return u.sales.Create(ctx, sale)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("branch_id", req.BranchID).Msg("Failed to create sale")
+		return nil, err
+	}
+
+	units := 0
+	for _, item := range sale.Items {
+		units += item.Quantity
+	}
+	metrics.RecordSale(sale.TotalAmount.InexactFloat64(), units)
+
+	return saleResponse(sale), nil
+}
+
+// sellProduct takes one line of a sale out of stock and returns the items it
+// was sold as.
+func (u *saleUsecase) sellProduct(ctx context.Context, branchID int64, line dto.SaleItemRequest, today time.Time) ([]entity.SaleItem, error) {
+	// Taking the units out of the product's stock first locks the product,
+	// so its batches cannot change until the sale commits.
+	product, err := u.products.AdjustStock(ctx, line.ProductID, -line.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.DeletedAt.Valid {
+		return nil, repository.ErrProductNotFound
+	}
+	if !product.IsActive {
+		return nil, ErrProductNotForSale
+	}
+
+	batches, err := u.batches.GetSellable(ctx, product.ID, branchID, today)
+	if err != nil {
+		return nil, err
+	}
+
+	batched, err := u.batches.BatchedStock(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := allocateSale(product.ID, line.Quantity, product.Price, batches, product.Stock+line.Quantity-batched)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("product_id", product.ID).Int64("branch_id", branchID).Int("quantity", line.Quantity).Msg("Not enough sellable stock")
+		return nil, err
+	}
+
+	for _, item := range items {
+		if item.BatchID == nil {
+			continue
+		}
+		if err := u.batches.Consume(ctx, *item.BatchID, item.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// allocateSale splits the sale of quantity units of a product across its
+// batches, which must be ordered soonest to expire first, and then across
+// the unbatched units of its stock. Units from a batch cost its cost price.
+// Unbatched units, such as stock entered before batches were recorded, have
+// no known cost and are costed at their price, so they add revenue but no
+// gross profit.
+func allocateSale(productID int64, quantity int, price decimal.Decimal, batches []*entity.ProductBatch, unbatched int) ([]entity.SaleItem, error) {
+	var items []entity.SaleItem
+	remaining := quantity
+	for _, batch := range batches {
+		if remaining == 0 {
+			break
+		}
+
+		take := min(remaining, batch.QuantityRemaining)
+		if take <= 0 {
+			continue
+		}
+
+		items = append(items, entity.SaleItem{
+			ProductID: productID,
+			BatchID:   &batch.ID,
+			Quantity:  take,
+			UnitPrice: price,
+			UnitCost:  batch.CostPrice,
+		})
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		if remaining > unbatched {
+			return nil, repository.ErrInsufficientStock
+		}
+
+		items = append(items, entity.SaleItem{
+			ProductID: productID,
+			Quantity:  remaining,
+			UnitPrice: price,
+			UnitCost:  price,
+		})
+	}
+	return items, nil
+}
+
+func saleTotals(items []entity.SaleItem) (amount, cost decimal.Decimal) {
+	for _, item := range items {
+		quantity := decimal.NewFromInt(int64(item.Quantity))
+		amount = amount.Add(item.UnitPrice.Mul(quantity))
+		cost = cost.Add(item.UnitCost.Mul(quantity))
+	}
+	return amount, cost
+}
+
+func (u *saleUsecase) GetSaleByID(ctx context.Context, id int64) (*dto.SaleResponse, error) {
+	ctx, span := tracing.Start(ctx, "SaleUsecase.GetSaleByID")
+	defer span.End()
+
+	sale, err := u.sales.GetByID(ctx, id)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("sale_id", id).Msg("Failed to fetch sale")
+		return nil, err
+	}
+
+	return saleResponse(sale), nil
+}
+
+func saleResponse(sale *entity.Sale) *dto.SaleResponse {
+	items := make([]dto.SaleItemResponse, 0, len(sale.Items))
+	for _, item := range sale.Items {
+		items = append(items, dto.SaleItemResponse{
+			ProductID: item.ProductID,
+			BatchID:   item.BatchID,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Subtotal:  item.UnitPrice.Mul(decimal.NewFromInt(int64(item.Quantity))),
+		})
+	}
+
+	return &dto.SaleResponse{
+		ID:          sale.ID,
+		BranchID:    sale.BranchID,
+		CashierID:   sale.CashierID,
+		TotalAmount: sale.TotalAmount,
+		SoldAt:      sale.SoldAt,
+		Items:       items,
+	}
+}
@@ -0,0 +1,259 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"pharmly-backend/internal/database"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAllocateSale(t *testing.T) {
+	price := decimal.NewFromInt(10)
+	batches := func() []*entity.ProductBatch {
+		return []*entity.ProductBatch{
+			{ID: 1, QuantityRemaining: 3, CostPrice: decimal.NewFromInt(4)},
+			{ID: 2, QuantityRemaining: 5, CostPrice: decimal.NewFromInt(6)},
+		}
+	}
+
+	type item struct {
+		batchID  int64
+		quantity int
+		cost     int64
+	}
+
+	tests := []struct {
+		name      string
+		quantity  int
+		batches   []*entity.ProductBatch
+		unbatched int
+		want      []item
+		wantErr   error
+	}{
+		{
+			name:     "first batch covers the sale",
+			quantity: 2,
+			batches:  batches(),
+			want:     []item{{1, 2, 4}},
+		},
+		{
+			name:     "soonest to expire is used up first",
+			quantity: 7,
+			batches:  batches(),
+			want:     []item{{1, 3, 4}, {2, 4, 6}},
+		},
+		{
+			name:      "unbatched stock is sold at its price",
+			quantity:  10,
+			batches:   batches(),
+			unbatched: 4,
+			want:      []item{{1, 3, 4}, {2, 5, 6}, {0, 2, 10}},
+		},
+		{
+			name:      "no batches",
+			quantity:  2,
+			unbatched: 2,
+			want:      []item{{0, 2, 10}},
+		},
+		{
+			name:      "not enough stock",
+			quantity:  10,
+			batches:   batches(),
+			unbatched: 1,
+			wantErr:   repository.ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := allocateSale(7, tt.quantity, price, tt.batches, tt.unbatched)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("allocateSale() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var got []item
+			for _, it := range items {
+				if it.ProductID != 7 || !it.UnitPrice.Equal(price) {
+					t.Errorf("item %+v does not sell product 7 at %s", it, price)
+				}
+				var batchID int64
+				if it.BatchID != nil {
+					batchID = *it.BatchID
+				}
+				got = append(got, item{batchID, it.Quantity, it.UnitCost.IntPart()})
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocateSale() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("allocateSale() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...database.TxOption) error {
+	return fn(ctx)
+}
+
+type fakeSaleProducts struct {
+	repository.ProductRepository
+	products map[int64]*entity.Product
+}
+
+func (f *fakeSaleProducts) AdjustStock(ctx context.Context, id int64, delta int) (*entity.Product, error) {
+	product, ok := f.products[id]
+	if !ok {
+		return nil, repository.ErrProductNotFound
+	}
+	if product.Stock+delta < 0 {
+		return nil, repository.ErrInsufficientStock
+	}
+	product.Stock += delta
+	adjusted := *product
+	return &adjusted, nil
+}
+
+type fakeSaleBatches struct {
+	repository.ProductBatchRepository
+	batches  []*entity.ProductBatch
+	asOf     time.Time
+	consumed map[int64]int
+}
+
+func (f *fakeSaleBatches) GetSellable(ctx context.Context, productID, branchID int64, asOf time.Time) ([]*entity.ProductBatch, error) {
+	f.asOf = asOf
+	var sellable []*entity.ProductBatch
+	for _, batch := range f.batches {
+		if batch.ProductID == productID && batch.BranchID == branchID && batch.QuantityRemaining > 0 {
+			sellable = append(sellable, batch)
+		}
+	}
+	return sellable, nil
+}
+
+func (f *fakeSaleBatches) BatchedStock(ctx context.Context, productID int64) (int, error) {
+	total := 0
+	for _, batch := range f.batches {
+		if batch.ProductID == productID {
+			total += batch.QuantityRemaining
+		}
+	}
+	return total, nil
+}
+
+func (f *fakeSaleBatches) Consume(ctx context.Context, id int64, quantity int) error {
+	f.consumed[id] += quantity
+	for _, batch := range f.batches {
+		if batch.ID == id {
+			batch.QuantityRemaining -= quantity
+		}
+	}
+	return nil
+}
+
+type fakeSales struct {
+	repository.SaleRepository
+	created *entity.Sale
+}
+
+func (f *fakeSales) Create(ctx context.Context, sale *entity.Sale) error {
+	sale.ID = 1
+	f.created = sale
+	return nil
+}
+
+func TestCreateSale(t *testing.T) {
+	newFakes := func() (*fakeSaleProducts, *fakeSaleBatches) {
+		products := &fakeSaleProducts{products: map[int64]*entity.Product{
+			1: {ID: 1, Price: decimal.NewFromInt(10), Stock: 6, IsActive: true},
+			2: {ID: 2, Price: decimal.NewFromInt(3), Stock: 5, IsActive: true},
+			3: {ID: 3, Price: decimal.NewFromInt(8), Stock: 5},
+		}}
+		batches := &fakeSaleBatches{
+			consumed: map[int64]int{},
+			batches: []*entity.ProductBatch{
+				// Product 1 has one unbatched unit besides these.
+				{ID: 10, ProductID: 1, BranchID: 1, QuantityRemaining: 2, CostPrice: decimal.NewFromInt(5)},
+				{ID: 11, ProductID: 1, BranchID: 1, QuantityRemaining: 2, CostPrice: decimal.NewFromInt(6)},
+				{ID: 12, ProductID: 1, BranchID: 2, QuantityRemaining: 1, CostPrice: decimal.NewFromInt(1)},
+				{ID: 20, ProductID: 2, BranchID: 1, QuantityRemaining: 5, CostPrice: decimal.NewFromInt(2)},
+			},
+		}
+		return products, batches
+	}
+
+	t.Run("takes stock from batches and then unbatched units", func(t *testing.T) {
+		products, batches := newFakes()
+		sales := &fakeSales{}
+		u := NewSaleUsecase(sales, batches, products, fakeTransactor{}, time.UTC)
+
+		response, err := u.CreateSale(context.Background(), 9, &dto.SaleRequest{
+			BranchID: 1,
+			Items:    []dto.SaleItemRequest{{ProductID: 1, Quantity: 5}, {ProductID: 2, Quantity: 2}},
+		})
+		if err != nil {
+			t.Fatalf("CreateSale() error = %v", err)
+		}
+
+		if got := batches.consumed; got[10] != 2 || got[11] != 2 || got[12] != 0 || got[20] != 2 {
+			t.Errorf("consumed = %v, want batches 10 and 11 used up and 2 units of batch 20", got)
+		}
+		if products.products[1].Stock != 1 || products.products[2].Stock != 3 {
+			t.Errorf("stock = %d and %d, want 1 and 3", products.products[1].Stock, products.products[2].Stock)
+		}
+
+		// 5 x 10 + 2 x 3 sold; cost 2 x 5 + 2 x 6 + 1 x 10 (unbatched) + 2 x 2.
+		if !sales.created.TotalAmount.Equal(decimal.NewFromInt(56)) || !sales.created.TotalCost.Equal(decimal.NewFromInt(36)) {
+			t.Errorf("totals = %s and %s, want 56 and 36", sales.created.TotalAmount, sales.created.TotalCost)
+		}
+		if sales.created.CashierID != 9 || response.CashierID != 9 {
+			t.Errorf("cashier = %d, want 9", sales.created.CashierID)
+		}
+		if len(response.Items) != 4 || response.Items[2].BatchID != nil || !response.Items[2].Subtotal.Equal(decimal.NewFromInt(10)) {
+			t.Errorf("items = %+v, want the unbatched unit third", response.Items)
+		}
+		if batches.asOf.Hour() != 0 {
+			t.Errorf("batches sellable as of %s, want a date", batches.asOf)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		items   []dto.SaleItemRequest
+		wantErr error
+	}{
+		{"more than the stock", []dto.SaleItemRequest{{ProductID: 1, Quantity: 7}}, repository.ErrInsufficientStock},
+		{"stock held by another branch", []dto.SaleItemRequest{{ProductID: 1, Quantity: 6}}, repository.ErrInsufficientStock},
+		{"inactive product", []dto.SaleItemRequest{{ProductID: 3, Quantity: 1}}, ErrProductNotForSale},
+		{"unknown product", []dto.SaleItemRequest{{ProductID: 4, Quantity: 1}}, repository.ErrProductNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, batches := newFakes()
+			sales := &fakeSales{}
+			u := NewSaleUsecase(sales, batches, products, fakeTransactor{}, time.UTC)
+
+			_, err := u.CreateSale(context.Background(), 9, &dto.SaleRequest{BranchID: 1, Items: tt.items})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateSale() error = %v, want %v", err, tt.wantErr)
+			}
+			if sales.created != nil {
+				t.Errorf("sale recorded despite error")
+			}
+		})
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
 	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/tracing"
 )
 
 type SupplierUsecase interface {
-	GetAllSuppliers(ctx context.Context, page, pageSize int) ([]*entity.Supplier, *dto.PaginationResponse, error)
+	GetAllSuppliers(ctx context.Context, req *dto.PaginationRequest) ([]*entity.Supplier, *dto.PaginationResponse, error)
+	ExportSuppliers(ctx context.Context, w spreadsheet.Writer) error
+	PatchSupplier(ctx context.Context, id int64, version int64, req *dto.SupplierPatchRequest) (*entity.Supplier, error)
 }
 
 type supplierUsecase struct {
@@ -20,33 +25,79 @@ func NewSupplierUsecase(repo repository.SupplierRepository) SupplierUsecase {
 	return &supplierUsecase{repo: repo}
 }
 
-func (u *supplierUsecase) GetAllSuppliers(ctx context.Context, page, pageSize int) ([]*entity.Supplier, *dto.PaginationResponse, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated suppliers")
+func (u *supplierUsecase) GetAllSuppliers(ctx context.Context, req *dto.PaginationRequest) ([]*entity.Supplier, *dto.PaginationResponse, error) {
+	ctx, span := tracing.Start(ctx, "SupplierUsecase.GetAllSuppliers")
+	defer span.End()
 
-	suppliers, total, err := u.repo.GetAll(ctx, page, pageSize)
+	logger.Ctx(ctx).Info().Int("page", req.Page).Int("page_size", req.PageSize).Msg("Fetching paginated suppliers")
+
+	query, err := pagination.NewQuery(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := u.repo.GetAll(ctx, query)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch suppliers")
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch suppliers")
 		return nil, nil, err
 	}
 
-	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
-	hasNextPage := page < int(totalPages)
-	hasPrevPage := page > 1
-
-	nextPage := page + 1
-	prevPage := page - 1
-
-	pagination := &dto.PaginationResponse{
-		TotalItems:   total,
-		TotalPages:   int(totalPages),
-		CurrentPage:  page,
-		PageSize:     pageSize,
-		HasNextPage:  hasNextPage,
-		HasPrevPage:  hasPrevPage,
-		NextPage:     &nextPage,
-		PreviousPage: &prevPage,
+	paging := pagination.Response(req, page, func(supplier *entity.Supplier) int64 { return supplier.ID })
+
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Suppliers fetched successfully")
+	return page.Items, paging, nil
+}
+
+func (u *supplierUsecase) ExportSuppliers(ctx context.Context, w spreadsheet.Writer) error {
+	ctx, span := tracing.Start(ctx, "SupplierUsecase.ExportSuppliers")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Msg("Exporting suppliers")
+
+	if err := w.Write("id", "name", "contact_person", "phone", "email", "address", "created_at", "updated_at"); err != nil {
+		return err
+	}
+
+	count, err := exportRows(ctx, u.repo.GetAll, func(supplier *entity.Supplier) int64 { return supplier.ID }, func(supplier *entity.Supplier) error {
+		return w.Write(
+			supplier.ID,
+			supplier.Name,
+			supplier.ContactPerson,
+			supplier.Phone,
+			supplier.Email,
+			supplier.Address,
+			supplier.CreatedAt,
+			supplier.UpdatedAt,
+		)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int("count", count).Msg("Failed to export suppliers")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int("count", count).Msg("Suppliers exported successfully")
+	return nil
+}
+
+func (u *supplierUsecase) PatchSupplier(ctx context.Context, id int64, version int64, req *dto.SupplierPatchRequest) (*entity.Supplier, error) {
+	ctx, span := tracing.Start(ctx, "SupplierUsecase.PatchSupplier")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("supplier_id", id).Msg("Starting supplier patch process")
+
+	var fields []repository.FieldUpdate
+	fields = patchOptional(fields, "name", req.Name)
+	fields = patchNullable(fields, "contact_person", req.ContactPerson)
+	fields = patchNullable(fields, "phone", req.Phone)
+	fields = patchNullable(fields, "address", req.Address)
+	fields = patchNullable(fields, "email", req.Email)
+
+	supplier, err := u.repo.Patch(ctx, id, version, fields)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("supplier_id", id).Msg("Failed to patch supplier")
+		return nil, err
 	}
 
-	logger.Info().Int("count", len(suppliers)).Int64("total", total).Msg("Suppliers fetched successfully")
-	return suppliers, pagination, nil
+	logger.Ctx(ctx).Info().Int64("supplier_id", id).Msg("Supplier patched successfully")
+	return supplier, nil
 }
@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"pharmly-backend/internal/apperror"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/entity"
+	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/tracing"
+	"pharmly-backend/internal/utils"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer        = "Pharmly"
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidTwoFactorCode    = apperror.Unauthenticated("invalid_two_factor_code", "invalid two-factor code")
+	ErrTwoFactorAlreadyEnabled = apperror.Conflict("two_factor_already_enabled", "two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = apperror.Conflict("two_factor_not_enabled", "two-factor authentication is not enabled")
+	ErrTwoFactorRequired       = apperror.Forbidden("two_factor_required", "two-factor authentication is required for this role")
+	ErrInvalidRole             = apperror.Validation("invalid_role", "invalid role")
+)
+
+var validRoles = map[string]bool{
+	"admin":      true,
+	"pharmacist": true,
+	"cashier":    true,
+}
+
+type TwoFactorUsecase interface {
+	GetStatus(ctx context.Context, userID int64) (*dto.TwoFactorStatusResponse, error)
+	Enroll(ctx context.Context, userID int64, req *dto.TwoFactorEnrollRequest) (*dto.TwoFactorEnrollResponse, error)
+	Confirm(ctx context.Context, userID int64, req *dto.TwoFactorCodeRequest, issueToken bool) (*dto.TwoFactorConfirmResponse, error)
+	Disable(ctx context.Context, userID int64, req *dto.TwoFactorDisableRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, req *dto.TwoFactorCodeRequest) ([]string, error)
+	GetRolePolicies(ctx context.Context) ([]*dto.RoleTwoFactorPolicyResponse, error)
+	SetRolePolicy(ctx context.Context, role string, required bool) (*dto.RoleTwoFactorPolicyResponse, error)
+}
+
+type twoFactorUsecase struct {
+	users repository.UserRepository
+	repo  repository.TwoFactorRepository
+}
+
+func NewTwoFactorUsecase(users repository.UserRepository, repo repository.TwoFactorRepository) TwoFactorUsecase {
+	return &twoFactorUsecase{users: users, repo: repo}
+}
+
+func (u *twoFactorUsecase) GetStatus(ctx context.Context, userID int64) (*dto.TwoFactorStatusResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.GetStatus")
+	defer span.End()
+
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := u.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := u.repo.IsRequiredForRole(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dto.TwoFactorStatusResponse{Required: required}
+	if totp != nil && totp.ConfirmedAt != nil {
+		status.Enabled = true
+		status.ConfirmedAt = totp.ConfirmedAt
+	}
+	return status, nil
+}
+
+func (u *twoFactorUsecase) Enroll(ctx context.Context, userID int64, req *dto.TwoFactorEnrollRequest) (*dto.TwoFactorEnrollResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.Enroll")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Starting two-factor enrollment")
+
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	existing, err := u.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil && existing.ConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.SaveTOTP(ctx, &entity.UserTOTP{UserID: userID, Secret: secret}); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to save two-factor enrollment")
+		return nil, err
+	}
+
+	return &dto.TwoFactorEnrollResponse{
+		Secret: secret,
+		URI:    utils.TOTPURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+func (u *twoFactorUsecase) Confirm(ctx context.Context, userID int64, req *dto.TwoFactorCodeRequest, issueToken bool) (*dto.TwoFactorConfirmResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.Confirm")
+	defer span.End()
+
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := u.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	if totp.ConfirmedAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, req.Code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to confirm two-factor enrollment")
+		return nil, err
+	}
+
+	response := &dto.TwoFactorConfirmResponse{RecoveryCodes: codes}
+	if issueToken {
+		if response.Token, err = utils.GenerateToken(user); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Two-factor enrollment confirmed")
+	return response, nil
+}
+
+func (u *twoFactorUsecase) Disable(ctx context.Context, userID int64, req *dto.TwoFactorDisableRequest) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.Disable")
+	defer span.End()
+
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	required, err := u.repo.IsRequiredForRole(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := u.verifyConfirmedCode(ctx, userID, req.Code); err != nil {
+		return err
+	}
+
+	if err := u.repo.DeleteTOTP(ctx, userID); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to disable two-factor authentication")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int64("user_id", userID).Msg("Two-factor authentication disabled")
+	return nil
+}
+
+func (u *twoFactorUsecase) RegenerateRecoveryCodes(ctx context.Context, userID int64, req *dto.TwoFactorCodeRequest) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.RegenerateRecoveryCodes")
+	defer span.End()
+
+	if err := u.verifyConfirmedCode(ctx, userID, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", userID).Msg("Failed to regenerate recovery codes")
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (u *twoFactorUsecase) GetRolePolicies(ctx context.Context) ([]*dto.RoleTwoFactorPolicyResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.GetRolePolicies")
+	defer span.End()
+
+	policies, err := u.repo.GetRolePolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]*dto.RoleTwoFactorPolicyResponse, 0, len(policies))
+	for _, policy := range policies {
+		response = append(response, &dto.RoleTwoFactorPolicyResponse{
+			Role:      policy.Role,
+			Required:  policy.Required,
+			UpdatedAt: policy.UpdatedAt,
+		})
+	}
+	return response, nil
+}
+
+func (u *twoFactorUsecase) SetRolePolicy(ctx context.Context, role string, required bool) (*dto.RoleTwoFactorPolicyResponse, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorUsecase.SetRolePolicy")
+	defer span.End()
+
+	if !validRoles[role] {
+		return nil, ErrInvalidRole
+	}
+
+	policy := &entity.RoleTwoFactorPolicy{Role: role, Required: required}
+	if err := u.repo.SaveRolePolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	logger.Ctx(ctx).Info().Str("role", role).Bool("required", required).Msg("Role two-factor policy updated")
+	return &dto.RoleTwoFactorPolicyResponse{
+		Role:      policy.Role,
+		Required:  policy.Required,
+		UpdatedAt: policy.UpdatedAt,
+	}, nil
+}
+
+func (u *twoFactorUsecase) getUser(ctx context.Context, userID int64) (*entity.User, error) {
+	user, err := u.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u *twoFactorUsecase) verifyConfirmedCode(ctx context.Context, userID int64, code string) error {
+	totp, err := u.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if totp == nil || totp.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	ok, err := verifyTOTPCode(ctx, u.repo, totp, code, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// verifyTOTPCode checks a code and atomically marks its time step as used, so
+// the same code cannot be replayed within its validity window.
+func verifyTOTPCode(ctx context.Context, repo repository.TwoFactorRepository, totp *entity.UserTOTP, code string, now time.Time) (bool, error) {
+	step, ok := utils.ValidateTOTP(totp.Secret, code, now, totpSkew)
+	if !ok || step <= totp.LastUsedStep {
+		return false, nil
+	}
+	return repo.MarkTOTPStepUsed(ctx, totp.UserID, step)
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
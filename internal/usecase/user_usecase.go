@@ -5,11 +5,18 @@ import (
 	"pharmly-backend/internal/dto"
 	"pharmly-backend/internal/entity"
 	"pharmly-backend/internal/logger"
+	"pharmly-backend/internal/pagination"
 	"pharmly-backend/internal/repository"
+	"pharmly-backend/internal/spreadsheet"
+	"pharmly-backend/internal/tracing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserUsecase interface {
-	GetAllUsers(ctx context.Context, page, pageSize int) ([]*entity.User, *dto.PaginationResponse, error)
+	GetAllUsers(ctx context.Context, req *dto.PaginationRequest) ([]*entity.User, *dto.PaginationResponse, error)
+	ExportUsers(ctx context.Context, w spreadsheet.Writer) error
+	PatchUser(ctx context.Context, id int64, version int64, req *dto.UserPatchRequest) (*dto.UserResponse, error)
 }
 
 type userUsecase struct {
@@ -20,33 +27,91 @@ func NewUserUsecase(repo repository.UserRepository) UserUsecase {
 	return &userUsecase{repo: repo}
 }
 
-func (u *userUsecase) GetAllUsers(ctx context.Context, page, pageSize int) ([]*entity.User, *dto.PaginationResponse, error) {
-	logger.Info().Int("page", page).Int("page_size", pageSize).Msg("Fetching paginated users")
+func (u *userUsecase) GetAllUsers(ctx context.Context, req *dto.PaginationRequest) ([]*entity.User, *dto.PaginationResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.GetAllUsers")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int("page", req.Page).Int("page_size", req.PageSize).Msg("Fetching paginated users")
 
-	users, total, err := u.repo.GetAll(ctx, page, pageSize)
+	query, err := pagination.NewQuery(req)
 	if err != nil {
-		logger.Error().Err(err).Msg("Failed to fetch users")
 		return nil, nil, err
 	}
 
-	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
-	hasNextPage := page < int(totalPages)
-	hasPrevPage := page > 1
-
-	nextPage := page + 1
-	prevPage := page - 1
-
-	pagination := &dto.PaginationResponse{
-		TotalItems:   total,
-		TotalPages:   int(totalPages),
-		CurrentPage:  page,
-		PageSize:     pageSize,
-		HasNextPage:  hasNextPage,
-		HasPrevPage:  hasPrevPage,
-		NextPage:     &nextPage,
-		PreviousPage: &prevPage,
+	page, err := u.repo.GetAll(ctx, query)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Msg("Failed to fetch users")
+		return nil, nil, err
 	}
 
-	logger.Info().Int("count", len(users)).Int64("total", total).Msg("Users fetched successfully")
-	return users, pagination, nil
+	paging := pagination.Response(req, page, func(user *entity.User) int64 { return user.ID })
+
+	logger.Ctx(ctx).Info().Int("count", len(page.Items)).Msg("Users fetched successfully")
+	return page.Items, paging, nil
+}
+
+func (u *userUsecase) ExportUsers(ctx context.Context, w spreadsheet.Writer) error {
+	ctx, span := tracing.Start(ctx, "UserUsecase.ExportUsers")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Msg("Exporting users")
+
+	if err := w.Write("id", "username", "full_name", "email", "role", "status", "created_at", "updated_at"); err != nil {
+		return err
+	}
+
+	count, err := exportRows(ctx, u.repo.GetAll, func(user *entity.User) int64 { return user.ID }, func(user *entity.User) error {
+		return w.Write(
+			user.ID,
+			user.Username,
+			user.FullName,
+			user.Email,
+			user.Role,
+			user.Status,
+			user.CreatedAt,
+			user.UpdatedAt,
+		)
+	})
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int("count", count).Msg("Failed to export users")
+		return err
+	}
+
+	logger.Ctx(ctx).Info().Int("count", count).Msg("Users exported successfully")
+	return nil
+}
+
+func (u *userUsecase) PatchUser(ctx context.Context, id int64, version int64, req *dto.UserPatchRequest) (*dto.UserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserUsecase.PatchUser")
+	defer span.End()
+
+	logger.Ctx(ctx).Info().Int64("user_id", id).Msg("Starting user patch process")
+
+	if req.Password.Set {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password.Value), bcrypt.DefaultCost)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Int64("user_id", id).Msg("Failed to hash password")
+			return nil, err
+		}
+		req.Password.Value = string(hashedPassword)
+	}
+
+	var fields []repository.FieldUpdate
+	fields = patchOptional(fields, "username", req.Username)
+	fields = patchOptional(fields, "full_name", req.FullName)
+	fields = patchOptional(fields, "email", req.Email)
+	fields = patchOptional(fields, "password", req.Password)
+	fields = patchOptional(fields, "role", req.Role)
+	fields = patchOptional(fields, "status", req.Status)
+
+	user, err := u.repo.Patch(ctx, id, version, fields)
+	if err != nil {
+		logger.Ctx(ctx).Error().Err(err).Int64("user_id", id).Msg("Failed to patch user")
+		return nil, err
+	}
+
+	user.Password = ""
+
+	logger.Ctx(ctx).Info().Int64("user_id", id).Msg("User patched successfully")
+	return (*dto.UserResponse)(user), nil
 }
@@ -2,7 +2,7 @@ package utils
 
 import (
 	"errors"
-	"os"
+	"pharmly-backend/internal/apperror"
 	"pharmly-backend/internal/entity"
 	"time"
 
@@ -10,37 +10,85 @@ import (
 )
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-	ErrExpiredToken = errors.New("token has expired")
+	ErrInvalidToken = apperror.Unauthenticated("invalid_token", "invalid token")
+	ErrExpiredToken = apperror.Unauthenticated("expired_token", "token has expired")
+)
+
+const (
+	// TokenPurposeTwoFactor marks a challenge issued after a correct password
+	// that must be exchanged for an access token with a second factor.
+	TokenPurposeTwoFactor = "2fa"
+	// TokenPurposeEnrollment marks a challenge that only allows enrolling a
+	// second factor, for roles where two-factor authentication is required.
+	TokenPurposeEnrollment = "2fa_enroll"
+
+	ChallengeTokenTTL = 5 * time.Minute
 )
 
 type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Purpose  string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func GenerateToken(user *entity.User) (string, error) {
+	return signClaims(user, "", 24*time.Hour)
+}
+
+// GenerateChallengeToken issues a short-lived token that is rejected by
+// ValidateToken and only accepted where purpose is explicitly expected.
+func GenerateChallengeToken(user *entity.User, purpose string) (string, error) {
+	return signClaims(user, purpose, ChallengeTokenTTL)
+}
+
+func signClaims(user *entity.User, purpose string, ttl time.Duration) (string, error) {
 	claims := Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+		Purpose:  purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	ks, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+
+	key := ks.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, "")
+}
+
+func ValidateChallengeToken(tokenString, purpose string) (*Claims, error) {
+	return validateToken(tokenString, purpose)
+}
+
+func validateToken(tokenString, purpose string) (*Claims, error) {
+	ks, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.Lookup(kid)
+		if !ok || token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -49,7 +97,7 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Purpose == purpose {
 		return claims, nil
 	}
 
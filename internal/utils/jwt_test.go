@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"pharmly-backend/internal/entity"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeys writes an Ed25519 key "ed-2026", an RSA key "rsa-2025" and the
+// public half of an RSA key "retired" to a temporary directory.
+func writeKeys(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	write := func(name, blockType string, der []byte) {
+		data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name+".pem"), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("ed-2026", "PRIVATE KEY", der)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("rsa-2025", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	retired, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(&retired.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("retired", "PUBLIC KEY", der)
+
+	return dir
+}
+
+func useKeySet(t *testing.T, dir, active string) {
+	t.Helper()
+
+	ks, err := LoadKeySet(dir, active)
+	if err != nil {
+		t.Fatalf("LoadKeySet(%s) error = %v", active, err)
+	}
+	SetKeySet(ks)
+	t.Cleanup(func() { SetKeySet(nil) })
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := writeKeys(t)
+
+	ks, err := LoadKeySet(dir, "ed-2026")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.Active().ID != "ed-2026" || ks.Active().Method != jwt.SigningMethodEdDSA {
+		t.Errorf("active key = %s %s, want ed-2026 EdDSA", ks.Active().ID, ks.Active().Method.Alg())
+	}
+
+	jwks := ks.JWKS()
+	wantKeys := []struct{ kid, kty, alg string }{
+		{"ed-2026", "OKP", "EdDSA"},
+		{"retired", "RSA", "RS256"},
+		{"rsa-2025", "RSA", "RS256"},
+	}
+	if len(jwks.Keys) != len(wantKeys) {
+		t.Fatalf("JWKS has %d keys, want %d", len(jwks.Keys), len(wantKeys))
+	}
+	for i, want := range wantKeys {
+		got := jwks.Keys[i]
+		if got.KeyID != want.kid || got.KeyType != want.kty || got.Algorithm != want.alg || got.Use != "sig" {
+			t.Errorf("JWKS key %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	for _, active := range []string{"", "missing", "retired"} {
+		if _, err := LoadKeySet(dir, active); !errors.Is(err, ErrNoSigningKey) {
+			t.Errorf("LoadKeySet(%q) error = %v, want ErrNoSigningKey", active, err)
+		}
+	}
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	dir := writeKeys(t)
+	user := &entity.User{ID: 7, Username: "alice", Role: "pharmacist"}
+
+	for _, active := range []string{"ed-2026", "rsa-2025"} {
+		t.Run(active, func(t *testing.T) {
+			useKeySet(t, dir, active)
+
+			token, err := GenerateToken(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := ValidateToken(token)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.UserID != 7 || claims.Username != "alice" || claims.Role != "pharmacist" || claims.Purpose != "" {
+				t.Errorf("claims = %+v, want alice's", claims)
+			}
+		})
+	}
+}
+
+func TestTokenSurvivesRotation(t *testing.T) {
+	dir := writeKeys(t)
+	user := &entity.User{ID: 7, Username: "alice", Role: "pharmacist"}
+
+	useKeySet(t, dir, "rsa-2025")
+	token, err := GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useKeySet(t, dir, "ed-2026")
+	if _, err := ValidateToken(token); err != nil {
+		t.Errorf("token signed before rotation: ValidateToken() error = %v", err)
+	}
+}
+
+func TestChallengeTokenPurpose(t *testing.T) {
+	useKeySet(t, writeKeys(t), "ed-2026")
+	user := &entity.User{ID: 7, Username: "alice", Role: "admin"}
+
+	challenge, err := GenerateChallengeToken(user, TokenPurposeTwoFactor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, err := GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateToken(challenge); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateToken(challenge) error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := ValidateChallengeToken(challenge, TokenPurposeEnrollment); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateChallengeToken(other purpose) error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := ValidateChallengeToken(access, TokenPurposeTwoFactor); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateChallengeToken(access token) error = %v, want ErrInvalidToken", err)
+	}
+	claims, err := ValidateChallengeToken(challenge, TokenPurposeTwoFactor)
+	if err != nil || claims.Purpose != TokenPurposeTwoFactor {
+		t.Errorf("ValidateChallengeToken() = %+v, %v, want the 2fa challenge", claims, err)
+	}
+}
+
+func TestValidateTokenRejects(t *testing.T) {
+	dir := writeKeys(t)
+	useKeySet(t, dir, "ed-2026")
+	ks, _ := currentKeySet()
+	active := ks.Active()
+
+	sign := func(method jwt.SigningMethod, kid string, key any, expires time.Time) string {
+		token := jwt.NewWithClaims(method, Claims{
+			UserID: 7,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(expires),
+			},
+		})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", sign(active.Method, active.ID, active.Private, time.Now().Add(-time.Minute)), ErrExpiredToken},
+		{"unknown kid", sign(active.Method, "other", active.Private, later), ErrInvalidToken},
+		{"alg does not match the kid", sign(jwt.SigningMethodHS256, active.ID, []byte("secret"), later), ErrInvalidToken},
+		{"malformed", "not.a.token", ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ValidateToken(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoKeySet(t *testing.T) {
+	SetKeySet(nil)
+	if _, err := GenerateToken(&entity.User{ID: 1}); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("GenerateToken() error = %v, want ErrNoSigningKey", err)
+	}
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testSecretBoxKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+func TestNewSecretBox(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"32-byte key", testSecretBoxKey, false},
+		{"empty", "", true},
+		{"not base64", "not a key!", true},
+		{"16-byte key", base64.StdEncoding.EncodeToString(make([]byte, 16)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSecretBox(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSecretBox() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretBoxSealOpen(t *testing.T) {
+	box, err := NewSecretBox(testSecretBoxKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := box.Seal(rfc6238Secret, "user_totp:7")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if strings.Contains(sealed, rfc6238Secret) {
+		t.Fatalf("Seal() = %s, holds the plaintext", sealed)
+	}
+	if again, _ := box.Seal(rfc6238Secret, "user_totp:7"); again == sealed {
+		t.Errorf("Seal() returned the same value twice, want a fresh nonce")
+	}
+
+	if got, err := box.Open(sealed, "user_totp:7"); err != nil || got != rfc6238Secret {
+		t.Errorf("Open() = %q, %v, want %q", got, err, rfc6238Secret)
+	}
+
+	otherKey, err := NewSecretBox(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 1
+
+	for _, tt := range []struct {
+		name    string
+		box     *SecretBox
+		sealed  string
+		context string
+	}{
+		{"another record", box, sealed, "user_totp:8"},
+		{"another key", otherKey, sealed, "user_totp:7"},
+		{"altered", box, string(tampered), "user_totp:7"},
+		{"plaintext", box, rfc6238Secret, "user_totp:7"},
+		{"too short", box, "AAAA", "user_totp:7"},
+	} {
+		if _, err := tt.box.Open(tt.sealed, tt.context); !errors.Is(err, ErrSealedSecret) {
+			t.Errorf("%s: Open() error = %v, want %v", tt.name, err, ErrSealedSecret)
+		}
+	}
+}
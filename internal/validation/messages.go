@@ -0,0 +1,145 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	LangEnglish    = "en"
+	LangIndonesian = "id"
+)
+
+// validationMessages holds the field error messages per language, keyed by
+// validation tag. Length tags have separate entries for strings (".string")
+// and lists (".items"). {param} is replaced by the tag parameter.
+var validationMessages = map[string]map[string]string{
+	LangEnglish: {
+		"failed":           "Validation failed",
+		"required":         "This field is required",
+		"required_without": "This field is required",
+		"excluded_with":    "Cannot be combined with the other parameters given",
+		"email":            "Invalid email format",
+		"password":         "Password must be at least 8 characters long and contain uppercase, lowercase, number, and special character",
+		"len":              "Invalid length",
+		"len.string":       "Must be exactly {param} characters long",
+		"len.items":        "Must contain exactly {param} items",
+		"min":              "Must be at least {param}",
+		"min.string":       "Must be at least {param} characters long",
+		"min.items":        "Must contain at least {param} items",
+		"max":              "Must be at most {param}",
+		"max.string":       "Must be at most {param} characters long",
+		"max.items":        "Must contain at most {param} items",
+		"gt":               "Must be greater than {param}",
+		"gte":              "Value must be greater than or equal to {param}",
+		"lt":               "Must be less than {param}",
+		"lte":              "Value must be less than or equal to {param}",
+		"decimal_gt":       "Must be greater than {param}",
+		"oneof":            "Must be one of: {param}",
+		"numeric":          "Must contain digits only",
+		"cidr|ip":          "Must be a valid IP address or CIDR range",
+		"datetime":         "Must be an RFC 3339 date and time",
+		"barcode":          "Must be a valid EAN-8, UPC-A, EAN-13 or GTIN-14 barcode",
+		"phone":            "Must be a valid phone number",
+		"future_on_create": "Must be a date in the future",
+		"category_exists":  "Category does not exist",
+		"supplier_exists":  "Supplier does not exist",
+		"branch_exists":    "Branch does not exist",
+		"timezone":         "Must be an IANA time zone such as Asia/Jakarta",
+		"unique":           "Must not contain duplicates",
+		"default":          "Invalid value",
+	},
+	LangIndonesian: {
+		"failed":           "Validasi gagal",
+		"required":         "Wajib diisi",
+		"required_without": "Wajib diisi",
+		"excluded_with":    "Tidak dapat digabungkan dengan parameter lain yang diberikan",
+		"email":            "Format email tidak valid",
+		"password":         "Kata sandi minimal 8 karakter dan harus mengandung huruf besar, huruf kecil, angka, dan karakter khusus",
+		"len":              "Panjang tidak valid",
+		"len.string":       "Harus tepat {param} karakter",
+		"len.items":        "Harus berisi tepat {param} item",
+		"min":              "Minimal {param}",
+		"min.string":       "Minimal {param} karakter",
+		"min.items":        "Minimal berisi {param} item",
+		"max":              "Maksimal {param}",
+		"max.string":       "Maksimal {param} karakter",
+		"max.items":        "Maksimal berisi {param} item",
+		"gt":               "Harus lebih besar dari {param}",
+		"gte":              "Harus lebih besar dari atau sama dengan {param}",
+		"lt":               "Harus lebih kecil dari {param}",
+		"lte":              "Harus lebih kecil dari atau sama dengan {param}",
+		"decimal_gt":       "Harus lebih besar dari {param}",
+		"oneof":            "Harus salah satu dari: {param}",
+		"numeric":          "Hanya boleh berisi angka",
+		"cidr|ip":          "Harus berupa alamat IP atau rentang CIDR yang valid",
+		"datetime":         "Harus berupa tanggal dan waktu RFC 3339",
+		"barcode":          "Harus berupa barcode EAN-8, UPC-A, EAN-13 atau GTIN-14 yang valid",
+		"phone":            "Nomor telepon tidak valid",
+		"future_on_create": "Harus berupa tanggal di masa depan",
+		"category_exists":  "Kategori tidak ditemukan",
+		"supplier_exists":  "Pemasok tidak ditemukan",
+		"branch_exists":    "Cabang tidak ditemukan",
+		"timezone":         "Harus berupa zona waktu IANA seperti Asia/Jakarta",
+		"unique":           "Tidak boleh berisi duplikat",
+		"default":          "Nilai tidak valid",
+	},
+}
+
+// FailedMessage returns the summary message for a failed validation in lang.
+func FailedMessage(lang string) string {
+	if messages, ok := validationMessages[lang]; ok {
+		return messages["failed"]
+	}
+	return validationMessages[LangEnglish]["failed"]
+}
+
+// GetValidationErrors maps each invalid field, by its JSON name, to a message
+// in lang.
+func GetValidationErrors(err error, lang string) map[string]string {
+	errs := make(map[string]string)
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, e := range validationErrors {
+			errs[e.Field()] = validationMessage(e, lang)
+		}
+	}
+
+	return errs
+}
+
+func validationMessage(e validator.FieldError, lang string) string {
+	messages, ok := validationMessages[lang]
+	if !ok {
+		messages = validationMessages[LangEnglish]
+	}
+
+	message, ok := messages[e.Tag()+lengthSuffix(e)]
+	if !ok {
+		message, ok = messages[e.Tag()]
+	}
+	if !ok {
+		message = messages["default"]
+	}
+	return strings.ReplaceAll(message, "{param}", strings.ReplaceAll(e.Param(), " ", ", "))
+}
+
+func lengthSuffix(e validator.FieldError) string {
+	switch e.Tag() {
+	case "len", "min", "max":
+	default:
+		return ""
+	}
+
+	switch e.Kind() {
+	case reflect.String:
+		return ".string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return ".items"
+	}
+	return ""
+}
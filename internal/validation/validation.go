@@ -0,0 +1,172 @@
+// Package validation holds the request validator shared by the handlers and
+// the usecases, with its custom tags and localized field messages.
+package validation
+
+import (
+	"context"
+	"pharmly-backend/internal/dto"
+	"pharmly-backend/internal/logger"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+)
+
+var Validate *validator.Validate
+
+func init() {
+	Validate = validator.New()
+	Validate.RegisterTagNameFunc(jsonFieldName)
+	Validate.RegisterValidation("password", validatePassword)
+	Validate.RegisterValidation("barcode", validateBarcode)
+	Validate.RegisterValidation("phone", validatePhone)
+	Validate.RegisterValidation("decimal_gt", validateDecimalGreaterThan)
+	Validate.RegisterValidationCtx("future_on_create", validateFutureOnCreate)
+	Validate.RegisterCustomTypeFunc(decimalValue, decimal.Decimal{})
+	Validate.RegisterCustomTypeFunc(patchFieldValue,
+		dto.Optional[string]{},
+		dto.Optional[int]{},
+		dto.Optional[int64]{},
+		dto.Optional[bool]{},
+		dto.Optional[decimal.Decimal]{},
+		dto.Optional[time.Time]{},
+		dto.Nullable[string]{},
+		dto.Nullable[int64]{},
+	)
+}
+
+type creatingKey struct{}
+
+// ValidateCreate validates a request that creates a record. Rules such as
+// future_on_create only apply here, so an existing record can still be
+// updated after, for example, its expiration date has passed.
+func ValidateCreate(ctx context.Context, s any) error {
+	return Validate.StructCtx(context.WithValue(ctx, creatingKey{}, true), s)
+}
+
+// ExistsFunc reports whether the record with the given ID exists.
+type ExistsFunc func(ctx context.Context, id int64) (bool, error)
+
+// RegisterExists adds a validation tag that fails when an ID field names no
+// existing record. Lookup errors are logged and let the value through, so
+// the foreign key constraint remains the final check.
+func RegisterExists(tag string, exists ExistsFunc) {
+	Validate.RegisterValidationCtx(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
+		id := fl.Field().Int()
+		if id <= 0 {
+			return true
+		}
+
+		ok, err := exists(ctx, id)
+		if err != nil {
+			logger.Ctx(ctx).Error().Err(err).Str("tag", tag).Int64("id", id).Msg("Failed to check record exists")
+			return true
+		}
+		return ok
+	})
+}
+
+// jsonFieldName reports fields by their JSON or query parameter name so
+// error keys match what the client sent.
+func jsonFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		name := strings.SplitN(field.Tag.Get(key), ",", 2)[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// patchFieldValue lets validation tags on merge patch fields apply to the
+// supplied value; absent fields are skipped through "omitempty".
+func patchFieldValue(field reflect.Value) any {
+	return field.Interface().(dto.PatchField).PatchValue()
+}
+
+func decimalValue(field reflect.Value) any {
+	return field.Interface().(decimal.Decimal).String()
+}
+
+func validatePassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+
+	if len(password) < 8 {
+		return false
+	}
+
+	if !strings.ContainsAny(password, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+		return false
+	}
+
+	if !strings.ContainsAny(password, "abcdefghijklmnopqrstuvwxyz") {
+		return false
+	}
+
+	if !strings.ContainsAny(password, "0123456789") {
+		return false
+	}
+
+	if !strings.ContainsAny(password, "!@#$%^&*()_+-=[]{}|;:,.<>?") {
+		return false
+	}
+
+	return true
+}
+
+// validateBarcode accepts EAN-8, UPC-A, EAN-13 and GTIN-14 codes with a
+// correct GS1 check digit.
+func validateBarcode(fl validator.FieldLevel) bool {
+	code := fl.Field().String()
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	check := int(code[len(code)-1] - '0')
+	return check >= 0 && check <= 9 && (10-sum%10)%10 == check
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,28}[0-9]$`)
+
+func validatePhone(fl validator.FieldLevel) bool {
+	return phonePattern.MatchString(fl.Field().String())
+}
+
+func validateDecimalGreaterThan(fl validator.FieldLevel) bool {
+	value, err := decimal.NewFromString(fl.Field().String())
+	if err != nil {
+		return false
+	}
+
+	limit, err := decimal.NewFromString(fl.Param())
+	if err != nil {
+		return false
+	}
+	return value.GreaterThan(limit)
+}
+
+func validateFutureOnCreate(ctx context.Context, fl validator.FieldLevel) bool {
+	if creating, _ := ctx.Value(creatingKey{}).(bool); !creating {
+		return true
+	}
+
+	value, ok := fl.Field().Interface().(time.Time)
+	return ok && value.After(time.Now())
+}
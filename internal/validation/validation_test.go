@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"pharmly-backend/internal/dto"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	exists := func(ctx context.Context, id int64) (bool, error) { return id != 404, nil }
+	RegisterExists("category_exists", exists)
+	RegisterExists("supplier_exists", exists)
+}
+
+func TestValidateMergePatch(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]string
+	}{
+		{"absent fields are skipped", `{}`, map[string]string{}},
+		{"valid values pass", `{"name":"Paracetamol","stock":0,"price":"1.5","description":null}`, map[string]string{}},
+		{
+			name: "explicit zero values are still validated",
+			body: `{"name":"","price":"0","stock":-1}`,
+			want: map[string]string{
+				"name":  "Must be at least 1 characters long",
+				"price": "Must be greater than 0",
+				"stock": "Value must be greater than or equal to 0",
+			},
+		},
+		{"unknown category", `{"category_id":404}`, map[string]string{"category_id": "Category does not exist"}},
+		{"invalid barcode", `{"barcode":"4006381333932"}`, map[string]string{"barcode": validationMessages[LangEnglish]["barcode"]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req dto.ProductPatchRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+
+			got := GetValidationErrors(Validate.StructCtx(context.Background(), &req), LangEnglish)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCreateOnlyRules(t *testing.T) {
+	req := dto.ProductRequest{
+		Name:           "Paracetamol",
+		CategoryID:     1,
+		Unit:           "box",
+		SupplierID:     1,
+		Price:          decimal.NewFromInt(1),
+		ExpirationDate: time.Now().AddDate(0, 0, -1),
+	}
+
+	if errs := GetValidationErrors(Validate.StructCtx(context.Background(), &req), LangEnglish); len(errs) != 0 {
+		t.Errorf("update errors = %v, want none", errs)
+	}
+	errs := GetValidationErrors(ValidateCreate(context.Background(), &req), LangEnglish)
+	if _, ok := errs["expiration_date"]; !ok || len(errs) != 1 {
+		t.Errorf("create errors = %v, want expiration_date only", errs)
+	}
+}
+
+func TestMessagesFallBackToEnglish(t *testing.T) {
+	if got := FailedMessage("fr"); got != validationMessages[LangEnglish]["failed"] {
+		t.Errorf("FailedMessage(fr) = %q, want the English message", got)
+	}
+	if got := FailedMessage(LangIndonesian); got == FailedMessage(LangEnglish) {
+		t.Errorf("FailedMessage(id) = %q, want an Indonesian message", got)
+	}
+}